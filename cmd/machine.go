@@ -25,9 +25,11 @@ import (
 	"github.com/docker/machine/drivers/vmwarefusion"
 	"github.com/docker/machine/drivers/vmwarevcloudair"
 	"github.com/docker/machine/drivers/vmwarevsphere"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin"
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
 	"github.com/docker/machine/version"
 )
 
@@ -117,6 +119,30 @@ func main() {
 			Value:  mcndirs.GetBaseDir(),
 			Usage:  "Configures storage path",
 		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORAGE_BACKEND",
+			Name:   "storage-backend",
+			Value:  persist.FileBackend,
+			Usage:  "Configures how machines are stored: [file, db]",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORE_PASSPHRASE",
+			Name:   "store-passphrase",
+			Usage:  "Passphrase to encrypt the credentials of the machines with",
+			Value:  "",
+		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_STORE_KEY_FILE",
+			Name:   "store-key-file",
+			Usage:  "Key file to encrypt the credentials of the machines with",
+			Value:  "",
+		},
+		cli.IntFlag{
+			EnvVar: "MACHINE_LOCK_TIMEOUT",
+			Name:   "lock-timeout",
+			Value:  int(persist.DefaultLockTimeout.Seconds()),
+			Usage:  "Seconds to wait for a machine used by another process",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_TLS_CA_CERT",
 			Name:   "tls-ca-cert",
@@ -141,6 +167,12 @@ func main() {
 			Usage:  "Private key used in client TLS auth",
 			Value:  "",
 		},
+		cli.StringFlag{
+			EnvVar: "MACHINE_SERVER",
+			Name:   "server",
+			Usage:  "Address of a Docker Machine server to manage the machines with",
+			Value:  "",
+		},
 		cli.StringFlag{
 			EnvVar: "MACHINE_GITHUB_API_TOKEN",
 			Name:   "github-api-token",
@@ -166,41 +198,45 @@ func main() {
 }
 
 func runDriver(driverName string) {
+	var newDriver func() drivers.Driver
+
 	switch driverName {
 	case "amazonec2":
-		plugin.RegisterDriver(amazonec2.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return amazonec2.NewDriver("", "") }
 	case "azure":
-		plugin.RegisterDriver(azure.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return azure.NewDriver("", "") }
 	case "digitalocean":
-		plugin.RegisterDriver(digitalocean.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return digitalocean.NewDriver("", "") }
 	case "exoscale":
-		plugin.RegisterDriver(exoscale.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return exoscale.NewDriver("", "") }
 	case "generic":
-		plugin.RegisterDriver(generic.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return generic.NewDriver("", "") }
 	case "google":
-		plugin.RegisterDriver(google.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return google.NewDriver("", "") }
 	case "hyperv":
-		plugin.RegisterDriver(hyperv.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return hyperv.NewDriver("", "") }
 	case "none":
-		plugin.RegisterDriver(none.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return none.NewDriver("", "") }
 	case "openstack":
-		plugin.RegisterDriver(openstack.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return openstack.NewDriver("", "") }
 	case "rackspace":
-		plugin.RegisterDriver(rackspace.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return rackspace.NewDriver("", "") }
 	case "softlayer":
-		plugin.RegisterDriver(softlayer.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return softlayer.NewDriver("", "") }
 	case "virtualbox":
-		plugin.RegisterDriver(virtualbox.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return virtualbox.NewDriver("", "") }
 	case "vmwarefusion":
-		plugin.RegisterDriver(vmwarefusion.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return vmwarefusion.NewDriver("", "") }
 	case "vmwarevcloudair":
-		plugin.RegisterDriver(vmwarevcloudair.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return vmwarevcloudair.NewDriver("", "") }
 	case "vmwarevsphere":
-		plugin.RegisterDriver(vmwarevsphere.NewDriver("", ""))
+		newDriver = func() drivers.Driver { return vmwarevsphere.NewDriver("", "") }
 	default:
 		fmt.Fprintf(os.Stderr, "Unsupported driver: %s\n", driverName)
 		os.Exit(1)
 	}
+
+	plugin.RegisterDriverFactory(newDriver)
 }
 
 func cmdNotFound(c *cli.Context, command string) {
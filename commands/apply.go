@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/fleet"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
+)
+
+var errNoFleetFile = errors.New("Error: Expected the path of a fleet file with --file")
+
+// loadPlan reads the fleet file and compares it with the store.
+func loadPlan(c CommandLine, api libmachine.API) (*fleet.Plan, error) {
+	path := c.String("file")
+	if path == "" {
+		return nil, errNoFleetFile
+	}
+
+	spec, err := fleet.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range spec.Names() {
+		if err := validateSwarmDiscovery(spec.Machines[name].Swarm.Discovery); err != nil {
+			return nil, fmt.Errorf("Error parsing swarm discovery of %q: %s", name, err)
+		}
+	}
+
+	return fleet.NewPlan(api, spec, c.Bool("prune"))
+}
+
+func cmdPlan(c CommandLine, api libmachine.API) error {
+	plan, err := loadPlan(c, api)
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, plan)
+
+	return nil
+}
+
+func cmdApply(c CommandLine, api libmachine.API) error {
+	plan, err := loadPlan(c, api)
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, plan)
+
+	if !plan.HasChanges() {
+		log.Info("The machines match the fleet, there is nothing to do.")
+		return nil
+	}
+
+	if !c.Bool("y") {
+		ok, err := confirmInput("Apply these changes?")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	var errs []string
+	for _, action := range plan.Actions {
+		if err := applyAction(ctx, c, api, action); err != nil {
+			errs = append(errs, fmt.Sprintf("Error applying %s to %q: %s", action.Type, action.Name, err))
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+func applyAction(ctx context.Context, c CommandLine, api libmachine.API, action fleet.Action) error {
+	switch action.Type {
+	case fleet.Create:
+		return createFleetMachine(ctx, c, api, action.Name, action.Machine)
+	case fleet.Remove:
+		return removeFleetMachine(ctx, api, action.Name)
+	case fleet.Drifted:
+		if action.Recreate() {
+			if !c.Bool("recreate") {
+				log.Warnf("Machine %q has to be created again to apply the changes, use --recreate to do so.", action.Name)
+				return nil
+			}
+
+			if err := removeFleetMachine(ctx, api, action.Name); err != nil {
+				return err
+			}
+			return createFleetMachine(ctx, c, api, action.Name, action.Machine)
+		}
+
+		return updateFleetMachine(api, action.Name, action.Machine)
+	}
+
+	return nil
+}
+
+func createFleetMachine(ctx context.Context, c CommandLine, api libmachine.API, name string, m *fleet.Machine) error {
+	hostOptions := &host.Options{
+		AuthOptions:   newAuthOptions(c, name),
+		EngineOptions: m.EngineOptions(),
+		SwarmOptions:  m.SwarmOptions(),
+	}
+
+	return createMachine(ctx, c, api, name, m.Driver, hostOptions, m.DriverOptions)
+}
+
+func removeFleetMachine(ctx context.Context, api libmachine.API, name string) error {
+	results := batch.Run(ctx, api, batch.Remove, []string{name}, batch.Options{})
+	if errs := batch.Errors(results); len(errs) > 0 {
+		return errs[0]
+	}
+
+	log.Infof("Successfully removed %s", name)
+
+	return nil
+}
+
+// updateFleetMachine provisions a machine again with the engine and Swarm
+// options of the fleet.
+func updateFleetMachine(api libmachine.API, name string, m *fleet.Machine) error {
+	h, err := api.Load(name)
+	if err != nil {
+		return err
+	}
+
+	if !drivers.MachineInState(h.Driver, state.Running)() {
+		return fmt.Errorf("Machine %q must be running to be provisioned again", name)
+	}
+
+	h.HostOptions.EngineOptions = m.EngineOptions()
+	h.HostOptions.SwarmOptions = m.SwarmOptions()
+
+	log.Infof("Provisioning %q again...", name)
+	if err := h.Provision(); err != nil {
+		return err
+	}
+
+	return api.Save(h)
+}
+
+func printPlan(w io.Writer, plan *fleet.Plan) {
+	tw := tabwriter.NewWriter(w, 5, 1, 3, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "ACTION\tMACHINE\tDETAILS")
+
+	for _, action := range plan.Actions {
+		details := []string{}
+
+		switch action.Type {
+		case fleet.Create:
+			details = append(details, action.Machine.Driver)
+		case fleet.Drifted:
+			for _, change := range action.Changes {
+				detail := change.String()
+				if change.Recreate {
+					detail += " (recreate)"
+				}
+				details = append(details, detail)
+			}
+		}
+
+		if len(details) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\n", action.Type, action.Name)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", action.Type, action.Name, details[0])
+		for _, detail := range details[1:] {
+			fmt.Fprintf(tw, "\t\t%s\n", detail)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/fleet"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestCmdApplyMissingFile(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdApply(commandLine, api)
+
+	assert.Equal(t, errNoFleetFile, err)
+}
+
+func TestCmdApplyInvalidSwarmDiscovery(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "machine-test-")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fleet.yml")
+	ioutil.WriteFile(path, []byte("machines:\n  dev:\n    driver: none\n    swarm:\n      discovery: nodiscovery\n"), 0600)
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"file": path,
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdPlan(commandLine, api)
+
+	assert.EqualError(t, err, `Error parsing swarm discovery of "dev": Swarm Discovery URL was in the wrong format: nodiscovery`)
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := &fleet.Plan{
+		Actions: []fleet.Action{
+			{Type: fleet.Create, Name: "web", Machine: &fleet.Machine{Driver: "virtualbox"}},
+			{Type: fleet.Drifted, Name: "db", Changes: []fleet.Change{
+				{Field: "engine.labels", Current: "[]", Desired: `["role=db"]`},
+				{Field: "driver", Current: "virtualbox", Desired: "amazonec2", Recreate: true},
+			}},
+			{Type: fleet.Unchanged, Name: "cache"},
+			{Type: fleet.Remove, Name: "old"},
+		},
+	}
+
+	var out bytes.Buffer
+	printPlan(&out, plan)
+
+	assert.Equal(t, `ACTION      MACHINE   DETAILS
+create      web       virtualbox
+drifted     db        engine.labels: [] -> ["role=db"]
+                      driver: virtualbox -> amazonec2 (recreate)
+unchanged   cache
+remove      old
+`, out.String())
+}
+
+func TestCreateFleetMachineIsValidatedLikeCreate(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := createFleetMachine(context.Background(), commandLine, api, "dev_1", &fleet.Machine{Driver: "none"})
+	assert.EqualError(t, err, "Error creating machine: Invalid hostname specified. Allowed hostname chars are: 0-9a-zA-Z . -")
+
+	err = createFleetMachine(context.Background(), commandLine, api, "dev", &fleet.Machine{Driver: "none", Swarm: fleet.Swarm{Discovery: "nodiscovery"}})
+	assert.EqualError(t, err, "Error parsing swarm discovery: Swarm Discovery URL was in the wrong format: nodiscovery")
+}
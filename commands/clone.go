@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+var errExpectedSourceAndTarget = errors.New("Error: Expected the name of the machine to clone and the name of the copy as arguments")
+
+func cmdClone(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 2 {
+		return errExpectedSourceAndTarget
+	}
+
+	srcName, name := c.Args()[0], c.Args()[1]
+
+	if !host.ValidateHostName(name) {
+		return fmt.Errorf("Error cloning machine: %s", mcnerror.ErrInvalidHostname)
+	}
+
+	exists, err := api.Exists(name)
+	if err != nil {
+		return fmt.Errorf("Error checking if host exists: %s", err)
+	}
+	if exists {
+		return mcnerror.ErrHostAlreadyExists{
+			Name: name,
+		}
+	}
+
+	src, err := api.Load(srcName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	rawDriver, err := src.CloneContext(ctx, name)
+	if err != nil {
+		return fmt.Errorf("Error cloning %s: %s", srcName, err)
+	}
+
+	h, err := api.NewHost(src.DriverName, rawDriver)
+	if err != nil {
+		return fmt.Errorf("Error getting new host: %s", err)
+	}
+
+	h.HostOptions = cloneHostOptions(src.HostOptions, name)
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error attempting to save store: %s", err)
+	}
+
+	// The copy has a new IP, so it is provisioned again to get its own
+	// hostname and server certificates.
+	if err := h.StartContext(ctx); err != nil {
+		return fmt.Errorf("Error starting %s: %s", name, err)
+	}
+
+	log.Info("Regenerating TLS certificates")
+	if err := h.ConfigureAuth(); err != nil {
+		return fmt.Errorf("Error regenerating the certificates of %s: %s", name, err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error attempting to save store: %s", err)
+	}
+
+	log.Infof("Machine %q was cloned from %q.", name, srcName)
+
+	return nil
+}
+
+// cloneHostOptions copies the options of a machine for its copy, which gets
+// its own server certificate.
+func cloneHostOptions(options *host.Options, name string) *host.Options {
+	clone := *options
+
+	if options.AuthOptions != nil {
+		authOptions := *options.AuthOptions
+		authOptions.ServerCertPath = filepath.Join(mcndirs.GetMachineDir(), name, "server.pem")
+		authOptions.ServerKeyPath = filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem")
+		authOptions.StorePath = filepath.Join(mcndirs.GetMachineDir(), name)
+		clone.AuthOptions = &authOptions
+	}
+
+	if options.EngineOptions != nil {
+		engineOptions := *options.EngineOptions
+		clone.EngineOptions = &engineOptions
+	}
+
+	if options.SwarmOptions != nil {
+		swarmOptions := *options.SwarmOptions
+		clone.SwarmOptions = &swarmOptions
+	}
+
+	return &clone
+}
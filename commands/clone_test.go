@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdCloneMissingTarget(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"golden"},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdClone(commandLine, api)
+
+	assert.Equal(t, errExpectedSourceAndTarget, err)
+}
+
+func TestCmdCloneTargetExists(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"golden", "copy"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "golden",
+			},
+			{
+				Name: "copy",
+			},
+		},
+	}
+
+	err := cmdClone(commandLine, api)
+
+	assert.Equal(t, mcnerror.ErrHostAlreadyExists{Name: "copy"}, err)
+}
+
+func TestCmdCloneNotSupported(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"golden", "copy"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "golden",
+				Driver: none.NewDriver("golden", ""),
+			},
+		},
+	}
+
+	err := cmdClone(commandLine, api)
+
+	assert.EqualError(t, err, `Error cloning golden: Driver "none" does not support cloning`)
+}
+
+func TestCloneHostOptions(t *testing.T) {
+	options := &host.Options{
+		AuthOptions: &auth.Options{
+			CaCertPath:     "/store/certs/ca.pem",
+			ServerCertPath: "/store/machines/golden/server.pem",
+		},
+		EngineOptions: &engine.Options{
+			Labels: []string{"golden"},
+		},
+	}
+
+	clone := cloneHostOptions(options, "copy")
+
+	assert.Equal(t, "/store/certs/ca.pem", clone.AuthOptions.CaCertPath)
+	assert.Equal(t, filepath.Join(mcndirs.GetMachineDir(), "copy", "server.pem"), clone.AuthOptions.ServerCertPath)
+	assert.Equal(t, "/store/machines/golden/server.pem", options.AuthOptions.ServerCertPath)
+	assert.Equal(t, []string{"golden"}, clone.EngineOptions.Labels)
+	assert.Nil(t, clone.SwarmOptions)
+}
@@ -1,21 +1,32 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/batch"
+	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/crashreport"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/remote"
 	"github.com/docker/machine/libmachine/ssh"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -46,6 +57,10 @@ type CommandLine interface {
 
 	GlobalString(name string) string
 
+	GlobalBool(name string) bool
+
+	GlobalInt(name string) int
+
 	FlagNames() (names []string)
 
 	Generic(name string) interface{}
@@ -67,6 +82,69 @@ func (c *contextCommandLine) Application() *cli.App {
 	return c.App
 }
 
+// runBatchAction applies the action to the machines given as arguments and
+// optionally prints a summary of the results.
+func runBatchAction(action batch.Action, c CommandLine, api libmachine.API) error {
+	if len(c.Args()) == 0 {
+		return ErrNoMachineSpecified
+	}
+
+	results, err := runBatch(action, c, api)
+	if err != nil {
+		return err
+	}
+
+	if err := printBatchSummary(os.Stdout, c.String("summary"), results); err != nil {
+		return err
+	}
+
+	if errs := batch.Errors(results); len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
+
+func runBatch(action batch.Action, c CommandLine, api libmachine.API) ([]batch.Result, error) {
+	summaryFormat := c.String("summary")
+	if summaryFormat != "" && summaryFormat != "table" && summaryFormat != "json" {
+		return nil, fmt.Errorf("Unknown summary format %q, expected table or json", summaryFormat)
+	}
+
+	// Keep stdout machine-readable.
+	if summaryFormat == "json" {
+		log.SetOutWriter(os.Stderr)
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	return batch.Run(ctx, api, action, c.Args(), batch.Options{
+		Parallel: c.Int("parallel"),
+		Force:    c.Bool("force"),
+	}), nil
+}
+
+func printBatchSummary(out io.Writer, format string, results []batch.Result) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(out).Encode(results)
+	case "table":
+		w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tACTION\tRESULT\tDURATION\tERROR")
+		for _, r := range results {
+			status, errMsg := "Success", ""
+			if !r.Succeeded() {
+				status, errMsg = "Error", r.Err.Error()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.Action, status, r.Duration, errMsg)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
+
 func runAction(actionName string, c CommandLine, api libmachine.API) error {
 	hosts, hostsInError := persist.LoadHosts(api, c.Args())
 
@@ -82,7 +160,10 @@ func runAction(actionName string, c CommandLine, api libmachine.API) error {
 		return ErrNoMachineSpecified
 	}
 
-	if errs := runActionForeachMachine(actionName, hosts); len(errs) > 0 {
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	if errs := runActionForeachMachine(ctx, actionName, hosts); len(errs) > 0 {
 		return consolidateErrs(errs)
 	}
 
@@ -95,25 +176,110 @@ func runAction(actionName string, c CommandLine, api libmachine.API) error {
 	return nil
 }
 
-func runCommand(command func(commandLine CommandLine, api libmachine.API) error) func(context *cli.Context) {
-	return func(context *cli.Context) {
-		api := libmachine.NewClient(mcndirs.GetBaseDir(), mcndirs.GetMachineCertDir())
-		defer api.Close()
+// commandContext returns a context which is cancelled on the first interrupt
+// signal and, if the command has a --timeout flag set, when the timeout
+// expires. A second interrupt terminates the process as usual.
+func commandContext(c CommandLine) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
 
-		if context.GlobalBool("native-ssh") {
-			api.SSHClientType = ssh.Native
+	if timeout := c.Int("timeout"); timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	go func() {
+		select {
+		case <-interrupts:
+			log.Info("Interrupted, aborting... (press Ctrl-C again to force exit)")
+			cancel()
+		case <-ctx.Done():
 		}
-		api.GithubAPIToken = context.GlobalString("github-api-token")
-		api.Filestore.Path = context.GlobalString("storage-path")
+		signal.Stop(interrupts)
+	}()
+
+	return ctx, cancel
+}
+
+// newLocalAPI returns the API managing the machines of the local store, as
+// configured by the global flags.
+func newLocalAPI(c CommandLine) (*libmachine.Client, error) {
+	api := libmachine.NewClient(mcndirs.GetBaseDir(), mcndirs.GetMachineCertDir())
+
+	if c.GlobalBool("native-ssh") {
+		api.SSHClientType = ssh.Native
+	}
+	api.GithubAPIToken = c.GlobalString("github-api-token")
+	api.Filestore.Path = c.GlobalString("storage-path")
+
+	api.Filestore.LockTimeout = time.Duration(c.GlobalInt("lock-timeout")) * time.Second
+
+	keys, err := newKeyProvider(c.GlobalString("store-passphrase"), c.GlobalString("store-key-file"), api.Filestore.Path)
+	if err != nil {
+		return nil, err
+	}
+	api.Filestore.KeyProvider = keys
+
+	store, err := persist.NewStore(c.GlobalString("storage-backend"), api.Filestore)
+	if err != nil {
+		return nil, err
+	}
+	api.Store = store
+
+	return api, nil
+}
+
+// newAPI returns the API of the server given with --server if any, or the
+// one managing the machines of the local store.
+func newAPI(c CommandLine) (libmachine.API, error) {
+	server := c.GlobalString("server")
+	if server == "" {
+		return newLocalAPI(c)
+	}
+
+	tlsConfig, err := cert.ReadClientTLSConfig(&auth.Options{
+		CaCertPath:     tlsPath(c, "tls-ca-cert", "ca.pem"),
+		ClientCertPath: tlsPath(c, "tls-client-cert", "cert.pem"),
+		ClientKeyPath:  tlsPath(c, "tls-client-key", "key.pem"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the certificates to connect to %s: %s", server, err)
+	}
+
+	return remote.NewClient(server, c.GlobalString("storage-path"), tlsConfig), nil
+}
 
+func runCommand(command func(commandLine CommandLine, api libmachine.API) error) func(context *cli.Context) {
+	return func(context *cli.Context) {
 		// TODO (nathanleclaire): These should ultimately be accessed
 		// through the libmachine client by the rest of the code and
 		// not through their respective modules.  For now, however,
 		// they are also being set the way that they originally were
 		// set to preserve backwards compatibility.
-		mcndirs.BaseDir = api.Filestore.Path
-		mcnutils.GithubAPIToken = api.GithubAPIToken
-		ssh.SetDefaultClient(api.SSHClientType)
+		mcndirs.BaseDir = context.GlobalString("storage-path")
+		localbinary.PluginDir = mcndirs.GetPluginDir()
+		mcnutils.GithubAPIToken = context.GlobalString("github-api-token")
+		if context.GlobalBool("native-ssh") {
+			ssh.SetDefaultClient(ssh.Native)
+		} else {
+			ssh.SetDefaultClient(ssh.External)
+		}
+
+		api, err := newAPI(&contextCommandLine{context})
+		if err != nil {
+			log.Error(err)
+			osExit(1)
+			return
+		}
+		defer api.Close()
+
+		defer subscribeLogEvent(api)()
 
 		if err := command(&contextCommandLine{context}, api); err != nil {
 			log.Error(err)
@@ -145,12 +311,86 @@ func confirmInput(msg string) (bool, error) {
 	return confirmed, nil
 }
 
+var (
+	timeoutFlag = cli.IntFlag{
+		Name:  "timeout",
+		Usage: "Abort if the operation is not done after this many seconds, 0 to wait indefinitely",
+	}
+	parallelFlag = cli.IntFlag{
+		Name:  "parallel",
+		Usage: "Maximum number of machines acted upon at the same time",
+		Value: batch.DefaultParallel,
+	}
+	summaryFlag = cli.StringFlag{
+		Name:  "summary",
+		Usage: "Print a summary of the result for each machine: [table, json]",
+	}
+
+	driverFormatFlag = cli.StringFlag{
+		Name:  "format, f",
+		Usage: "Output format: [table, json]",
+		Value: "table",
+	}
+	driverInstallFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "Name of the driver, guessed from the binary name if not given",
+		},
+		cli.StringFlag{
+			Name:  "sha256",
+			Usage: "Expected SHA-256 checksum of the binary",
+		},
+		cli.StringFlag{
+			Name:  "signature",
+			Usage: "Path or URL of a detached SHA-256 signature of the binary",
+		},
+		cli.StringFlag{
+			Name:  "public-key",
+			Usage: "Local file of the PEM encoded RSA or ECDSA public key the signature is verified with",
+		},
+	}
+)
+
 var Commands = []cli.Command{
 	{
 		Name:   "active",
 		Usage:  "Print which machine is active",
 		Action: runCommand(cmdActive),
 	},
+	{
+		Name:        "apply",
+		Usage:       "Create, update or remove machines to match a fleet file",
+		Description: "The fleet file is given with --file, in YAML or in JSON if its extension is .json.",
+		Action:      runCommand(cmdApply),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file, f",
+				Usage: "Path of the fleet file",
+			},
+			cli.BoolFlag{
+				Name:  "prune",
+				Usage: "Remove the machines which are not in the fleet file",
+			},
+			cli.BoolFlag{
+				Name:  "recreate",
+				Usage: "Remove and create again the machines whose driver configuration changed",
+			},
+			cli.BoolFlag{
+				Name:  "y",
+				Usage: "Assumes automatic yes to apply the changes, without prompting further user confirmation",
+			},
+			timeoutFlag,
+		},
+	},
+	{
+		Name:        "clone",
+		Usage:       "Create a copy of a machine",
+		Description: "Arguments are the name of the machine to clone and the name of the copy.",
+		Action:      runCommand(cmdClone),
+		Flags: []cli.Flag{
+			timeoutFlag,
+		},
+	},
 	{
 		Name:        "config",
 		Usage:       "Print the connection config for machine",
@@ -171,6 +411,49 @@ var Commands = []cli.Command{
 		Action:          runCommand(cmdCreateOuter),
 		SkipFlagParsing: true,
 	},
+	{
+		Name:  "driver",
+		Usage: "List, inspect and install the machine drivers",
+		Subcommands: []cli.Command{
+			{
+				Name:        "info",
+				Usage:       "Show the details and create flags of a driver",
+				Description: "Argument is a driver name.",
+				Action:      runCommand(cmdDriverInfo),
+				Flags: []cli.Flag{
+					driverFormatFlag,
+				},
+			},
+			{
+				Name:        "install",
+				Usage:       "Install a driver plugin once its checksum or signature is verified",
+				Description: "Argument is the path or URL of a docker-machine-driver-NAME binary.",
+				Action:      runCommand(cmdDriverInstall),
+				Flags:       driverInstallFlags,
+			},
+			{
+				Name:   "ls",
+				Usage:  "List the core drivers and the plugin drivers installed or found in the PATH",
+				Action: runCommand(cmdDriverLs),
+				Flags: []cli.Flag{
+					driverFormatFlag,
+				},
+			},
+			{
+				Name:        "uninstall",
+				Usage:       "Uninstall a driver plugin",
+				Description: "Argument is a driver name.",
+				Action:      runCommand(cmdDriverUninstall),
+			},
+			{
+				Name:        "upgrade",
+				Usage:       "Replace an installed driver plugin once its checksum or signature is verified",
+				Description: "Argument is the path or URL of a docker-machine-driver-NAME binary.",
+				Action:      runCommand(cmdDriverUpgrade),
+				Flags:       driverInstallFlags,
+			},
+		},
+	},
 	{
 		Name:        "env",
 		Usage:       "Display the commands to set up the environment for the Docker client",
@@ -195,6 +478,42 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:        "export",
+		Usage:       "Export a machine to a bundle",
+		Description: "Argument is a machine name.",
+		Action:      runCommand(cmdExport),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Path of the bundle, defaults to <name>.tar.gz",
+			},
+			cli.BoolFlag{
+				Name:  "redact",
+				Usage: "Remove the credentials of the driver from the bundle",
+			},
+			cli.BoolFlag{
+				Name:  "include-ca-key",
+				Usage: "Add the private key of the CA to the bundle, to regenerate the certificates of the machine once imported",
+			},
+		},
+	},
+	{
+		Name:        "import",
+		Usage:       "Import a machine from a bundle",
+		Description: "Argument is the path of a bundle created with export.",
+		Action:      runCommand(cmdImport),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "no-check",
+				Usage: "Do not check the connection to the imported machine",
+			},
+			cli.BoolFlag{
+				Name:  "allow-hooks",
+				Usage: "Import the hooks of the machine, whose commands run on this host",
+			},
+		},
+	},
 	{
 		Name:        "inspect",
 		Usage:       "Inspect information about a machine",
@@ -219,6 +538,10 @@ var Commands = []cli.Command{
 		Usage:       "Kill a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdKill),
+		Flags: []cli.Flag{
+			parallelFlag,
+			summaryFlag,
+		},
 	},
 	{
 		Name:   "ls",
@@ -243,6 +566,62 @@ var Commands = []cli.Command{
 				Name:  "format, f",
 				Usage: "Pretty-print machines using a Go template",
 			},
+			cli.BoolFlag{
+				Name:  "cached",
+				Usage: "Use the state the machines were last seen in instead of asking the drivers",
+			},
+			cli.StringFlag{
+				Name:  "max-age",
+				Usage: "Use the cached state of the machines if it is not older than this duration, e.g. 5m",
+			},
+		},
+	},
+	{
+		Name:        "mv",
+		Usage:       "Rename a machine",
+		Description: "Arguments are the name of the machine and its new name.",
+		Action:      runCommand(cmdMv),
+		Flags: []cli.Flag{
+			timeoutFlag,
+		},
+	},
+	{
+		Name:        "pause",
+		Usage:       "Pause a machine",
+		Description: "Argument(s) are one or more machine names.",
+		Action:      runCommand(cmdPause),
+		Flags: []cli.Flag{
+			parallelFlag,
+			summaryFlag,
+		},
+	},
+	{
+		Name:        "plan",
+		Usage:       "Show the changes needed to match a fleet file",
+		Description: "The fleet file is given with --file, in YAML or in JSON if its extension is .json.",
+		Action:      runCommand(cmdPlan),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file, f",
+				Usage: "Path of the fleet file",
+			},
+			cli.BoolFlag{
+				Name:  "prune",
+				Usage: "Show the machines which are not in the fleet file as removed",
+			},
+		},
+	},
+	{
+		Name:        "reap",
+		Usage:       "Stop idle machines and stop or remove expired machines",
+		Description: "Argument(s) are one or more machine names, all the machines are checked if none is given.",
+		Action:      runCommand(cmdReap),
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Only show what would be done",
+			},
+			timeoutFlag,
 		},
 	},
 	{
@@ -255,6 +634,8 @@ var Commands = []cli.Command{
 				Name:  "force, f",
 				Usage: "Force rebuild and do not prompt",
 			},
+			parallelFlag,
+			summaryFlag,
 		},
 	},
 	{
@@ -262,6 +643,36 @@ var Commands = []cli.Command{
 		Usage:       "Restart a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdRestart),
+		Flags: []cli.Flag{
+			timeoutFlag,
+			parallelFlag,
+			summaryFlag,
+		},
+	},
+	{
+		Name:        "resize",
+		Usage:       "Change the CPUs, memory or disk size of a machine",
+		Description: "Argument is a machine name. A running machine is stopped and started again.",
+		Action:      runCommand(cmdResize),
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "cpus",
+				Usage: "Number of CPUs",
+			},
+			cli.IntFlag{
+				Name:  "memory",
+				Usage: "Size of memory in MB",
+			},
+			cli.IntFlag{
+				Name:  "disk-size",
+				Usage: "Size of disk in MB, disks can only grow",
+			},
+			cli.StringFlag{
+				Name:  "instance-type",
+				Usage: "Instance or machine type of cloud drivers",
+			},
+			timeoutFlag,
+		},
 	},
 	{
 		Flags: []cli.Flag{
@@ -273,12 +684,70 @@ var Commands = []cli.Command{
 				Name:  "y",
 				Usage: "Assumes automatic yes to proceed with remove, without prompting further user confirmation",
 			},
+			parallelFlag,
+			summaryFlag,
 		},
 		Name:        "rm",
 		Usage:       "Remove a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdRm),
 	},
+	{
+		Name:        "serve",
+		Usage:       "Serve the machines to other Docker Machine clients",
+		Description: "Clients connect with --server and a certificate signed by the CA of this store.",
+		Action:      runCommand(cmdServe),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "addr",
+				Usage: "Address to listen on",
+				Value: defaultServeAddr,
+			},
+			cli.StringSliceFlag{
+				Name:  "hostname",
+				Usage: "Name or IP address of the server in its certificate, defaults to the hostname, localhost and 127.0.0.1",
+				Value: &cli.StringSlice{},
+			},
+			cli.StringFlag{
+				Name:  "tls-server-cert",
+				Usage: "Server certificate, created if it does not exist",
+			},
+			cli.StringFlag{
+				Name:  "tls-server-key",
+				Usage: "Private key of the server certificate",
+			},
+		},
+	},
+	{
+		Name:  "snapshot",
+		Usage: "Manage the snapshots of a machine",
+		Subcommands: []cli.Command{
+			{
+				Name:        "create",
+				Usage:       "Save the state of a machine",
+				Description: "Arguments are the machine name and the name of the snapshot.",
+				Action:      runCommand(cmdSnapshotCreate),
+			},
+			{
+				Name:        "ls",
+				Usage:       "List the snapshots of a machine",
+				Description: "Argument is a machine name.",
+				Action:      runCommand(cmdSnapshotLs),
+			},
+			{
+				Name:        "restore",
+				Usage:       "Roll a machine back to a snapshot",
+				Description: "Arguments are the machine name and the name of the snapshot.",
+				Action:      runCommand(cmdSnapshotRestore),
+			},
+			{
+				Name:        "rm",
+				Usage:       "Remove a snapshot of a machine",
+				Description: "Arguments are the machine name and the name of the snapshot.",
+				Action:      runCommand(cmdSnapshotRm),
+			},
+		},
+	},
 	{
 		Name:            "ssh",
 		Usage:           "Log into or run a command on a machine with SSH.",
@@ -303,6 +772,11 @@ var Commands = []cli.Command{
 		Usage:       "Start a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdStart),
+		Flags: []cli.Flag{
+			timeoutFlag,
+			parallelFlag,
+			summaryFlag,
+		},
 	},
 	{
 		Name:        "status",
@@ -315,12 +789,97 @@ var Commands = []cli.Command{
 		Usage:       "Stop a machine",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdStop),
+		Flags: []cli.Flag{
+			timeoutFlag,
+			parallelFlag,
+			summaryFlag,
+		},
+	},
+	{
+		Name:  "store",
+		Usage: "Manage the machine store",
+		Subcommands: []cli.Command{
+			{
+				Name:        "encrypt",
+				Usage:       "Encrypt the credentials of the machines with a new key",
+				Description: "The machines are decrypted with the key given with --store-passphrase or --store-key-file.",
+				Action:      runCommand(cmdStoreEncrypt),
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "new-passphrase",
+						Usage: "Passphrase to encrypt the credentials with",
+					},
+					cli.StringFlag{
+						Name:  "new-key-file",
+						Usage: "Key file to encrypt the credentials with",
+					},
+					cli.BoolFlag{
+						Name:  "decrypt",
+						Usage: "Store the credentials in plaintext",
+					},
+				},
+			},
+			{
+				Name:        "generate-key",
+				Usage:       "Generate a key file to encrypt the credentials of the machines",
+				Description: "Argument is the path of the key file.",
+				Action:      runCommand(cmdStoreGenerateKey),
+			},
+			{
+				Name:        "migrate",
+				Usage:       "Copy the machines to another storage backend",
+				Description: "Machines are copied from the backend selected with --storage-backend.",
+				Action:      runCommand(cmdStoreMigrate),
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "to",
+						Usage: "Storage backend to copy the machines to: [file, db]",
+					},
+				},
+			},
+			{
+				Name:        "relocate",
+				Usage:       "Update the paths of the machines after the store has been moved",
+				Description: "The paths are made relative to the storage path, so that the store can be moved again.",
+				Action:      runCommand(cmdStoreRelocate),
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "from",
+						Usage: "Previous storage path, defaults to the one recorded by each machine",
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "suspend",
+		Usage:       "Save the state of a machine to disk and stop it",
+		Description: "Argument(s) are one or more machine names.",
+		Action:      runCommand(cmdSuspend),
+		Flags: []cli.Flag{
+			parallelFlag,
+			summaryFlag,
+		},
+	},
+	{
+		Name:        "unpause",
+		Usage:       "Unpause a machine",
+		Description: "Argument(s) are one or more machine names.",
+		Action:      runCommand(cmdUnpause),
+		Flags: []cli.Flag{
+			parallelFlag,
+			summaryFlag,
+		},
 	},
 	{
 		Name:        "upgrade",
 		Usage:       "Upgrade a machine to the latest version of Docker",
 		Description: "Argument(s) are one or more machine names.",
 		Action:      runCommand(cmdUpgrade),
+		Flags: []cli.Flag{
+			parallelFlag,
+			summaryFlag,
+		},
 	},
 	{
 		Name:        "url",
@@ -333,6 +892,24 @@ var Commands = []cli.Command{
 		Usage:  "Show the Docker Machine version or a machine docker version",
 		Action: runCommand(cmdVersion),
 	},
+	{
+		Name:        "watch",
+		Usage:       "Watch the health of machines and recover them",
+		Description: "Argument(s) are one or more machine names, all the machines are watched if none is given.",
+		Action:      runCommand(cmdWatch),
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "interval",
+				Usage: "Number of seconds between two checks",
+				Value: 30,
+			},
+			cli.StringFlag{
+				Name:  "restart-policy",
+				Usage: "Restart policy of the machines created without one: no, machine or docker",
+				Value: "no",
+			},
+		},
+	},
 }
 
 func printIP(h *host.Host) func() error {
@@ -350,48 +927,24 @@ func printIP(h *host.Host) func() error {
 
 // machineCommand maps the command name to the corresponding machine command.
 // We run commands concurrently and communicate back an error if there was one.
-func machineCommand(actionName string, host *host.Host, errorChan chan<- error) {
+func machineCommand(actionName string, host *host.Host) error {
 	// TODO: These actions should have their own type.
 	commands := map[string](func() error){
-		"configureAuth": host.ConfigureAuth,
-		"start":         host.Start,
-		"stop":          host.Stop,
-		"restart":       host.Restart,
-		"kill":          host.Kill,
-		"upgrade":       host.Upgrade,
-		"ip":            printIP(host),
+		"ip": printIP(host),
 	}
 
 	log.Debugf("command=%s machine=%s", actionName, host.Name)
 
-	errorChan <- commands[actionName]()
+	return commands[actionName]()
 }
 
 // runActionForeachMachine will run the command across multiple machines
-func runActionForeachMachine(actionName string, machines []*host.Host) []error {
-	var (
-		numConcurrentActions = 0
-		errorChan            = make(chan error)
-		errs                 = []error{}
-	)
-
-	for _, machine := range machines {
-		numConcurrentActions++
-		go machineCommand(actionName, machine, errorChan)
-	}
-
-	// TODO: We should probably only do 5-10 of these
-	// at a time, since otherwise cloud providers might
-	// rate limit us.
-	for i := 0; i < numConcurrentActions; i++ {
-		if err := <-errorChan; err != nil {
-			errs = append(errs, err)
-		}
-	}
-
-	close(errorChan)
+func runActionForeachMachine(ctx context.Context, actionName string, machines []*host.Host) []error {
+	results := batch.ForEach(ctx, machines, batch.DefaultParallel, func(ctx context.Context, h *host.Host) error {
+		return machineCommand(actionName, h)
+	})
 
-	return errs
+	return batch.Errors(results)
 }
 
 func consolidateErrs(errs []error) error {
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"testing"
@@ -9,81 +10,54 @@ import (
 	"github.com/docker/machine/commands/commandstest"
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
 	"github.com/docker/machine/libmachine/crashreport"
-	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/libmachinetest"
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRunActionForeachMachine(t *testing.T) {
-	// Assume a bunch of machines in randomly started or
-	// stopped states.
-	machines := []*host.Host{
-		{
-			Name:       "foo",
-			DriverName: "fakedriver",
-			Driver: &fakedriver.Driver{
-				MockState: state.Running,
-			},
-		},
-		{
-			Name:       "bar",
-			DriverName: "fakedriver",
-			Driver: &fakedriver.Driver{
-				MockState: state.Stopped,
-			},
-		},
-		{
-			Name: "baz",
-			// Ssh, don't tell anyone but this
-			// driver only _thinks_ it's named
-			// virtualbox...  (to test serial actions)
-			// It's actually FakeDriver!
-			DriverName: "virtualbox",
-			Driver: &fakedriver.Driver{
-				MockState: state.Stopped,
-			},
-		},
-		{
-			Name:       "spam",
-			DriverName: "virtualbox",
-			Driver: &fakedriver.Driver{
-				MockState: state.Running,
-			},
-		},
-		{
-			Name:       "eggs",
-			DriverName: "fakedriver",
-			Driver: &fakedriver.Driver{
-				MockState: state.Stopped,
-			},
-		},
-		{
-			Name:       "ham",
-			DriverName: "fakedriver",
-			Driver: &fakedriver.Driver{
-				MockState: state.Running,
-			},
-		},
-	}
+func TestPrintBatchSummaryTable(t *testing.T) {
+	out := &bytes.Buffer{}
 
-	runActionForeachMachine("start", machines)
+	err := printBatchSummary(out, "table", []batch.Result{
+		{Name: "foo", Action: batch.Stop},
+		{Name: "bar", Action: batch.Stop, Err: errors.New("boom")},
+	})
 
-	for _, machine := range machines {
-		machineState, _ := machine.Driver.GetState()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "NAME")
+	assert.Regexp(t, "foo +stop +Success", out.String())
+	assert.Regexp(t, "bar +stop +Error +0s +boom", out.String())
+}
 
-		assert.Equal(t, state.Running, machineState)
-	}
+func TestPrintBatchSummaryJSON(t *testing.T) {
+	out := &bytes.Buffer{}
 
-	runActionForeachMachine("stop", machines)
+	err := printBatchSummary(out, "json", []batch.Result{
+		{Name: "foo", Action: batch.Kill, Err: errors.New("boom")},
+	})
 
-	for _, machine := range machines {
-		machineState, _ := machine.Driver.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"Name":"foo","Action":"kill","Success":false,"Error":"boom","Duration":0}]`+"\n", out.String())
+}
 
-		assert.Equal(t, state.Stopped, machineState)
+func TestRunBatchActionUnknownSummary(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"summary": "xml",
+			},
+		},
 	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := runBatchAction(batch.Stop, commandLine, api)
+
+	assert.EqualError(t, err, `Unknown summary format "xml", expected table or json`)
 }
 
 func TestPrintIPEmptyGivenLocalEngine(t *testing.T) {
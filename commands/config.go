@@ -7,6 +7,7 @@ import (
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/check"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 )
 
 func cmdConfig(c CommandLine, api libmachine.API) error {
@@ -24,6 +25,9 @@ func cmdConfig(c CommandLine, api libmachine.API) error {
 	}
 
 	dockerHost, authOptions, err := check.DefaultConnChecker.Check(host, c.Bool("swarm"))
+	if _, ok := err.(mcnerror.ErrHostPaused); ok {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("Error running connection boilerplate: %s", err)
 	}
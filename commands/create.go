@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"errors"
 
@@ -19,11 +20,13 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/swarm"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -118,10 +121,55 @@ var (
 			Usage: "Support extra SANs for TLS certs",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringSliceFlag{
+			Name:  "hook",
+			Usage: "Run a local command at an event of the machine lifecycle, in the form event=command",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "ssh-hook",
+			Usage: "Run a script on the machine over SSH at an event of its lifecycle, in the form event=script",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "hook-on-failure",
+			Usage: "What to do when a hook fails: abort, warn or ignore",
+			Value: "abort",
+		},
+		cli.StringFlag{
+			Name:  "restart-policy",
+			Usage: "How 'docker-machine watch' recovers the machine when it goes down: no, machine or docker",
+			Value: "no",
+		},
+		cli.IntFlag{
+			Name:  "idle-stop",
+			Usage: "Let 'docker-machine reap' stop the machine after this many minutes without running containers, 0 to never stop it",
+		},
+		cli.StringFlag{
+			Name:  "expire-after",
+			Usage: "Let 'docker-machine reap' stop or remove the machine once this duration passed, e.g. 72h",
+		},
+		cli.StringFlag{
+			Name:  "expire-action",
+			Usage: "What 'docker-machine reap' does to the machine once it expired: stop or rm",
+			Value: "stop",
+		},
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "Abort and remove the machine if it is not ready after this many seconds, 0 to wait indefinitely",
+		},
+		cli.StringFlag{
+			Name:  "resume",
+			Usage: "Resume the creation of the named machine from the stage which failed",
+		},
 	}
 )
 
 func cmdCreateInner(c CommandLine, api libmachine.API) error {
+	if name := c.String("resume"); name != "" {
+		return resumeCreate(c, api, name)
+	}
+
 	if len(c.Args()) > 1 {
 		return fmt.Errorf("Invalid command line. Found extra arguments %v", c.Args()[1:])
 	}
@@ -132,42 +180,29 @@ func cmdCreateInner(c CommandLine, api libmachine.API) error {
 		return errNoMachineName
 	}
 
-	validName := host.ValidateHostName(name)
-	if !validName {
-		return fmt.Errorf("Error creating machine: %s", mcnerror.ErrInvalidHostname)
-	}
+	authOptions := newAuthOptions(c, name)
+	authOptions.ServerCertSANs = c.StringSlice("tls-san")
 
-	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
-		return fmt.Errorf("Error parsing swarm discovery: %s", err)
+	hookOptions, err := newHookOptions(c)
+	if err != nil {
+		return err
 	}
 
-	// TODO: Fix hacky JSON solution
-	rawDriver, err := json.Marshal(&drivers.BaseDriver{
-		MachineName: name,
-		StorePath:   c.GlobalString("storage-path"),
-	})
+	restartPolicy, err := host.ParseRestartPolicy(c.String("restart-policy"))
 	if err != nil {
-		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
+		return err
 	}
 
-	driverName := c.String("driver")
-	h, err := api.NewHost(driverName, rawDriver)
+	reapPolicy, err := newReapPolicy(c, time.Now())
 	if err != nil {
-		return fmt.Errorf("Error getting new host: %s", err)
+		return err
 	}
 
-	h.HostOptions = &host.Options{
-		AuthOptions: &auth.Options{
-			CertDir:          mcndirs.GetMachineCertDir(),
-			CaCertPath:       tlsPath(c, "tls-ca-cert", "ca.pem"),
-			CaPrivateKeyPath: tlsPath(c, "tls-ca-key", "ca-key.pem"),
-			ClientCertPath:   tlsPath(c, "tls-client-cert", "cert.pem"),
-			ClientKeyPath:    tlsPath(c, "tls-client-key", "key.pem"),
-			ServerCertPath:   filepath.Join(mcndirs.GetMachineDir(), name, "server.pem"),
-			ServerKeyPath:    filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem"),
-			StorePath:        filepath.Join(mcndirs.GetMachineDir(), name),
-			ServerCertSANs:   c.StringSlice("tls-san"),
-		},
+	hostOptions := &host.Options{
+		AuthOptions:   authOptions,
+		HookOptions:   hookOptions,
+		RestartPolicy: restartPolicy,
+		ReapPolicy:    reapPolicy,
 		EngineOptions: &engine.Options{
 			ArbitraryFlags:   c.StringSlice("engine-opt"),
 			Env:              c.StringSlice("engine-env"),
@@ -190,6 +225,54 @@ func cmdCreateInner(c CommandLine, api libmachine.API) error {
 		},
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	// driverOpts is the actual data we send over the wire to set the
+	// driver parameters (an interface fulfilling drivers.DriverOptions,
+	// concrete type rpcdriver.RpcFlags).
+	driverOpts := func(mcnFlags []mcnflag.Flag) (drivers.DriverOptions, error) {
+		return getDriverOpts(c, mcnFlags), nil
+	}
+
+	if err := createMachine(ctx, c, api, name, c.String("driver"), hostOptions, driverOpts); err != nil {
+		return err
+	}
+
+	log.Infof("To see how to connect Docker to this machine, run: %s", fmt.Sprintf("%s env %s", os.Args[0], name))
+
+	return nil
+}
+
+// createMachine creates the machine name with the driver driverName and
+// saves it. It is shared by create and apply: the name and the Swarm
+// discovery are checked, then the driver is configured with the options
+// driverOpts returns for its create flags.
+func createMachine(ctx context.Context, c CommandLine, api libmachine.API, name, driverName string, hostOptions *host.Options, driverOpts func([]mcnflag.Flag) (drivers.DriverOptions, error)) error {
+	if !host.ValidateHostName(name) {
+		return fmt.Errorf("Error creating machine: %s", mcnerror.ErrInvalidHostname)
+	}
+
+	if err := validateSwarmDiscovery(hostOptions.SwarmOptions.Discovery); err != nil {
+		return fmt.Errorf("Error parsing swarm discovery: %s", err)
+	}
+
+	// TODO: Fix hacky JSON solution
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: name,
+		StorePath:   c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
+	}
+
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		return fmt.Errorf("Error getting new host: %s", err)
+	}
+
+	h.HostOptions = hostOptions
+
 	exists, err := api.Exists(h.Name)
 	if err != nil {
 		return fmt.Errorf("Error checking if host exists: %s", err)
@@ -200,17 +283,17 @@ func cmdCreateInner(c CommandLine, api libmachine.API) error {
 		}
 	}
 
-	// driverOpts is the actual data we send over the wire to set the
-	// driver parameters (an interface fulfilling drivers.DriverOptions,
-	// concrete type rpcdriver.RpcFlags).
-	mcnFlags := h.Driver.GetCreateFlags()
-	driverOpts := getDriverOpts(c, mcnFlags)
+	opts, err := driverOpts(h.Driver.GetCreateFlags())
+	if err != nil {
+		return err
+	}
 
-	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
+	if err := h.Driver.SetConfigFromFlags(opts); err != nil {
 		return fmt.Errorf("Error setting machine configuration from flags provided: %s", err)
 	}
 
-	if err := api.Create(h); err != nil {
+	if err := api.CreateContext(ctx, h); err != nil {
+		logResumeHint(api, h)
 		return fmt.Errorf("Error creating machine: %s", err)
 	}
 
@@ -218,11 +301,49 @@ func cmdCreateInner(c CommandLine, api libmachine.API) error {
 		return fmt.Errorf("Error attempting to save store: %s", err)
 	}
 
+	return nil
+}
+
+func resumeCreate(c CommandLine, api libmachine.API, name string) error {
+	if len(c.Args()) > 0 {
+		return fmt.Errorf("Invalid command line. Found extra arguments %v", c.Args())
+	}
+
+	h, err := api.Load(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	if err := api.ResumeCreateContext(ctx, h); err != nil {
+		logResumeHint(api, h)
+		return fmt.Errorf("Error resuming machine creation: %s", err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error attempting to save store: %s", err)
+	}
+
 	log.Infof("To see how to connect Docker to this machine, run: %s", fmt.Sprintf("%s env %s", os.Args[0], name))
 
 	return nil
 }
 
+func logResumeHint(api libmachine.API, h *host.Host) {
+	if !h.IsPartiallyCreated() {
+		return
+	}
+
+	// Aborted creations are cleaned up, there is nothing left to resume.
+	if exists, err := api.Exists(h.Name); err != nil || !exists {
+		return
+	}
+
+	log.Infof("The machine was kept. Once the problem is fixed, run '%s create --resume %s' to continue from where it failed, or '%s rm %s' to remove it.", os.Args[0], h.Name, os.Args[0], h.Name)
+}
+
 // The following function is needed because the CLI acrobatics that we're doing
 // (with having an "outer" and "inner" function each with their own custom
 // settings and flag parsing needs) are not well supported by codegangsta/cli.
@@ -264,8 +385,18 @@ func cmdCreateOuter(c CommandLine, api libmachine.API) error {
 		flagLookupMachineName = "flag-lookup"
 	)
 
-	// We didn't recognize the driver name.
 	driverName := flagHackLookup("--driver")
+
+	// Resuming uses the driver the machine was created with.
+	if resumeName := flagHackLookup("--resume"); resumeName != "" && driverName == "" {
+		h, err := api.Load(resumeName)
+		if err != nil {
+			return err
+		}
+		driverName = h.DriverName
+	}
+
+	// We didn't recognize the driver name.
 	if driverName == "" {
 		c.ShowHelp()
 		return nil // ?
@@ -418,6 +549,20 @@ func validateSwarmDiscovery(discovery string) error {
 	return fmt.Errorf("Swarm Discovery URL was in the wrong format: %s", discovery)
 }
 
+// newAuthOptions returns the paths of the certificates of a new machine.
+func newAuthOptions(c CommandLine, name string) *auth.Options {
+	return &auth.Options{
+		CertDir:          mcndirs.GetMachineCertDir(),
+		CaCertPath:       tlsPath(c, "tls-ca-cert", "ca.pem"),
+		CaPrivateKeyPath: tlsPath(c, "tls-ca-key", "ca-key.pem"),
+		ClientCertPath:   tlsPath(c, "tls-client-cert", "cert.pem"),
+		ClientKeyPath:    tlsPath(c, "tls-client-key", "key.pem"),
+		ServerCertPath:   filepath.Join(mcndirs.GetMachineDir(), name, "server.pem"),
+		ServerKeyPath:    filepath.Join(mcndirs.GetMachineDir(), name, "server-key.pem"),
+		StorePath:        filepath.Join(mcndirs.GetMachineDir(), name),
+	}
+}
+
 func tlsPath(c CommandLine, flag string, defaultName string) string {
 	path := c.GlobalString(flag)
 	if path != "" {
@@ -426,3 +571,65 @@ func tlsPath(c CommandLine, flag string, defaultName string) string {
 
 	return filepath.Join(mcndirs.GetMachineCertDir(), defaultName)
 }
+
+// newHookOptions returns the hooks given with --hook and --ssh-hook, which
+// all fail with the policy given with --hook-on-failure.
+func newHookOptions(c CommandLine) (*hook.Options, error) {
+	policy := hook.Policy(c.String("hook-on-failure"))
+	hooks := []hook.Hook{}
+
+	for _, value := range c.StringSlice("hook") {
+		h, err := hook.Parse(value, false, policy)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+
+	for _, value := range c.StringSlice("ssh-hook") {
+		h, err := hook.Parse(value, true, policy)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+
+	return &hook.Options{Hooks: hooks}, nil
+}
+
+// newReapPolicy returns the policy given with --idle-stop, --expire-after and
+// --expire-action, nil if the machine is never reaped.
+func newReapPolicy(c CommandLine, now time.Time) (*host.ReapPolicy, error) {
+	idleMinutes := c.Int("idle-stop")
+	if idleMinutes < 0 {
+		return nil, errors.New("Error: The idle time must not be negative")
+	}
+
+	action, err := host.ParseExpiryAction(c.String("expire-action"))
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &host.ReapPolicy{
+		IdleMinutes:  idleMinutes,
+		ExpiryAction: action,
+	}
+
+	if value := c.String("expire-after"); value != "" {
+		after, err := time.ParseDuration(value)
+		if err != nil || after <= 0 {
+			return nil, fmt.Errorf("Error: Invalid expiry duration %q", value)
+		}
+		policy.ExpiresAt = now.Add(after)
+	}
+
+	if !policy.StopsWhenIdle() && !policy.Expires() {
+		return nil, nil
+	}
+
+	return policy, nil
+}
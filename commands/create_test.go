@@ -2,7 +2,15 @@ package commands
 
 import (
 	"testing"
+	"time"
 
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,3 +28,142 @@ func TestValidateSwarmDiscoveryAcceptsValidFormat(t *testing.T) {
 	err := validateSwarmDiscovery("token://deadbeefcafe")
 	assert.NoError(t, err)
 }
+
+func TestCmdCreateResume(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"resume": "partial",
+			},
+		},
+	}
+	partial := &host.Host{
+		Name:        "partial",
+		Driver:      &fakedriver.Driver{MockState: state.Running},
+		CreateStage: event.WaitForSSH,
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{partial},
+	}
+
+	err := cmdCreateInner(commandLine, api)
+
+	assert.NoError(t, err)
+	assert.False(t, partial.IsPartiallyCreated())
+}
+
+func TestCmdCreateResumeMissingMachine(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"resume": "missing",
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdCreateInner(commandLine, api)
+
+	assert.EqualError(t, err, `Host does not exist: "missing"`)
+}
+
+func TestNewHookOptions(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"hook":            []string{"post-create=./register-dns.sh", "post-rm=./unregister-dns.sh"},
+				"ssh-hook":        []string{"post-start=sudo /etc/init.d/agent start"},
+				"hook-on-failure": "warn",
+			},
+		},
+	}
+
+	opts, err := newHookOptions(commandLine)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &hook.Options{
+		Hooks: []hook.Hook{
+			{Event: hook.PostCreate, Command: "./register-dns.sh", OnFailure: hook.Warn},
+			{Event: hook.PostRemove, Command: "./unregister-dns.sh", OnFailure: hook.Warn},
+			{Event: hook.PostStart, Script: "sudo /etc/init.d/agent start", OnFailure: hook.Warn},
+		},
+	}, opts)
+}
+
+func TestNewHookOptionsNone(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+
+	opts, err := newHookOptions(commandLine)
+
+	assert.NoError(t, err)
+	assert.Nil(t, opts)
+}
+
+func TestNewHookOptionsInvalid(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"ssh-hook": []string{"pre-create=hostname"},
+			},
+		},
+	}
+
+	_, err := newHookOptions(commandLine)
+
+	assert.EqualError(t, err, "The machine is not running at pre-create, a script can not be run on it")
+}
+
+func TestNewReapPolicy(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"idle-stop":     30,
+				"expire-after":  "72h",
+				"expire-action": "rm",
+			},
+		},
+	}
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	policy, err := newReapPolicy(commandLine, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &host.ReapPolicy{
+		IdleMinutes:  30,
+		ExpiresAt:    time.Date(2016, 3, 4, 12, 0, 0, 0, time.UTC),
+		ExpiryAction: host.ExpiryRemove,
+	}, policy)
+}
+
+func TestNewReapPolicyNone(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"expire-action": "stop",
+			},
+		},
+	}
+
+	policy, err := newReapPolicy(commandLine, time.Now())
+
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+}
+
+func TestNewReapPolicyInvalid(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"expire-after": "3 days",
+			},
+		},
+	}
+
+	_, err := newReapPolicy(commandLine, time.Now())
+
+	assert.EqualError(t, err, `Error: Invalid expiry duration "3 days"`)
+}
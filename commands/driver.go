@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+)
+
+var errNoDriverName = errors.New("Error: No driver name specified")
+
+// DriverInfo describes a driver found on the system.
+type DriverInfo struct {
+	Name string
+	Path string
+	Core bool
+
+	// Managed is true for the drivers installed with `driver install`.
+	Managed bool `json:",omitempty"`
+
+	// Version is the version of Docker Machine the driver was built with.
+	Version string
+
+	// APIVersion is the version of the RPC protocol spoken by the driver.
+	APIVersion int
+
+	// Capabilities are the optional features of the driver, nil if it can
+	// not tell.
+	Capabilities []string `json:",omitempty"`
+
+	Flags []DriverFlag `json:",omitempty"`
+	Error string       `json:",omitempty"`
+}
+
+// DriverFlag is a create flag of a driver.
+type DriverFlag struct {
+	Name    string
+	Type    string
+	Default interface{}
+	EnvVar  string `json:",omitempty"`
+	Usage   string
+}
+
+func (d DriverInfo) Type() string {
+	switch {
+	case d.Core:
+		return "core"
+	case d.Managed:
+		return "installed"
+	}
+	return "plugin"
+}
+
+// pluginDriver is implemented by the drivers served by a plugin binary.
+type pluginDriver interface {
+	APIVersion() int
+	MachineVersion() (string, error)
+}
+
+func cmdDriverLs(c CommandLine, api libmachine.API) error {
+	format, err := driverOutputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	infos := []DriverInfo{}
+	for _, binary := range localbinary.FindDrivers() {
+		infos = append(infos, describeDriver(api, binary))
+	}
+
+	if format == "json" {
+		return json.NewEncoder(os.Stdout).Encode(infos)
+	}
+
+	return printDrivers(os.Stdout, infos)
+}
+
+func cmdDriverInfo(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errNoDriverName
+	}
+
+	format, err := driverOutputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().First()
+	path, err := localbinary.FindDriver(name)
+	if err != nil {
+		return err
+	}
+
+	info := describeDriver(api, localbinary.Binary{
+		Name:    name,
+		Path:    path,
+		Core:    localbinary.IsCoreDriver(name),
+		Managed: path == localbinary.ManagedBinaryPath(name),
+	})
+
+	if format == "json" {
+		return json.NewEncoder(os.Stdout).Encode(info)
+	}
+
+	return printDriverInfo(os.Stdout, info)
+}
+
+func driverOutputFormat(c CommandLine) (string, error) {
+	format := c.String("format")
+	switch format {
+	case "", "table":
+		return "table", nil
+	case "json":
+		// Keep stdout machine-readable.
+		log.SetOutWriter(os.Stderr)
+		return format, nil
+	}
+
+	return "", fmt.Errorf("Unknown format %q, expected table or json", format)
+}
+
+// describeDriver starts the driver to ask for its versions and create
+// flags. A driver which can not be started is described with its error.
+func describeDriver(api libmachine.API, binary localbinary.Binary) DriverInfo {
+	info := DriverInfo{
+		Name:    binary.Name,
+		Path:    binary.Path,
+		Core:    binary.Core,
+		Managed: binary.Managed,
+	}
+
+	if binary.Path == "" {
+		if _, err := localbinary.FindDriver(binary.Name); err != nil {
+			info.Error = err.Error()
+		}
+		return info
+	}
+
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: "driver-info",
+	})
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	h, err := api.NewHost(binary.Name, rawDriver)
+	if err != nil {
+		if incompatible, ok := err.(rpcdriver.ErrIncompatibleAPIVersion); ok {
+			info.APIVersion = incompatible.Version
+		}
+		info.Error = err.Error()
+		return info
+	}
+
+	if d, ok := h.Driver.(pluginDriver); ok {
+		info.APIVersion = d.APIVersion()
+		if info.Version, err = d.MachineVersion(); err != nil {
+			log.Debugf("Error getting the version of driver %q: %s", binary.Name, err)
+		}
+	}
+
+	if capabilities, known := drivers.Capabilities(h.Driver); known {
+		info.Capabilities = append([]string{}, capabilities...)
+	}
+
+	for _, f := range h.Driver.GetCreateFlags() {
+		info.Flags = append(info.Flags, newDriverFlag(f))
+	}
+
+	return info
+}
+
+func newDriverFlag(f mcnflag.Flag) DriverFlag {
+	flag := DriverFlag{
+		Name:    f.String(),
+		Default: f.Default(),
+	}
+
+	switch f := f.(type) {
+	case *mcnflag.BoolFlag:
+		flag.Type, flag.EnvVar, flag.Usage = "bool", f.EnvVar, f.Usage
+	case *mcnflag.IntFlag:
+		flag.Type, flag.EnvVar, flag.Usage = "int", f.EnvVar, f.Usage
+	case *mcnflag.StringFlag:
+		flag.Type, flag.EnvVar, flag.Usage = "string", f.EnvVar, f.Usage
+	case *mcnflag.StringSliceFlag:
+		flag.Type, flag.EnvVar, flag.Usage = "string slice", f.EnvVar, f.Usage
+	}
+
+	return flag
+}
+
+func printDrivers(out io.Writer, infos []DriverInfo) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tVERSION\tAPI\tPATH\tERROR")
+
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", info.Name, info.Type(), orUnknown(info.Version), apiVersion(info), info.Path, info.Error)
+	}
+
+	return w.Flush()
+}
+
+func printDriverInfo(out io.Writer, info DriverInfo) error {
+	w := tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", info.Name)
+	fmt.Fprintf(w, "Type:\t%s\n", info.Type())
+	fmt.Fprintf(w, "Path:\t%s\n", info.Path)
+	fmt.Fprintf(w, "Version:\t%s\n", orUnknown(info.Version))
+	fmt.Fprintf(w, "API version:\t%s\n", apiVersion(info))
+	fmt.Fprintf(w, "Capabilities:\t%s\n", capabilities(info))
+	if info.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", info.Error)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(info.Flags) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out, "\nCreate flags:")
+
+	w = tabwriter.NewWriter(out, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tTYPE\tDEFAULT\tENV\tUSAGE")
+	for _, f := range info.Flags {
+		fmt.Fprintf(w, "--%s\t%s\t%v\t%s\t%s\n", f.Name, f.Type, f.Default, f.EnvVar, f.Usage)
+	}
+
+	return w.Flush()
+}
+
+func capabilities(info DriverInfo) string {
+	switch {
+	case info.Capabilities == nil:
+		return "unknown"
+	case len(info.Capabilities) == 0:
+		return "none"
+	}
+	return strings.Join(info.Capabilities, ", ")
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+func apiVersion(info DriverInfo) string {
+	if info.APIVersion == 0 {
+		return "unknown"
+	}
+	return fmt.Sprint(info.APIVersion)
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers/plugin/installer"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	errNoDriverSource  = errors.New("Error: Expected the path or URL of a driver binary as an argument")
+	errNoPublicKey     = errors.New("Error: A signature is verified with the key given with --public-key")
+	errRemotePublicKey = errors.New("Error: The public key must be a local file, not a URL")
+)
+
+// errDriverUnusable is returned when an installed binary can not be used, in
+// which case it is removed and the previous binary is restored.
+type errDriverUnusable struct {
+	Name    string
+	Upgrade bool
+	Err     string
+}
+
+func (e errDriverUnusable) Error() string {
+	if e.Upgrade {
+		return fmt.Sprintf("Error: The new binary of driver %q can not be used, the previous one was restored: %s", e.Name, e.Err)
+	}
+	return fmt.Sprintf("Error: Driver %q can not be used, it was not installed: %s", e.Name, e.Err)
+}
+
+func cmdDriverInstall(c CommandLine, api libmachine.API) error {
+	return installDriver(c, api, false)
+}
+
+func cmdDriverUpgrade(c CommandLine, api libmachine.API) error {
+	return installDriver(c, api, true)
+}
+
+func cmdDriverUninstall(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errNoDriverName
+	}
+
+	name := c.Args().First()
+	if err := installer.Uninstall(name); err != nil {
+		return err
+	}
+
+	log.Infof("Driver %q was uninstalled.", name)
+
+	return nil
+}
+
+func installDriver(c CommandLine, api libmachine.API, upgrade bool) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errNoDriverSource
+	}
+
+	source := c.Args().First()
+	name := c.String("name")
+	if name == "" {
+		var err error
+		if name, err = installer.DriverName(source); err != nil {
+			return err
+		}
+	}
+
+	verifier, err := newVerifier(c)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Fetching %s...", source)
+	data, err := installer.Fetch(source)
+	if err != nil {
+		return err
+	}
+
+	if err := verifier.Verify(data); err != nil {
+		return err
+	}
+
+	path, err := installer.Install(name, data, upgrade, func(path string) error {
+		info := describeDriver(api, localbinary.Binary{Name: name, Path: path, Managed: true})
+		if info.Error != "" {
+			return errDriverUnusable{Name: name, Upgrade: upgrade, Err: info.Error}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if upgrade {
+		log.Infof("Driver %q was upgraded in %s.", name, path)
+	} else {
+		log.Infof("Driver %q was installed in %s.", name, path)
+	}
+
+	return nil
+}
+
+// newVerifier returns the checks given with --sha256, --signature and
+// --public-key. The signature may be a path or a URL, the public key must be
+// a local file.
+func newVerifier(c CommandLine) (*installer.Verifier, error) {
+	verifier := &installer.Verifier{
+		SHA256: c.String("sha256"),
+	}
+
+	// Fail before downloading anything.
+	if verifier.SHA256 == "" && c.String("signature") == "" {
+		return nil, installer.ErrUnverified
+	}
+
+	if source := c.String("signature"); source != "" {
+		keyPath := c.String("public-key")
+		if keyPath == "" {
+			return nil, errNoPublicKey
+		}
+
+		// A key downloaded along with the binary would prove nothing.
+		if installer.IsURL(keyPath) {
+			return nil, errRemotePublicKey
+		}
+
+		var err error
+		if verifier.Signature, err = installer.Fetch(source); err != nil {
+			return nil, err
+		}
+		if verifier.PublicKey, err = ioutil.ReadFile(keyPath); err != nil {
+			return nil, fmt.Errorf("Error reading the public key: %s", err)
+		}
+	}
+
+	return verifier, nil
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers/plugin/installer"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func withPluginDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "machine-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := localbinary.PluginDir
+	localbinary.PluginDir = filepath.Join(dir, "plugins")
+
+	return dir, func() {
+		localbinary.PluginDir = previous
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCmdDriverInstallUnverified(t *testing.T) {
+	dir, cleanup := withPluginDir(t)
+	defer cleanup()
+
+	source := filepath.Join(dir, "docker-machine-driver-foo")
+	ioutil.WriteFile(source, []byte("foo"), 0755)
+
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs:    []string{source},
+		LocalFlags: &commandstest.FakeFlagger{Data: map[string]interface{}{}},
+	}
+
+	err := cmdDriverInstall(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, installer.ErrUnverified, err)
+	_, err = os.Stat(localbinary.ManagedBinaryPath("foo"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCmdDriverInstallChecksumMismatch(t *testing.T) {
+	dir, cleanup := withPluginDir(t)
+	defer cleanup()
+
+	source := filepath.Join(dir, "docker-machine-driver-foo")
+	ioutil.WriteFile(source, []byte("foo"), 0755)
+
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{source},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"sha256": fmt.Sprintf("%x", sha256.Sum256([]byte("bar"))),
+			},
+		},
+	}
+
+	err := cmdDriverInstall(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Error(t, err)
+	_, err = os.Stat(localbinary.ManagedBinaryPath("foo"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCmdDriverInstallSignatureWithoutKey(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"docker-machine-driver-foo"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"signature": "docker-machine-driver-foo.sig",
+			},
+		},
+	}
+
+	err := cmdDriverInstall(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errNoPublicKey, err)
+}
+
+func TestCmdDriverInstallRemotePublicKey(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"https://example.com/docker-machine-driver-foo"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"signature":  "https://example.com/docker-machine-driver-foo.sig",
+				"public-key": "https://example.com/key.pem",
+			},
+		},
+	}
+
+	err := cmdDriverInstall(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errRemotePublicKey, err)
+}
+
+// brokenPluginAPI fails to start the plugin binaries.
+type brokenPluginAPI struct {
+	*libmachinetest.FakeAPI
+}
+
+func (api *brokenPluginAPI) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	return nil, errors.New("exec format error")
+}
+
+func TestCmdDriverInstallUnusable(t *testing.T) {
+	dir, cleanup := withPluginDir(t)
+	defer cleanup()
+
+	source := filepath.Join(dir, "docker-machine-driver-foo")
+	ioutil.WriteFile(source, []byte("foo"), 0755)
+
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{source},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"sha256": fmt.Sprintf("%x", sha256.Sum256([]byte("foo"))),
+			},
+		},
+	}
+
+	err := cmdDriverInstall(commandLine, &brokenPluginAPI{&libmachinetest.FakeAPI{}})
+
+	assert.Equal(t, errDriverUnusable{Name: "foo", Err: "exec format error"}, err)
+	_, err = os.Stat(localbinary.ManagedBinaryPath("foo"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCmdDriverUninstallNotInstalled(t *testing.T) {
+	_, cleanup := withPluginDir(t)
+	defer cleanup()
+
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"foo"},
+	}
+
+	err := cmdDriverUninstall(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, installer.ErrNotInstalled{Name: "foo"}, err)
+}
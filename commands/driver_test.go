@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDriverFlag(t *testing.T) {
+	flag := newDriverFlag(&mcnflag.IntFlag{
+		Name:   "virtualbox-memory",
+		Usage:  "Size of memory for host in MB",
+		EnvVar: "VIRTUALBOX_MEMORY_SIZE",
+		Value:  1024,
+	})
+
+	assert.Equal(t, DriverFlag{
+		Name:    "virtualbox-memory",
+		Type:    "int",
+		Default: 1024,
+		EnvVar:  "VIRTUALBOX_MEMORY_SIZE",
+		Usage:   "Size of memory for host in MB",
+	}, flag)
+}
+
+func TestPrintDrivers(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := printDrivers(out, []DriverInfo{
+		{Name: "none", Path: "/usr/bin/docker-machine", Core: true, Version: "0.6.0", APIVersion: 1},
+		{Name: "foo", Path: "/opt/bin/docker-machine-driver-foo", APIVersion: 1},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   TYPE     VERSION   API   PATH                                 ERROR
+none   core     0.6.0     1     /usr/bin/docker-machine              
+foo    plugin   unknown   1     /opt/bin/docker-machine-driver-foo   
+`, out.String())
+}
+
+func TestPrintDriverInfo(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := printDriverInfo(out, DriverInfo{
+		Name:         "foo",
+		Path:         "/opt/bin/docker-machine-driver-foo",
+		Version:      "0.6.0",
+		APIVersion:   1,
+		Capabilities: []string{"snapshots", "pausing"},
+		Flags: []DriverFlag{
+			{Name: "foo-token", Type: "string", Default: "", EnvVar: "FOO_TOKEN", Usage: "API token"},
+			{Name: "foo-disk", Type: "int", Default: 20, Usage: "Disk size in GB"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Name:           foo
+Type:           plugin
+Path:           /opt/bin/docker-machine-driver-foo
+Version:        0.6.0
+API version:    1
+Capabilities:   snapshots, pausing
+
+Create flags:
+FLAG          TYPE     DEFAULT   ENV         USAGE
+--foo-token   string             FOO_TOKEN   API token
+--foo-disk    int      20                    Disk size in GB
+`, out.String())
+}
+
+func TestCmdDriverInfoInvalidFormat(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"none"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"format": "yaml",
+			},
+		},
+	}
+
+	err := cmdDriverInfo(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.EqualError(t, err, `Unknown format "yaml", expected table or json`)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/check"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/shell"
 )
 
@@ -78,6 +79,9 @@ func shellCfgSet(c CommandLine, api libmachine.API) (*ShellConfig, error) {
 	}
 
 	dockerHost, _, err := check.DefaultConnChecker.Check(host, c.Bool("swarm"))
+	if _, ok := err.(mcnerror.ErrHostPaused); ok {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Error checking TLS connection: %s", err)
 	}
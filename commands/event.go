@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// subscribeOnce makes sure that the CLI consumes the events of the default
+// dispatcher once, since "create" re-enters runCommand once the driver flags
+// are known.
+var subscribeOnce sync.Once
+
+// subscriber is implemented by the APIs which publish the events of their
+// own machines.
+type subscriber interface {
+	Subscribe(handler event.Handler) func()
+}
+
+// subscribeLogEvent logs the events of the machines of api. The returned
+// function stops logging them.
+func subscribeLogEvent(api libmachine.API) func() {
+	if s, ok := api.(subscriber); ok {
+		return s.Subscribe(logEvent)
+	}
+
+	subscribeOnce.Do(func() {
+		event.Subscribe(logEvent)
+	})
+	return func() {}
+}
+
+// logEvent is the CLI consumer of the libmachine lifecycle events. It turns
+// them into the progress messages users are used to.
+func logEvent(e event.Event) {
+	switch e.Type {
+	case event.Started:
+		logStageStarted(e)
+	case event.Finished:
+		logStageFinished(e)
+	case event.Failed:
+		log.Debugf("(%s) Stage %s failed after %s: %s", e.HostName, e.Stage, e.Duration, e.Err)
+	case event.Changed:
+		log.Infof("%s Machine %q is %s.", e.Time.Format(time.RFC3339), e.HostName, e.Detail)
+	}
+}
+
+func logStageStarted(e event.Event) {
+	switch e.Stage {
+	case event.PreCreateCheck:
+		log.Info("Running pre-create checks...")
+	case event.Create:
+		log.Info("Creating machine...")
+	case event.WaitForRunning:
+		log.Info("Waiting for machine to be running, this may take a few minutes...")
+	case event.WaitForSSH:
+		log.Info("Machine is running, waiting for SSH to be available...")
+	case event.DetectProvisioner:
+		log.Info("Detecting operating system of created instance...")
+	case event.Provision:
+		log.Infof("Provisioning with %s...", e.Detail)
+	case event.CheckConnection:
+		log.Info("Checking connection to Docker...")
+	case event.Start:
+		log.Infof("Starting %q...", e.HostName)
+	case event.Stop:
+		log.Infof("Stopping %q...", e.HostName)
+	case event.Kill:
+		log.Infof("Killing %q...", e.HostName)
+	case event.Restart:
+		log.Infof("Restarting %q...", e.HostName)
+	case event.Upgrade:
+		log.Info("Upgrading docker...")
+	case event.Pause:
+		log.Infof("Pausing %q...", e.HostName)
+	case event.Unpause:
+		log.Infof("Unpausing %q...", e.HostName)
+	case event.Suspend:
+		log.Infof("Suspending %q...", e.HostName)
+	case event.Resize:
+		log.Infof("Resizing %q...", e.HostName)
+	case event.Clone:
+		log.Infof("Cloning %q...", e.HostName)
+	case event.Rename:
+		log.Infof("Renaming %q...", e.HostName)
+	case event.Hook:
+		log.Infof("Running %s hook of %q...", e.Detail, e.HostName)
+	case event.RestartDocker:
+		log.Infof("Restarting Docker on %q...", e.HostName)
+	}
+}
+
+func logStageFinished(e event.Event) {
+	log.Debugf("(%s) Stage %s finished in %s", e.HostName, e.Stage, e.Duration)
+
+	switch e.Stage {
+	case event.CheckConnection:
+		log.Info("Docker is up and running!")
+	case event.Start:
+		log.Infof("Machine %q was started.", e.HostName)
+	case event.Stop:
+		log.Infof("Machine %q was stopped.", e.HostName)
+	case event.Kill:
+		log.Infof("Machine %q was killed.", e.HostName)
+	case event.Pause:
+		log.Infof("Machine %q was paused.", e.HostName)
+	case event.Unpause:
+		log.Infof("Machine %q was unpaused.", e.HostName)
+	case event.Suspend:
+		log.Infof("Machine %q was suspended.", e.HostName)
+	case event.Resize:
+		log.Infof("Machine %q was resized.", e.HostName)
+	}
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/bundle"
+	"github.com/docker/machine/libmachine/log"
+)
+
+func cmdExport(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return ErrExpectedOneMachine
+	}
+
+	name := c.Args().First()
+
+	store, err := globalStore(c)
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output == "" {
+		output = name + ".tar.gz"
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Error creating the bundle: %s", err)
+	}
+
+	err = bundle.Export(f, store, c.GlobalString("storage-path"), name, bundle.ExportOptions{
+		Redact:       c.Bool("redact"),
+		IncludeCAKey: c.Bool("include-ca-key"),
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("Error exporting %q: %s", name, err)
+	}
+
+	log.Infof("Machine %q was exported to %s", name, output)
+	if !c.Bool("redact") {
+		log.Info("The bundle contains the credentials of the machine, share it carefully.")
+	}
+	if c.Bool("include-ca-key") {
+		log.Info("The bundle contains the private key of the CA, which signs the certificates of every machine of this host.")
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/bundle"
+	"github.com/docker/machine/libmachine/check"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var errExpectedBundle = errors.New("Error: Expected to get the path of a bundle as argument")
+
+func cmdImport(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errExpectedBundle
+	}
+
+	store, err := globalStore(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("Error opening the bundle: %s", err)
+	}
+	defer f.Close()
+
+	manifest, h, err := bundle.Import(f, store, c.GlobalString("storage-path"), bundle.ImportOptions{
+		AllowHooks: c.Bool("allow-hooks"),
+	})
+	if err != nil {
+		if _, ok := err.(bundle.ErrHooks); ok {
+			return fmt.Errorf("Error importing %s: %s\nUse --allow-hooks to import it if you trust the bundle.", c.Args().First(), err)
+		}
+		return fmt.Errorf("Error importing %s: %s", c.Args().First(), err)
+	}
+
+	if manifest.Redacted {
+		log.Warnf("The credentials of machine %q were removed when it was exported, the driver will not be able to manage it.", h.Name)
+	}
+
+	if !c.Bool("no-check") {
+		if err := checkImportedHost(api, h.Name); err != nil {
+			if removeErr := store.Remove(h.Name); removeErr != nil {
+				log.Errorf("Error removing %q: %s", h.Name, removeErr)
+			}
+			return fmt.Errorf("Machine %q was not imported, unable to connect to it: %s\nUse --no-check to import it anyway, e.g. if it is stopped.", h.Name, err)
+		}
+	}
+
+	log.Infof("Machine %q was imported.", h.Name)
+
+	return nil
+}
+
+func checkImportedHost(api libmachine.API, name string) error {
+	h, err := api.Load(name)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = check.DefaultConnChecker.Check(h, false)
+	return err
+}
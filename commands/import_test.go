@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdImportMissingBundle(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdImport(commandLine, api)
+
+	assert.Equal(t, errExpectedBundle, err)
+}
+
+func TestCmdExportImport(t *testing.T) {
+	srcDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(srcDir)
+
+	dstDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dstDir)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.HostOptions.AuthOptions.CaCertPath = ""
+
+	if err := persist.NewFilestore(srcDir, "", "").Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	bundlePath := filepath.Join(srcDir, "bundle.tar.gz")
+
+	err = cmdExport(&commandstest.FakeCommandLine{
+		CliArgs: []string{h.Name},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"output": bundlePath,
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"storage-path": srcDir,
+			},
+		},
+	}, &libmachinetest.FakeAPI{})
+	assert.NoError(t, err)
+
+	importCommandLine := func(noCheck bool) CommandLine {
+		return &commandstest.FakeCommandLine{
+			CliArgs: []string{bundlePath},
+			LocalFlags: &commandstest.FakeFlagger{
+				Data: map[string]interface{}{
+					"no-check": noCheck,
+				},
+			},
+			GlobalFlags: &commandstest.FakeFlagger{
+				Data: map[string]interface{}{
+					"storage-path": dstDir,
+				},
+			},
+		}
+	}
+
+	dst := persist.NewFilestore(dstDir, "", "")
+
+	// The fake API does not know about the imported machine, so the
+	// connection check fails and the machine is removed.
+	err = cmdImport(importCommandLine(false), &libmachinetest.FakeAPI{})
+	assert.Error(t, err)
+	exists, _ := dst.Exists(h.Name)
+	assert.False(t, exists)
+
+	err = cmdImport(importCommandLine(true), &libmachinetest.FakeAPI{})
+	assert.NoError(t, err)
+	exists, _ = dst.Exists(h.Name)
+	assert.True(t, exists)
+}
@@ -1,7 +1,10 @@
 package commands
 
-import "github.com/docker/machine/libmachine"
+import (
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
+)
 
 func cmdKill(c CommandLine, api libmachine.API) error {
-	return runAction("kill", c, api)
+	return runBatchAction(batch.Kill, c, api)
 }
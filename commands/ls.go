@@ -3,6 +3,8 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"regexp"
 	"sort"
@@ -33,6 +35,11 @@ const (
 
 var (
 	stateTimeoutDuration = lsDefaultTimeout * time.Second
+
+	// useCachedState makes ls use the state the machines were last seen
+	// in if it is not older than cacheMaxAge, zero meaning any age.
+	useCachedState = false
+	cacheMaxAge    time.Duration
 )
 
 // FilterOptions -
@@ -57,6 +64,15 @@ type HostListItem struct {
 	EngineOptions *engine.Options
 	Error         string
 	DockerVersion string
+
+	// CacheAge is how long ago the state was observed when it comes from
+	// the cache, and empty otherwise.
+	CacheAge string
+
+	// IdleStop and Expires describe the reap policy of the machine, and are
+	// empty if it has none.
+	IdleStop string
+	Expires  string
 }
 
 type Headers struct {
@@ -72,12 +88,26 @@ type Headers struct {
 	EngineOptions string
 	Error         string
 	DockerVersion string
+	CacheAge      string
+	IdleStop      string
+	Expires       string
 }
 
 func cmdLs(c CommandLine, api libmachine.API) error {
 	stateTimeoutDuration = time.Duration(c.Int("timeout")) * time.Second
 	log.Debugf("ls timeout set to %s", stateTimeoutDuration)
 
+	useCachedState = c.Bool("cached")
+	cacheMaxAge = 0
+	if maxAge := c.String("max-age"); maxAge != "" {
+		duration, err := time.ParseDuration(maxAge)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("Invalid --max-age %q, expected a duration such as 30s or 5m", maxAge)
+		}
+		useCachedState = true
+		cacheMaxAge = duration
+	}
+
 	filters, err := parseFilters(c.StringSlice("filter"))
 	if err != nil {
 		return err
@@ -123,6 +153,9 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 			EngineOptions: "ENGINE_OPTIONS",
 			Error:         "ERRORS",
 			DockerVersion: "DOCKER",
+			CacheAge:      "AGE",
+			IdleStop:      "IDLE-STOP",
+			Expires:       "EXPIRES",
 		}
 
 		if err := template.Execute(w, headers); err != nil {
@@ -134,6 +167,8 @@ func cmdLs(c CommandLine, api libmachine.API) error {
 
 	items := getHostListItems(hostList, hostInError)
 
+	saveCachedStates(api, hostList, items)
+
 	swarmMasters := make(map[string]string)
 	swarmInfo := make(map[string]string)
 
@@ -294,8 +329,13 @@ func matchesState(host *host.Host, states []string) bool {
 		return true
 	}
 	for _, n := range states {
-		s, err := host.Driver.GetState()
-		if err != nil {
+		var (
+			s   state.State
+			err error
+		)
+		if cached := usableCachedState(host); cached != nil {
+			s = cached.State
+		} else if s, err = host.Driver.GetState(); err != nil {
 			log.Warn(err)
 		}
 		if strings.EqualFold(n, s.String()) {
@@ -391,6 +431,10 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		hostError = ""
 	}
 
+	stateQueryChan <- newHostListItem(h, currentState, url, dockerVersion, hostError)
+}
+
+func newHostListItem(h *host.Host, currentState state.State, url, dockerVersion, hostError string) HostListItem {
 	var swarmOptions *swarm.Options
 	var engineOptions *engine.Options
 	if h.HostOptions != nil {
@@ -415,7 +459,9 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		active = "* (swarm)"
 	}
 
-	stateQueryChan <- HostListItem{
+	idleStop, expires := describeReapPolicy(h.ReapPolicy())
+
+	return HostListItem{
 		Name:          h.Name,
 		Active:        active,
 		ActiveHost:    activeHost,
@@ -427,10 +473,52 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		EngineOptions: engineOptions,
 		DockerVersion: dockerVersion,
 		Error:         hostError,
+		IdleStop:      idleStop,
+		Expires:       expires,
+	}
+}
+
+// describeReapPolicy returns the idle time after which the machine is
+// stopped and when it expires, e.g. "30m" and "2016-03-04T12:00:00Z (rm)".
+func describeReapPolicy(policy *host.ReapPolicy) (string, string) {
+	idleStop, expires := "", ""
+	if policy.StopsWhenIdle() {
+		idleStop = fmt.Sprintf("%dm", policy.IdleMinutes)
+	}
+	if policy.Expires() {
+		expires = fmt.Sprintf("%s (%s)", policy.ExpiresAt.Format(time.RFC3339), policy.ExpiryAction)
+	}
+	return idleStop, expires
+}
+
+// usableCachedState returns the cached state of the host if ls may use it.
+func usableCachedState(h *host.Host) *host.CachedState {
+	if !useCachedState || h.CachedState == nil {
+		return nil
+	}
+	if cacheMaxAge > 0 && h.CachedState.Age() > cacheMaxAge {
+		return nil
 	}
+	return h.CachedState
+}
+
+func getCachedHostState(h *host.Host, cached *host.CachedState) HostListItem {
+	dockerVersion := cached.DockerVersion
+	if dockerVersion == "" {
+		dockerVersion = "Unknown"
+	}
+
+	item := newHostListItem(h, cached.State, cached.URL, dockerVersion, cached.Error)
+	item.CacheAge = (cached.Age() / time.Second * time.Second).String()
+	return item
 }
 
 func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem) {
+	if cached := usableCachedState(h); cached != nil {
+		hostListItemsChan <- getCachedHostState(h, cached)
+		return
+	}
+
 	// This channel is used to communicate the properties we are querying
 	// about the host in the case of a successful read.
 	stateQueryChan := make(chan HostListItem)
@@ -480,6 +568,55 @@ func getHostListItems(hostList []*host.Host, hostsInError map[string]error) []Ho
 	return hostListItems
 }
 
+// saveCachedStates records the states which were just read from the drivers
+// so that later calls to ls can use them with --cached. Only the cached
+// states are saved, the rest of the configuration of the machines is left as
+// it is in the store.
+func saveCachedStates(api libmachine.API, hostList []*host.Host, items []HostListItem) {
+	hosts := map[string]*host.Host{}
+	for _, h := range hostList {
+		hosts[h.Name] = h
+	}
+
+	for _, item := range items {
+		h, ok := hosts[item.Name]
+		if !ok || item.CacheAge != "" || item.State == state.Timeout {
+			continue
+		}
+
+		cached := &host.CachedState{
+			State:         item.State,
+			IP:            urlHost(item.URL),
+			URL:           item.URL,
+			DockerVersion: item.DockerVersion,
+			Error:         item.Error,
+			Time:          time.Now(),
+		}
+		if item.DockerVersion == "Unknown" {
+			cached.DockerVersion = ""
+		}
+
+		// The configuration was loaded before the states were read, and
+		// may have been changed since by other commands.
+		if err := persist.SaveCachedState(api, h.Name, cached); err != nil {
+			log.Debugf("Error saving the state of %q: %s", h.Name, err)
+		}
+	}
+}
+
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return u.Host
+	}
+	return host
+}
+
 func sortHostListItemsByName(items []HostListItem) {
 	m := make(map[string]HostListItem, len(items))
 	s := make([]string, len(items))
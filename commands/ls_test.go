@@ -8,9 +8,11 @@ import (
 
 	"errors"
 
+	"github.com/docker/machine/commands/commandstest"
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
 	"github.com/docker/machine/libmachine/mcndockerclient"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
@@ -528,3 +530,109 @@ func TestGetSomeHostInError(t *testing.T) {
 	assert.Equal(t, "foo", hostItem.Name)
 	assert.Equal(t, state.Running, hostItem.State)
 }
+
+func TestGetHostListItemsCached(t *testing.T) {
+	defer func(use bool, maxAge time.Duration) { useCachedState, cacheMaxAge = use, maxAge }(useCachedState, cacheMaxAge)
+	useCachedState, cacheMaxAge = true, 0
+
+	hosts := []*host.Host{
+		{
+			Name: "foo",
+			Driver: &fakedriver.Driver{
+				MockState: state.Error,
+			},
+			CachedState: &host.CachedState{
+				State:         state.Running,
+				URL:           "tcp://1.2.3.4:2376",
+				DockerVersion: "v1.10.0",
+				Time:          time.Now().Add(-90 * time.Second),
+			},
+		},
+		{
+			Name: "bar",
+			Driver: &fakedriver.Driver{
+				MockState: state.Stopped,
+			},
+		},
+	}
+
+	items := getHostListItems(hosts, map[string]error{})
+
+	assert.Equal(t, "bar", items[0].Name)
+	assert.Equal(t, state.Stopped, items[0].State)
+	assert.Equal(t, "", items[0].CacheAge)
+
+	assert.Equal(t, "foo", items[1].Name)
+	assert.Equal(t, state.Running, items[1].State)
+	assert.Equal(t, "tcp://1.2.3.4:2376", items[1].URL)
+	assert.Equal(t, "v1.10.0", items[1].DockerVersion)
+	assert.Equal(t, "1m30s", items[1].CacheAge)
+}
+
+func TestGetHostListItemsCacheTooOld(t *testing.T) {
+	defer func(use bool, maxAge time.Duration) { useCachedState, cacheMaxAge = use, maxAge }(useCachedState, cacheMaxAge)
+	useCachedState, cacheMaxAge = true, time.Minute
+
+	hosts := []*host.Host{
+		{
+			Name: "foo",
+			Driver: &fakedriver.Driver{
+				MockState: state.Stopped,
+			},
+			CachedState: &host.CachedState{
+				State: state.Running,
+				Time:  time.Now().Add(-2 * time.Minute),
+			},
+		},
+	}
+
+	items := getHostListItems(hosts, map[string]error{})
+
+	assert.Equal(t, state.Stopped, items[0].State)
+	assert.Equal(t, "", items[0].CacheAge)
+}
+
+func TestSaveCachedStates(t *testing.T) {
+	h := &host.Host{
+		Name: "foo",
+	}
+	items := []HostListItem{
+		{Name: "foo", State: state.Running, URL: "tcp://1.2.3.4:2376", DockerVersion: "v1.10.0"},
+		{Name: "missing", State: state.Error, Error: "not found"},
+	}
+
+	saveCachedStates(&libmachinetest.FakeAPI{Hosts: []*host.Host{h}}, []*host.Host{h}, items)
+
+	assert.Equal(t, state.Running, h.CachedState.State)
+	assert.Equal(t, "1.2.3.4", h.CachedState.IP)
+	assert.Equal(t, "tcp://1.2.3.4:2376", h.CachedState.URL)
+	assert.Equal(t, "v1.10.0", h.CachedState.DockerVersion)
+}
+
+func TestCmdLsInvalidMaxAge(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"max-age": "5 minutes",
+			},
+		},
+	}
+
+	err := cmdLs(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.EqualError(t, err, `Invalid --max-age "5 minutes", expected a duration such as 30s or 5m`)
+}
+
+func TestDescribeReapPolicy(t *testing.T) {
+	idleStop, expires := describeReapPolicy(nil)
+	assert.Equal(t, "", idleStop)
+	assert.Equal(t, "", expires)
+
+	idleStop, expires = describeReapPolicy(&host.ReapPolicy{
+		IdleMinutes:  30,
+		ExpiresAt:    time.Date(2016, 3, 4, 12, 0, 0, 0, time.UTC),
+		ExpiryAction: host.ExpiryRemove,
+	})
+	assert.Equal(t, "30m", idleStop)
+	assert.Equal(t, "2016-03-04T12:00:00Z (rm)", expires)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/provision"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/swarm"
+)
+
+var (
+	errExpectedOldAndNewName = errors.New("Error: Expected the name of the machine and its new name as arguments")
+	errCanNotMoveDir         = errors.New("Error: The store can not move the directories of the machines")
+)
+
+func cmdMv(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 2 {
+		return errExpectedOldAndNewName
+	}
+
+	oldName, name := c.Args()[0], c.Args()[1]
+
+	if !host.ValidateHostName(name) {
+		return fmt.Errorf("Error renaming machine: %s", mcnerror.ErrInvalidHostname)
+	}
+
+	exists, err := api.Exists(name)
+	if err != nil {
+		return fmt.Errorf("Error checking if host exists: %s", err)
+	}
+	if exists {
+		return mcnerror.ErrHostAlreadyExists{
+			Name: name,
+		}
+	}
+
+	mover, ok := libmachine.GetDirMover(api)
+	if !ok {
+		return errCanNotMoveDir
+	}
+
+	h, err := api.Load(oldName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	if err := h.RenameContext(ctx, name, mover.MoveDir); err != nil {
+		return fmt.Errorf("Error renaming %s: %s", oldName, err)
+	}
+
+	if h.HostOptions != nil && h.HostOptions.AuthOptions != nil {
+		moveAuthOptions(h.HostOptions.AuthOptions, filepath.Join(mcndirs.GetMachineDir(), oldName), filepath.Join(mcndirs.GetMachineDir(), name))
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error attempting to save store: %s", err)
+	}
+
+	// The directory of the machine was moved, but other stores may still
+	// hold the old entry.
+	if exists, err := api.Exists(oldName); err == nil && exists {
+		if err := api.Remove(oldName); err != nil {
+			log.Warnf("Error removing %q from the store: %s", oldName, err)
+		}
+	}
+
+	if !drivers.MachineInState(h.Driver, state.Running)() {
+		log.Warnf("Machine %q is not running, run \"docker-machine regenerate-certs %s\" once it is started to update its hostname and certificates.", name, name)
+		return nil
+	}
+
+	if err := renameOnMachine(h); err != nil {
+		return fmt.Errorf("Error regenerating the certificates of %s: %s", name, err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error attempting to save store: %s", err)
+	}
+
+	log.Infof("Machine %q was renamed to %q.", oldName, name)
+
+	return nil
+}
+
+// renameOnMachine sets the hostname of a running machine to its new name and
+// provisions it again, with its own Swarm options, to generate server
+// certificates for that name.
+func renameOnMachine(h *host.Host) error {
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Setting the hostname to %q", h.Name)
+	if err := provisioner.SetHostname(h.Name); err != nil {
+		return err
+	}
+
+	swarmOptions := swarm.Options{}
+	if h.HostOptions.SwarmOptions != nil {
+		swarmOptions = *h.HostOptions.SwarmOptions
+	}
+
+	log.Info("Regenerating TLS certificates")
+	return provisioner.Provision(swarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions)
+}
+
+// moveAuthOptions updates the paths of the certificates kept in the
+// directory of a machine once it has been moved.
+func moveAuthOptions(options *auth.Options, oldDir, newDir string) {
+	paths := []*string{
+		&options.CertDir,
+		&options.CaCertPath,
+		&options.CaPrivateKeyPath,
+		&options.ClientCertPath,
+		&options.ClientKeyPath,
+		&options.ServerCertPath,
+		&options.ServerKeyPath,
+		&options.StorePath,
+	}
+
+	for _, path := range paths {
+		*path = persist.RewritePath(*path, oldDir, newDir)
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdMvMissingNewName(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"old"},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdMv(commandLine, api)
+
+	assert.Equal(t, errExpectedOldAndNewName, err)
+}
+
+func TestCmdMvNewNameExists(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"old", "new"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "old",
+			},
+			{
+				Name: "new",
+			},
+		},
+	}
+
+	err := cmdMv(commandLine, api)
+
+	assert.Equal(t, mcnerror.ErrHostAlreadyExists{Name: "new"}, err)
+}
+
+func TestCmdMvStoppedMachine(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"old", "new"},
+	}
+	driver := &fakedriver.Driver{
+		MockName:  "old",
+		MockState: state.Stopped,
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "old",
+				Driver: driver,
+			},
+		},
+	}
+
+	err := cmdMv(commandLine, api)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new", driver.MockName)
+	assert.True(t, libmachinetest.Exists(api, "new"))
+	assert.False(t, libmachinetest.Exists(api, "old"))
+}
+
+func TestCmdMvNotSupported(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"old", "new"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "old",
+				Driver: none.NewDriver("old", ""),
+			},
+		},
+	}
+
+	err := cmdMv(commandLine, api)
+
+	assert.EqualError(t, err, `Error renaming old: Driver "none" does not support renaming`)
+}
+
+func TestMoveAuthOptions(t *testing.T) {
+	options := &auth.Options{
+		CaCertPath:     "/store/certs/ca.pem",
+		ServerCertPath: "/store/machines/old/server.pem",
+		StorePath:      "/store/machines/old",
+	}
+
+	moveAuthOptions(options, "/store/machines/old", "/store/machines/new")
+
+	assert.Equal(t, "/store/certs/ca.pem", options.CaCertPath)
+	assert.Equal(t, "/store/machines/new/server.pem", options.ServerCertPath)
+	assert.Equal(t, "/store/machines/new", options.StorePath)
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
+)
+
+func cmdPause(c CommandLine, api libmachine.API) error {
+	return runBatchAction(batch.Pause, c, api)
+}
+
+func cmdUnpause(c CommandLine, api libmachine.API) error {
+	return runBatchAction(batch.Unpause, c, api)
+}
+
+func cmdSuspend(c CommandLine, api libmachine.API) error {
+	return runBatchAction(batch.Suspend, c, api)
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdPauseMissingMachineName(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdPause(commandLine, api)
+
+	assert.Equal(t, ErrNoMachineSpecified, err)
+}
+
+func TestCmdPauseUnpauseSuspend(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name: "machine",
+				Driver: &fakedriver.Driver{
+					MockState: state.Running,
+				},
+			},
+		},
+	}
+
+	assert.NoError(t, cmdPause(commandLine, api))
+	assert.Equal(t, state.Paused, libmachinetest.State(api, "machine"))
+
+	assert.NoError(t, cmdUnpause(commandLine, api))
+	assert.Equal(t, state.Running, libmachinetest.State(api, "machine"))
+
+	assert.NoError(t, cmdSuspend(commandLine, api))
+	assert.Equal(t, state.Saved, libmachinetest.State(api, "machine"))
+}
+
+func TestCmdPauseNotSupported(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machine",
+				Driver: none.NewDriver("machine", ""),
+			},
+		},
+	}
+
+	err := cmdPause(commandLine, api)
+
+	assert.EqualError(t, err, `Driver "none" does not support pausing`)
+}
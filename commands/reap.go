@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/reap"
+)
+
+func cmdReap(c CommandLine, api libmachine.API) error {
+	r := reap.NewReaper(api)
+	r.DryRun = c.Bool("dry-run")
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	decisions, err := r.Reap(ctx, c.Args())
+	printDecisions(os.Stdout, decisions)
+	if err != nil {
+		return err
+	}
+
+	if r.DryRun {
+		log.Info("Dry run, no machine was changed.")
+	}
+
+	errs := []error{}
+	for _, d := range decisions {
+		if d.Err != nil {
+			errs = append(errs, fmt.Errorf("Error reaping %q: %s", d.Name, d.Err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return consolidateErrs(errs)
+	}
+
+	return nil
+}
+
+func printDecisions(w io.Writer, decisions []reap.Decision) {
+	tw := tabwriter.NewWriter(w, 5, 1, 3, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tACTION\tREASON")
+
+	for _, d := range decisions {
+		reason := d.Reason
+		if d.Err != nil {
+			reason = d.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Name, d.Action, reason)
+	}
+}
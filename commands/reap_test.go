@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/reap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintDecisions(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	printDecisions(out, []reap.Decision{
+		{Name: "dev", Action: reap.Stop, Reason: "no running container for 30m0s"},
+		{Name: "ci", Action: reap.Keep, Err: errors.New("connection refused")},
+	})
+
+	assert.Equal(t, `NAME   ACTION   REASON
+dev    stop     no running container for 30m0s
+ci     keep     connection refused
+`, out.String())
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
 	"github.com/docker/machine/libmachine/log"
 )
 
@@ -19,5 +20,5 @@ func cmdRegenerateCerts(c CommandLine, api libmachine.API) error {
 
 	log.Infof("Regenerating TLS certificates")
 
-	return runAction("configureAuth", c, api)
+	return runBatchAction(batch.RegenerateCerts, c, api)
 }
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var errNothingToResize = errors.New("Error: Expected at least one of --cpus, --memory, --disk-size or --instance-type")
+
+func cmdResize(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	opts := drivers.ResizeOptions{
+		CPU:          c.Int("cpus"),
+		Memory:       c.Int("memory"),
+		DiskSize:     c.Int("disk-size"),
+		InstanceType: c.String("instance-type"),
+	}
+	if opts.IsZero() {
+		return errNothingToResize
+	}
+
+	h, err := api.Load(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	if err := h.ResizeContext(ctx, opts); err != nil {
+		return fmt.Errorf("Error resizing %s: %s", h.Name, err)
+	}
+
+	if err := api.Save(h); err != nil {
+		return err
+	}
+
+	log.Infof("Resized %s", h.Name)
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdResizeNothingToResize(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdResize(commandLine, api)
+
+	assert.Equal(t, errNothingToResize, err)
+}
+
+func TestCmdResizeRunningMachine(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"cpus":   2,
+				"memory": 2048,
+			},
+		},
+	}
+	driver := &fakedriver.Driver{
+		MockState: state.Running,
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machine",
+				Driver: driver,
+			},
+		},
+	}
+
+	err := cmdResize(commandLine, api)
+
+	assert.NoError(t, err)
+	assert.Equal(t, drivers.ResizeOptions{CPU: 2, Memory: 2048}, driver.Resized)
+	assert.Equal(t, state.Running, libmachinetest.State(api, "machine"))
+}
+
+func TestCmdResizeNotSupported(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"memory": 2048,
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machine",
+				Driver: none.NewDriver("machine", ""),
+			},
+		},
+	}
+
+	err := cmdResize(commandLine, api)
+
+	assert.EqualError(t, err, `Error resizing machine: Driver "none" does not support resizing`)
+}
@@ -2,11 +2,12 @@ package commands
 
 import (
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
 	"github.com/docker/machine/libmachine/log"
 )
 
 func cmdRestart(c CommandLine, api libmachine.API) error {
-	if err := runAction("restart", c, api); err != nil {
+	if err := runBatchAction(batch.Restart, c, api); err != nil {
 		return err
 	}
 
@@ -2,12 +2,14 @@ package commands
 
 import (
 	"fmt"
+	"os"
 
 	"strings"
 
 	"errors"
 
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
 	"github.com/docker/machine/libmachine/log"
 )
 
@@ -27,22 +29,23 @@ func cmdRm(c CommandLine, api libmachine.API) error {
 		return nil
 	}
 
-	for _, hostName := range c.Args() {
-		err := removeRemoteMachine(hostName, api)
-		if err != nil {
-			errorOccured = collectError(fmt.Sprintf("Error removing host %q: %s", hostName, err), force, errorOccured)
-		}
+	results, err := runBatch(batch.Remove, c, api)
+	if err != nil {
+		return err
+	}
 
-		if err == nil || force {
-			removeErr := removeLocalMachine(hostName, api)
-			if removeErr != nil {
-				errorOccured = collectError(fmt.Sprintf("Can't remove \"%s\"", hostName), force, errorOccured)
-			} else {
-				log.Infof("Successfully removed %s", hostName)
-			}
+	for _, result := range results {
+		if result.Err != nil {
+			errorOccured = collectError(fmt.Sprintf("Error removing host %q: %s", result.Name, result.Err), force, errorOccured)
+		} else {
+			log.Infof("Successfully removed %s", result.Name)
 		}
 	}
 
+	if err := printBatchSummary(os.Stdout, c.String("summary"), results); err != nil {
+		return err
+	}
+
 	if len(errorOccured) > 0 && !force {
 		return errors.New(strings.Join(errorOccured, "\n"))
 	}
@@ -63,23 +66,6 @@ func userConfirm(confirm bool, force bool) bool {
 	return sure
 }
 
-func removeRemoteMachine(hostName string, api libmachine.API) error {
-	currentHost, loaderr := api.Load(hostName)
-	if loaderr != nil {
-		return loaderr
-	}
-
-	return currentHost.Driver.Remove()
-}
-
-func removeLocalMachine(hostName string, api libmachine.API) error {
-	exist, _ := api.Exists(hostName)
-	if !exist {
-		return errors.New(hostName + " does not exist.")
-	}
-	return api.Remove(hostName)
-}
-
 func collectError(message string, force bool, errorOccured []string) []string {
 	if force {
 		log.Error(message)
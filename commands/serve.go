@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/remote"
+)
+
+const defaultServeAddr = ":7376"
+
+var errServeRemote = errors.New("Error: Can not serve the machines of another server, remove --server")
+
+// serveAuthOptions returns the certificates used by the server. The server
+// certificate is signed by the same CA as the client certificate, so that
+// the clients of this store can connect right away.
+func serveAuthOptions(c CommandLine) *auth.Options {
+	certDir := mcndirs.GetMachineCertDir()
+
+	serverCertPath := c.String("tls-server-cert")
+	if serverCertPath == "" {
+		serverCertPath = filepath.Join(certDir, "serve.pem")
+	}
+
+	serverKeyPath := c.String("tls-server-key")
+	if serverKeyPath == "" {
+		serverKeyPath = filepath.Join(certDir, "serve-key.pem")
+	}
+
+	return &auth.Options{
+		CertDir:          certDir,
+		CaCertPath:       tlsPath(c, "tls-ca-cert", "ca.pem"),
+		CaPrivateKeyPath: tlsPath(c, "tls-ca-key", "ca-key.pem"),
+		ClientCertPath:   tlsPath(c, "tls-client-cert", "cert.pem"),
+		ClientKeyPath:    tlsPath(c, "tls-client-key", "key.pem"),
+		ServerCertPath:   serverCertPath,
+		ServerKeyPath:    serverKeyPath,
+	}
+}
+
+func bootstrapServeCertificates(c CommandLine, authOptions *auth.Options) error {
+	if err := cert.BootstrapCertificates(authOptions); err != nil {
+		return fmt.Errorf("Error generating certificates: %s", err)
+	}
+
+	if _, err := os.Stat(authOptions.ServerCertPath); err == nil {
+		return nil
+	}
+
+	hosts := c.StringSlice("hostname")
+	if len(hosts) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		hosts = []string{hostname, "localhost", "127.0.0.1"}
+	}
+
+	log.Infof("Creating server certificate for %v: %s", hosts, authOptions.ServerCertPath)
+
+	org := mcnutils.GetUsername() + ".<serve>"
+	if err := cert.GenerateCert(hosts, authOptions.ServerCertPath, authOptions.ServerKeyPath, authOptions.CaCertPath, authOptions.CaPrivateKeyPath, org, 2048); err != nil {
+		return fmt.Errorf("Error generating the server certificate: %s", err)
+	}
+
+	return nil
+}
+
+func cmdServe(c CommandLine, api libmachine.API) error {
+	if c.GlobalString("server") != "" {
+		return errServeRemote
+	}
+
+	local, err := newLocalAPI(c)
+	if err != nil {
+		return err
+	}
+
+	authOptions := serveAuthOptions(c)
+	if err := bootstrapServeCertificates(c, authOptions); err != nil {
+		return err
+	}
+
+	tlsConfig, err := cert.ReadServerTLSConfig(authOptions)
+	if err != nil {
+		return fmt.Errorf("Error reading the server certificate: %s", err)
+	}
+
+	addr := c.String("addr")
+	if addr == "" {
+		addr = defaultServeAddr
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := remote.NewServer(local.Store, local.Filestore.Path, func() (libmachine.API, error) {
+		return newLocalAPI(c)
+	})
+
+	// Stop the drivers of the machines, which keep running between the
+	// requests, when the server is stopped.
+	stopped := make(chan os.Signal, 1)
+	signal.Notify(stopped, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopped)
+
+	closing := make(chan struct{})
+	go func() {
+		<-stopped
+		log.Info("Stopping the server...")
+		close(closing)
+		listener.Close()
+	}()
+	defer server.Close()
+
+	log.Infof("Serving the machines of %s on %s", local.Filestore.Path, listener.Addr())
+
+	err = http.Serve(tls.NewListener(listener, tlsConfig), server)
+
+	select {
+	case <-closing:
+		return nil
+	default:
+		return err
+	}
+}
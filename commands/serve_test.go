@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdServeRemote(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"server": "machines.example.com:7376",
+			},
+		},
+	}
+
+	err := cmdServe(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errServeRemote, err)
+}
+
+func TestNewAPI(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	defer func(baseDir string) { mcndirs.BaseDir = baseDir }(mcndirs.BaseDir)
+	mcndirs.BaseDir = tmpDir
+
+	globalFlags := &commandstest.FakeFlagger{
+		Data: map[string]interface{}{
+			"storage-path": tmpDir,
+		},
+	}
+
+	api, err := newAPI(&commandstest.FakeCommandLine{GlobalFlags: globalFlags})
+	assert.NoError(t, err)
+	assert.IsType(t, &libmachine.Client{}, api)
+
+	// The client certificates are needed to connect to a server.
+	globalFlags.Data["server"] = "machines.example.com:7376"
+	globalFlags.Data["tls-ca-cert"] = filepath.Join(tmpDir, "ca.pem")
+	globalFlags.Data["tls-ca-key"] = filepath.Join(tmpDir, "ca-key.pem")
+	globalFlags.Data["tls-client-cert"] = filepath.Join(tmpDir, "cert.pem")
+	globalFlags.Data["tls-client-key"] = filepath.Join(tmpDir, "key.pem")
+
+	commandLine := &commandstest.FakeCommandLine{GlobalFlags: globalFlags}
+
+	_, err = newAPI(commandLine)
+	assert.Error(t, err)
+
+	authOptions := serveAuthOptions(commandLine)
+	assert.NoError(t, bootstrapServeCertificates(commandLine, authOptions))
+
+	api, err = newAPI(commandLine)
+	assert.NoError(t, err)
+	assert.IsType(t, &remote.Client{}, api)
+}
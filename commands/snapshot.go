@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	errExpectedMachineAndSnapshot = errors.New("Error: Expected a machine name and a snapshot name as arguments")
+)
+
+// snapshotArgs returns the name of the machine and the name of the snapshot
+// given as arguments.
+func snapshotArgs(c CommandLine) (string, string, error) {
+	if len(c.Args()) != 2 {
+		return "", "", errExpectedMachineAndSnapshot
+	}
+
+	return c.Args()[0], c.Args()[1], nil
+}
+
+func loadSnapshotter(api libmachine.API, name string) (drivers.Snapshotter, error) {
+	h, err := api.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return drivers.GetSnapshotter(h.Driver)
+}
+
+func cmdSnapshotCreate(c CommandLine, api libmachine.API) error {
+	machineName, snapshotName, err := snapshotArgs(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadSnapshotter(api, machineName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.CreateSnapshot(snapshotName); err != nil {
+		return fmt.Errorf("Error creating snapshot %q of %s: %s", snapshotName, machineName, err)
+	}
+
+	log.Infof("Created snapshot %q of %s", snapshotName, machineName)
+
+	return nil
+}
+
+func cmdSnapshotLs(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	machineName := c.Args().First()
+
+	s, err := loadSnapshotter(api, machineName)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := s.ListSnapshots()
+	if err != nil {
+		return fmt.Errorf("Error listing the snapshots of %s: %s", machineName, err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tCURRENT")
+	for _, snapshot := range snapshots {
+		current := ""
+		if snapshot.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", snapshot.Name, current)
+	}
+
+	return nil
+}
+
+func cmdSnapshotRestore(c CommandLine, api libmachine.API) error {
+	machineName, snapshotName, err := snapshotArgs(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadSnapshotter(api, machineName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.RestoreSnapshot(snapshotName); err != nil {
+		return fmt.Errorf("Error restoring snapshot %q of %s: %s", snapshotName, machineName, err)
+	}
+
+	log.Infof("Restored %s to snapshot %q", machineName, snapshotName)
+
+	return nil
+}
+
+func cmdSnapshotRm(c CommandLine, api libmachine.API) error {
+	machineName, snapshotName, err := snapshotArgs(c)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadSnapshotter(api, machineName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.RemoveSnapshot(snapshotName); err != nil {
+		return fmt.Errorf("Error removing snapshot %q of %s: %s", snapshotName, machineName, err)
+	}
+
+	log.Infof("Removed snapshot %q of %s", snapshotName, machineName)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type snapshotDriver struct {
+	*fakedriver.Driver
+	snapshots []drivers.Snapshot
+	restored  string
+}
+
+func (d *snapshotDriver) CreateSnapshot(name string) error {
+	d.snapshots = append(d.snapshots, drivers.Snapshot{Name: name})
+	return nil
+}
+
+func (d *snapshotDriver) ListSnapshots() ([]drivers.Snapshot, error) {
+	return d.snapshots, nil
+}
+
+func (d *snapshotDriver) RestoreSnapshot(name string) error {
+	d.restored = name
+	return nil
+}
+
+func (d *snapshotDriver) RemoveSnapshot(name string) error {
+	d.snapshots = nil
+	return nil
+}
+
+func TestCmdSnapshotCreateMissingSnapshotName(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine"},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdSnapshotCreate(commandLine, api)
+
+	assert.Equal(t, errExpectedMachineAndSnapshot, err)
+}
+
+func TestCmdSnapshotNotSupported(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine", "clean"},
+	}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machine",
+				Driver: &fakedriver.Driver{},
+			},
+		},
+	}
+
+	err := cmdSnapshotCreate(commandLine, api)
+
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "Driver", Feature: "snapshots"}, err)
+}
+
+func TestCmdSnapshot(t *testing.T) {
+	driver := &snapshotDriver{Driver: &fakedriver.Driver{}}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "machine",
+				Driver: driver,
+			},
+		},
+	}
+
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"machine", "clean"},
+	}
+
+	assert.NoError(t, cmdSnapshotCreate(commandLine, api))
+	assert.Equal(t, []drivers.Snapshot{{Name: "clean"}}, driver.snapshots)
+
+	assert.NoError(t, cmdSnapshotRestore(commandLine, api))
+	assert.Equal(t, "clean", driver.restored)
+
+	stdoutGetter := commandstest.NewStdoutGetter()
+	err := cmdSnapshotLs(&commandstest.FakeCommandLine{CliArgs: []string{"machine"}}, api)
+	output := stdoutGetter.Output()
+	stdoutGetter.Stop()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "NAME    CURRENT\nclean   \n", output)
+
+	assert.NoError(t, cmdSnapshotRm(commandLine, api))
+	assert.Empty(t, driver.snapshots)
+}
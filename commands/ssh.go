@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -38,6 +39,10 @@ func cmdSSH(c CommandLine, api libmachine.API) error {
 		return err
 	}
 
+	if currentState == state.Paused || currentState == state.Saved {
+		return mcnerror.ErrHostPaused{Name: host.Name, State: currentState}
+	}
+
 	if currentState != state.Running {
 		return errStateInvalidForSSH{host.Name}
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/ssh/sshtest"
 	"github.com/docker/machine/libmachine/state"
@@ -92,6 +93,22 @@ func TestCmdSSH(t *testing.T) {
 			},
 			expectedErr: errStateInvalidForSSH{"default"},
 		},
+		{
+			commandLine: &commandstest.FakeCommandLine{
+				CliArgs: []string{"default"},
+			},
+			api: &libmachinetest.FakeAPI{
+				Hosts: []*host.Host{
+					{
+						Name: "default",
+						Driver: &fakedriver.Driver{
+							MockState: state.Paused,
+						},
+					},
+				},
+			},
+			expectedErr: mcnerror.ErrHostPaused{Name: "default", State: state.Paused},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2,11 +2,12 @@ package commands
 
 import (
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
 	"github.com/docker/machine/libmachine/log"
 )
 
 func cmdStart(c CommandLine, api libmachine.API) error {
-	if err := runAction("start", c, api); err != nil {
+	if err := runBatchAction(batch.Start, c, api); err != nil {
 		return err
 	}
 
@@ -1,7 +1,10 @@
 package commands
 
-import "github.com/docker/machine/libmachine"
+import (
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
+)
 
 func cmdStop(c CommandLine, api libmachine.API) error {
-	return runAction("stop", c, api)
+	return runBatchAction(batch.Stop, c, api)
 }
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/secret"
+)
+
+var (
+	errNoStoreBackend    = errors.New("Error: Expected the storage backend to migrate to with --to")
+	errSameStoreBackend  = errors.New("Error: The machines are already stored with this backend")
+	errTooManyKeys       = errors.New("Error: Expected either a passphrase or a key file, not both")
+	errNoStoreKey        = errors.New("Error: Expected a key to encrypt the store with, use --new-passphrase or --new-key-file")
+	errDecryptWithNewKey = errors.New("Error: --decrypt can not be used with a new key")
+	errNoKeyFile         = errors.New("Error: Expected to get the path of the key file to generate as argument")
+)
+
+// newKeyProvider returns the provider of the key used to encrypt the
+// credentials of the machines, or nil if neither a passphrase nor a key file
+// is given.
+func newKeyProvider(passphrase, keyFile, storePath string) (secret.KeyProvider, error) {
+	if passphrase != "" && keyFile != "" {
+		return nil, errTooManyKeys
+	}
+
+	if passphrase != "" {
+		return secret.NewPassphraseKeyProvider(passphrase, filepath.Join(storePath, "store.salt")), nil
+	}
+
+	if keyFile != "" {
+		return secret.NewKeyFileProvider(keyFile), nil
+	}
+
+	return nil, nil
+}
+
+// globalFilestore returns a filestore configured from the global flags,
+// independently from the one of the API.
+func globalFilestore(c CommandLine) (*persist.Filestore, error) {
+	storePath := c.GlobalString("storage-path")
+	certsDir := mcndirs.GetMachineCertDir()
+
+	keys, err := newKeyProvider(c.GlobalString("store-passphrase"), c.GlobalString("store-key-file"), storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	filestore := persist.NewFilestore(storePath, certsDir, certsDir)
+	filestore.KeyProvider = keys
+
+	return filestore, nil
+}
+
+// globalStore returns the store selected by the global flags. Unlike the
+// API, it does not load the drivers of the machines.
+func globalStore(c CommandLine) (persist.Store, error) {
+	filestore, err := globalFilestore(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return persist.NewStore(globalStoreBackend(c), filestore)
+}
+
+func globalStoreBackend(c CommandLine) string {
+	backend := c.GlobalString("storage-backend")
+	if backend == "" {
+		return persist.FileBackend
+	}
+	return backend
+}
+
+func cmdStoreMigrate(c CommandLine, api libmachine.API) error {
+	to := c.String("to")
+	if to == "" {
+		return errNoStoreBackend
+	}
+
+	from := globalStoreBackend(c)
+	if from == to {
+		return errSameStoreBackend
+	}
+
+	filestore, err := globalFilestore(c)
+	if err != nil {
+		return err
+	}
+
+	src, err := persist.NewStore(from, filestore)
+	if err != nil {
+		return err
+	}
+
+	dst, err := persist.NewStore(to, filestore)
+	if err != nil {
+		return err
+	}
+
+	hostNames, err := persist.CopyHosts(src, dst)
+	if err != nil {
+		return fmt.Errorf("Error migrating the store: %s", err)
+	}
+
+	for _, hostName := range hostNames {
+		log.Infof("Migrated %s", hostName)
+	}
+
+	log.Infof("Use --storage-backend %s or set MACHINE_STORAGE_BACKEND=%s to use the migrated store.", to, to)
+
+	return nil
+}
+
+func cmdStoreEncrypt(c CommandLine, api libmachine.API) error {
+	filestore, err := globalFilestore(c)
+	if err != nil {
+		return err
+	}
+
+	newKeys, err := newKeyProvider(c.String("new-passphrase"), c.String("new-key-file"), filestore.Path)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("decrypt") {
+		if newKeys != nil {
+			return errDecryptWithNewKey
+		}
+	} else if newKeys == nil {
+		// Encrypt the machines saved in plaintext with the current key.
+		newKeys = filestore.KeyProvider
+		if newKeys == nil {
+			return errNoStoreKey
+		}
+	}
+
+	src, err := persist.NewStore(globalStoreBackend(c), filestore)
+	if err != nil {
+		return err
+	}
+
+	reencrypted := *filestore
+	reencrypted.KeyProvider = newKeys
+
+	dst, err := persist.NewStore(globalStoreBackend(c), &reencrypted)
+	if err != nil {
+		return err
+	}
+
+	hostNames, err := persist.CopyHosts(src, dst)
+	if err != nil {
+		return fmt.Errorf("Error encrypting the store: %s", err)
+	}
+
+	for _, hostName := range hostNames {
+		if newKeys == nil {
+			log.Infof("Decrypted %s", hostName)
+		} else {
+			log.Infof("Encrypted %s", hostName)
+		}
+	}
+
+	return nil
+}
+
+func cmdStoreGenerateKey(c CommandLine, api libmachine.API) error {
+	if len(c.Args()) != 1 {
+		return errNoKeyFile
+	}
+
+	if err := secret.GenerateKeyFile(c.Args().First()); err != nil {
+		return fmt.Errorf("Error generating the key file: %s", err)
+	}
+
+	log.Infof("Use --store-key-file %s or set MACHINE_STORE_KEY_FILE=%s to encrypt the store with this key.", c.Args().First(), c.Args().First())
+
+	return nil
+}
+
+func cmdStoreRelocate(c CommandLine, api libmachine.API) error {
+	store, err := globalStore(c)
+	if err != nil {
+		return err
+	}
+
+	hostNames, err := persist.Relocate(store, c.GlobalString("storage-path"), c.String("from"))
+	if err != nil {
+		return fmt.Errorf("Error relocating the store: %s", err)
+	}
+
+	for _, hostName := range hostNames {
+		log.Infof("Relocated %s", hostName)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/secret"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdStoreMigrateMissingBackend(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdStoreMigrate(commandLine, api)
+
+	assert.Equal(t, errNoStoreBackend, err)
+}
+
+func TestCmdStoreMigrateSameBackend(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"to": "file",
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdStoreMigrate(commandLine, api)
+
+	assert.Equal(t, errSameStoreBackend, err)
+}
+
+func TestCmdStoreMigrate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := persist.NewFilestore(tmpDir, "", "").Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"to": "db",
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"storage-backend": "file",
+				"storage-path":    tmpDir,
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err = cmdStoreMigrate(commandLine, api)
+	assert.NoError(t, err)
+
+	exists, err := persist.NewDbstore(tmpDir).Exists(h.Name)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCmdStoreEncrypt(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.DriverName = "digitalocean"
+	h.Driver = &host.RawDataDriver{Data: []byte(`{"MachineName":"test-host","AccessToken":"token"}`)}
+
+	if err := persist.NewFilestore(tmpDir, "", "").Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(tmpDir, "key")
+	assert.NoError(t, secret.GenerateKeyFile(keyFile))
+
+	configPath := filepath.Join(tmpDir, "machines", h.Name, "config.json")
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"new-key-file": keyFile,
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"storage-path": tmpDir,
+			},
+		},
+	}
+
+	assert.NoError(t, cmdStoreEncrypt(commandLine, &libmachinetest.FakeAPI{}))
+
+	configData, err := ioutil.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(configData), `"token"`)
+
+	commandLine = &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"decrypt": true,
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"storage-path":   tmpDir,
+				"store-key-file": keyFile,
+			},
+		},
+	}
+
+	assert.NoError(t, cmdStoreEncrypt(commandLine, &libmachinetest.FakeAPI{}))
+
+	configData, err = ioutil.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(configData), `"token"`)
+}
+
+func TestCmdStoreEncryptWithoutKey(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{},
+		},
+	}
+
+	err := cmdStoreEncrypt(commandLine, &libmachinetest.FakeAPI{})
+
+	assert.Equal(t, errNoStoreKey, err)
+}
+
+func TestCmdStoreRelocate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.HostOptions.AuthOptions.CaCertPath = "/old/store/certs/ca.pem"
+	h.Driver = &host.RawDataDriver{Data: []byte(`{"MachineName":"test-host","StorePath":"/old/store"}`)}
+
+	if err := persist.NewFilestore(tmpDir, "", "").Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"from": "",
+			},
+		},
+		GlobalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"storage-path": tmpDir,
+			},
+		},
+	}
+
+	assert.NoError(t, cmdStoreRelocate(commandLine, &libmachinetest.FakeAPI{}))
+
+	relocated, err := persist.NewFilestore(tmpDir, "", "").Load(h.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "certs", "ca.pem"), relocated.HostOptions.AuthOptions.CaCertPath)
+}
@@ -1,7 +1,10 @@
 package commands
 
-import "github.com/docker/machine/libmachine"
+import (
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/batch"
+)
 
 func cmdUpgrade(c CommandLine, api libmachine.API) error {
-	return runAction("upgrade", c, api)
+	return runBatchAction(batch.Upgrade, c, api)
 }
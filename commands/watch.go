@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"errors"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/watch"
+	"golang.org/x/net/context"
+)
+
+var errInvalidInterval = errors.New("Error: The interval must be at least one second")
+
+func cmdWatch(c CommandLine, api libmachine.API) error {
+	interval := c.Int("interval")
+	if interval < 1 {
+		return errInvalidInterval
+	}
+
+	policy, err := host.ParseRestartPolicy(c.String("restart-policy"))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.Args() {
+		h, err := api.Load(name)
+		if err != nil {
+			return err
+		}
+		drivers.Close(h.Driver)
+	}
+
+	w := watch.NewWatcher(api)
+	w.Names = c.Args()
+	w.Interval = time.Duration(interval) * time.Second
+	w.DefaultPolicy = policy
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	log.Infof("Checking the machines every %s, press Ctrl-C to stop.", w.Interval)
+
+	if err := w.Run(ctx); err != context.Canceled && err != context.DeadlineExceeded {
+		return err
+	}
+
+	return nil
+}
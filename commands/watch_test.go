@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdWatchInvalidInterval(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"interval": 0,
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdWatch(commandLine, api)
+
+	assert.Equal(t, errInvalidInterval, err)
+}
+
+func TestCmdWatchInvalidPolicy(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"interval":       30,
+				"restart-policy": "always",
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdWatch(commandLine, api)
+
+	assert.EqualError(t, err, `Unknown restart policy "always", expected no, machine or docker`)
+}
+
+func TestCmdWatchMissingMachine(t *testing.T) {
+	commandLine := &commandstest.FakeCommandLine{
+		CliArgs: []string{"foo"},
+		LocalFlags: &commandstest.FakeFlagger{
+			Data: map[string]interface{}{
+				"interval": 30,
+			},
+		},
+	}
+	api := &libmachinetest.FakeAPI{}
+
+	err := cmdWatch(commandLine, api)
+
+	assert.Equal(t, mcnerror.ErrHostDoesNotExist{Name: "foo"}, err)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -51,6 +52,8 @@ var (
 	errorMissingAccessKeyOption = errors.New("amazonec2 driver requires the --amazonec2-access-key option or proper credentials in ~/.aws/credentials")
 	errorMissingSecretKeyOption = errors.New("amazonec2 driver requires the --amazonec2-secret-key option or proper credentials in ~/.aws/credentials")
 	errorNoVPCIdFound           = errors.New("amazonec2 driver requires either the --amazonec2-subnet-id or --amazonec2-vpc-id option or an AWS Account with a default vpc-id")
+	errorResizeWithInstanceType = errors.New("amazonec2 machines are resized by changing their instance type, use --instance-type")
+	errorResizeRootVolume       = errors.New("amazonec2 driver can not resize the root volume of a machine")
 )
 
 type Driver struct {
@@ -434,6 +437,13 @@ func (d *Driver) instanceIpAvailable() bool {
 }
 
 func (d *Driver) Create() error {
+	return d.CreateContext(context.Background())
+}
+
+// CreateContext launches the instance until ctx is done. A spot instance
+// request which is not fulfilled yet is cancelled then, and the instance
+// launched so far is left for Remove to terminate.
+func (d *Driver) CreateContext(ctx context.Context) error {
 	if err := d.checkPrereqs(); err != nil {
 		return err
 	}
@@ -448,6 +458,10 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	bdm := &ec2.BlockDeviceMapping{
 		DeviceName: aws.String(d.DeviceName),
 		Ebs: &ec2.EbsBlockDevice{
@@ -493,9 +507,10 @@ func (d *Driver) Create() error {
 		}
 
 		log.Info("Waiting for spot instance...")
-		err = d.getClient().WaitUntilSpotInstanceRequestFulfilled(&ec2.DescribeSpotInstanceRequestsInput{
-			SpotInstanceRequestIds: []*string{spotInstanceRequest.SpotInstanceRequests[0].SpotInstanceRequestId},
-		})
+		err = d.waitForSpotInstanceRequest(ctx, spotInstanceRequest.SpotInstanceRequests[0].SpotInstanceRequestId)
+		if err != nil && err == ctx.Err() {
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("Error fulfilling spot request: %v", err)
 		}
@@ -561,7 +576,7 @@ func (d *Driver) Create() error {
 	d.InstanceId = *instance.InstanceId
 
 	log.Debug("waiting for ip address to become available")
-	if err := mcnutils.WaitFor(d.instanceIpAvailable); err != nil {
+	if err := mcnutils.WaitForContext(ctx, d.instanceIpAvailable); err != nil {
 		return err
 	}
 
@@ -569,7 +584,9 @@ func (d *Driver) Create() error {
 		d.PrivateIPAddress = *instance.PrivateIpAddress
 	}
 
-	d.waitForInstance()
+	if err := d.waitForInstance(ctx); err != nil && err == ctx.Err() {
+		return err
+	}
 
 	log.Debugf("created instance ID %s, IP address %s, Private IP address %s",
 		d.InstanceId,
@@ -669,7 +686,7 @@ func (d *Driver) Start() error {
 		return err
 	}
 
-	return d.waitForInstance()
+	return d.waitForInstance(context.Background())
 }
 
 func (d *Driver) Stop() error {
@@ -680,6 +697,50 @@ func (d *Driver) Stop() error {
 	return err
 }
 
+// Resize changes the instance type of the stopped instance.
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	if opts.CPU != 0 || opts.Memory != 0 {
+		return errorResizeWithInstanceType
+	}
+
+	if opts.DiskSize != 0 {
+		return errorResizeRootVolume
+	}
+
+	if opts.InstanceType == "" || opts.InstanceType == d.InstanceType {
+		return nil
+	}
+
+	if _, err := d.getClient().ModifyInstanceAttribute(&ec2.ModifyInstanceAttributeInput{
+		InstanceId:   &d.InstanceId,
+		InstanceType: &ec2.AttributeValue{Value: aws.String(opts.InstanceType)},
+	}); err != nil {
+		return err
+	}
+
+	d.InstanceType = opts.InstanceType
+
+	return nil
+}
+
+// Rename updates the Name tag of the instance.
+func (d *Driver) Rename(name string) error {
+	if _, err := d.getClient().CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{&d.InstanceId},
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("Name"),
+				Value: aws.String(name),
+			},
+		},
+	}); err != nil {
+		return err
+	}
+
+	d.SetMachineName(name)
+	return nil
+}
+
 func (d *Driver) Restart() error {
 	_, err := d.getClient().RebootInstances(&ec2.RebootInstancesInput{
 		InstanceIds: []*string{&d.InstanceId},
@@ -729,14 +790,51 @@ func (d *Driver) instanceIsRunning() bool {
 	return false
 }
 
-func (d *Driver) waitForInstance() error {
-	if err := mcnutils.WaitFor(d.instanceIsRunning); err != nil {
+func (d *Driver) waitForInstance(ctx context.Context) error {
+	if err := mcnutils.WaitForContext(ctx, d.instanceIsRunning); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// waitForSpotInstanceRequest waits for the spot instance request to be
+// fulfilled. Should ctx be done first, the request is cancelled and ctx.Err()
+// returned once the wait is over. The instance is recorded if the request was
+// fulfilled in the meantime, so that Remove terminates it.
+func (d *Driver) waitForSpotInstanceRequest(ctx context.Context, requestId *string) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.getClient().WaitUntilSpotInstanceRequestFulfilled(&ec2.DescribeSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: []*string{requestId},
+		})
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Cancelling spot instance request...")
+	if _, err := d.getClient().CancelSpotInstanceRequests(&ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []*string{requestId},
+	}); err != nil {
+		log.Warnf("Error cancelling spot instance request %s: %s", *requestId, err)
+	}
+
+	if err := <-errCh; err == nil {
+		requests, err := d.getClient().DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: []*string{requestId},
+		})
+		if err == nil && requests.SpotInstanceRequests[0].InstanceId != nil {
+			d.InstanceId = *requests.SpotInstanceRequests[0].InstanceId
+		}
+	}
+
+	return ctx.Err()
+}
+
 func (d *Driver) createKeyPair() error {
 	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
 		return err
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/docker/machine/commands/commandstest"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -260,3 +261,37 @@ func TestPassingBothCLIArgWorked(t *testing.T) {
 	assert.Equal(t, "foobar", driver.AccessKey)
 	assert.Equal(t, "123", driver.SecretKey)
 }
+
+func TestResizeInstanceType(t *testing.T) {
+	client := &fakeEC2WithModify{}
+	driver := NewCustomTestDriver(client)
+	driver.InstanceId = "i-1234"
+
+	err := driver.Resize(drivers.ResizeOptions{InstanceType: "m4.large"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1234", *client.input.InstanceId)
+	assert.Equal(t, "m4.large", *client.input.InstanceType.Value)
+	assert.Equal(t, "m4.large", driver.InstanceType)
+}
+
+func TestResizeMemory(t *testing.T) {
+	driver := NewTestDriver()
+
+	err := driver.Resize(drivers.ResizeOptions{Memory: 4096})
+
+	assert.Equal(t, errorResizeWithInstanceType, err)
+}
+
+func TestRename(t *testing.T) {
+	client := &fakeEC2WithTags{}
+	driver := NewCustomTestDriver(client)
+	driver.InstanceId = "i-1234"
+
+	err := driver.Rename("machineBar")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1234", *client.input.Resources[0])
+	assert.Equal(t, "machineBar", *client.input.Tags[0].Value)
+	assert.Equal(t, "machineBar", driver.MachineName)
+}
@@ -89,6 +89,26 @@ func (f *fakeEC2WithLogin) DescribeAccountAttributes(input *ec2.DescribeAccountA
 	}, nil
 }
 
+type fakeEC2WithModify struct {
+	*fakeEC2
+	input *ec2.ModifyInstanceAttributeInput
+}
+
+func (f *fakeEC2WithModify) ModifyInstanceAttribute(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+	f.input = input
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+type fakeEC2WithTags struct {
+	*fakeEC2
+	input *ec2.CreateTagsInput
+}
+
+func (f *fakeEC2WithTags) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	f.input = input
+	return &ec2.CreateTagsOutput{}, nil
+}
+
 func NewTestDriver() *Driver {
 	driver := NewDriver("machineFoo", "path")
 	driver.clientFactory = func() Ec2Client { return &fakeEC2{} }
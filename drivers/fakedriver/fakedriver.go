@@ -1,6 +1,7 @@
 package fakedriver
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/docker/machine/libmachine/drivers"
@@ -13,6 +14,9 @@ type Driver struct {
 	MockState state.State
 	MockIP    string
 	MockName  string
+
+	// Resized holds the options of the last call to Resize.
+	Resized drivers.ResizeOptions
 }
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
@@ -107,3 +111,46 @@ func (d *Driver) Remove() error {
 func (d *Driver) Upgrade() error {
 	return nil
 }
+
+func (d *Driver) Pause() error {
+	d.MockState = state.Paused
+	return nil
+}
+
+func (d *Driver) Unpause() error {
+	d.MockState = state.Running
+	return nil
+}
+
+func (d *Driver) Suspend() error {
+	d.MockState = state.Saved
+	return nil
+}
+
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	if d.MockState != state.Stopped {
+		return fmt.Errorf("machine is %s, it must be stopped to be resized", d.MockState)
+	}
+	d.Resized = opts
+	return nil
+}
+
+func (d *Driver) Clone(name string) ([]byte, error) {
+	if d.MockState != state.Stopped {
+		return nil, fmt.Errorf("machine is %s, it must be stopped to be cloned", d.MockState)
+	}
+	return json.Marshal(&Driver{
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: name,
+		},
+		MockState: state.Stopped,
+	})
+}
+
+func (d *Driver) Rename(name string) error {
+	if d.MockState != state.Stopped {
+		return fmt.Errorf("machine is %s, it must be stopped to be renamed", d.MockState)
+	}
+	d.MockName = name
+	return nil
+}
@@ -1,8 +1,11 @@
 package google
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -13,6 +16,7 @@ import (
 
 	"errors"
 
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/googleapi"
@@ -29,10 +33,14 @@ type ComputeUtil struct {
 	preemptible   bool
 	useInternalIP bool
 	service       *raw.Service
+	client        *http.Client
 	zoneURL       string
 	globalURL     string
 	SwarmMaster   bool
 	SwarmHost     string
+
+	// ctx is the context the operations are waited for with.
+	ctx context.Context
 }
 
 const (
@@ -66,10 +74,12 @@ func newComputeUtil(driver *Driver) (*ComputeUtil, error) {
 		preemptible:   driver.Preemptible,
 		useInternalIP: driver.UseInternalIP,
 		service:       service,
+		client:        client,
 		zoneURL:       apiURL + driver.Project + "/zones/" + driver.Zone,
 		globalURL:     apiURL + driver.Project + "/global",
 		SwarmMaster:   driver.SwarmMaster,
 		SwarmHost:     driver.SwarmHost,
+		ctx:           context.Background(),
 	}, nil
 }
 
@@ -360,6 +370,48 @@ func (c *ComputeUtil) startInstance() error {
 	return c.waitForRegionalOp(op.Name)
 }
 
+// setMachineType changes the machine type of the stopped instance.
+func (c *ComputeUtil) setMachineType(machineType string) error {
+	body := map[string]string{"machineType": c.zoneURL + "/machineTypes/" + machineType}
+
+	log.Infof("Changing the machine type to %s...", machineType)
+	return c.postAndWait(c.zoneURL+"/instances/"+c.instanceName+"/setMachineType", body)
+}
+
+// resizeDisk grows the persistent disk attached to the vm.
+func (c *ComputeUtil) resizeDisk(sizeGb int) error {
+	body := map[string]string{"sizeGb": fmt.Sprintf("%d", sizeGb)}
+
+	log.Infof("Resizing the disk to %d GB...", sizeGb)
+	return c.postAndWait(c.zoneURL+"/disks/"+c.diskName()+"/resize", body)
+}
+
+// postAndWait calls a zonal method which the vendored API client doesn't
+// support yet, and waits for the resulting operation.
+func (c *ComputeUtil) postAndWait(url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	op := &raw.Operation{}
+	if err := json.NewDecoder(resp.Body).Decode(op); err != nil {
+		return err
+	}
+
+	return c.waitForRegionalOp(op.Name)
+}
+
 func (c *ComputeUtil) waitForOp(opGetter func() (*raw.Operation, error)) error {
 	for {
 		op, err := opGetter()
@@ -374,7 +426,12 @@ func (c *ComputeUtil) waitForOp(opGetter func() (*raw.Operation, error)) error {
 			}
 			break
 		}
-		time.Sleep(1 * time.Second)
+
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
 	}
 	return nil
 }
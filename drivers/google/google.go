@@ -1,6 +1,7 @@
 package google
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
 )
 
 // Driver is a struct compatible with the docker.hosts.drivers.Driver interface.
@@ -200,6 +202,13 @@ func (d *Driver) PreCreateCheck() error {
 
 // Create creates a GCE VM instance acting as a docker host.
 func (d *Driver) Create() error {
+	return d.CreateContext(context.Background())
+}
+
+// CreateContext creates the instance until ctx is done. The operations in
+// flight on GCE are not waited for anymore then, and whatever they create is
+// left for Remove to delete.
+func (d *Driver) CreateContext(ctx context.Context) error {
 	log.Infof("Generating SSH Key")
 
 	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
@@ -213,6 +222,8 @@ func (d *Driver) Create() error {
 		return err
 	}
 
+	c.ctx = ctx
+
 	return c.createInstance(d)
 }
 
@@ -325,6 +336,48 @@ func (d *Driver) Restart() error {
 	return d.Start()
 }
 
+// Resize changes the machine type and grows the disk of the stopped
+// instance. CPUs and memory are turned into a custom machine type.
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	machineType := opts.InstanceType
+	if opts.CPU != 0 || opts.Memory != 0 {
+		if machineType != "" {
+			return errors.New("Either a machine type or CPUs and memory can be given, not both")
+		}
+		if opts.CPU == 0 || opts.Memory == 0 {
+			return errors.New("Both CPUs and memory are needed for a custom machine type")
+		}
+		machineType = fmt.Sprintf("custom-%d-%d", opts.CPU, opts.Memory)
+	}
+
+	// The disk size of the driver is in GB.
+	diskSize := (opts.DiskSize + 1023) / 1024
+	if diskSize != 0 && diskSize < d.DiskSize {
+		return fmt.Errorf("The disk can only grow, it is already %d GB", d.DiskSize)
+	}
+
+	c, err := newComputeUtil(d)
+	if err != nil {
+		return err
+	}
+
+	if machineType != "" && machineType != d.MachineType {
+		if err := c.setMachineType(machineType); err != nil {
+			return err
+		}
+		d.MachineType = machineType
+	}
+
+	if diskSize > d.DiskSize {
+		if err := c.resizeDisk(diskSize); err != nil {
+			return err
+		}
+		d.DiskSize = diskSize
+	}
+
+	return nil
+}
+
 // Kill stops an existing GCE instance.
 func (d *Driver) Kill() error {
 	return d.Stop()
@@ -22,3 +22,20 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestResizeNeedsCPUAndMemory(t *testing.T) {
+	driver := NewDriver("", "")
+
+	err := driver.Resize(drivers.ResizeOptions{CPU: 2})
+
+	assert.EqualError(t, err, "Both CPUs and memory are needed for a custom machine type")
+}
+
+func TestResizeShrinkDisk(t *testing.T) {
+	driver := NewDriver("", "")
+	driver.DiskSize = 20
+
+	err := driver.Resize(drivers.ResizeOptions{DiskSize: 10 * 1024})
+
+	assert.EqualError(t, err, "The disk can only grow, it is already 20 GB")
+}
@@ -0,0 +1,62 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+)
+
+// Clone creates the VM name as a full copy of this one. The copy gets new
+// MAC addresses, hence a new IP, and its own copy of the SSH key and of the
+// boot2docker ISO.
+func (d *Driver) Clone(name string) ([]byte, error) {
+	clone := *d
+	clone.BaseDriver = &drivers.BaseDriver{
+		MachineName:    name,
+		SSHUser:        d.SSHUser,
+		StorePath:      d.StorePath,
+		SwarmMaster:    d.SwarmMaster,
+		SwarmHost:      d.SwarmHost,
+		SwarmDiscovery: d.SwarmDiscovery,
+	}
+	clone.Boot2DockerImportVM = ""
+
+	dir := clone.ResolveStorePath(".")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Copying the SSH key and the ISO...")
+	files := map[string]string{
+		d.GetSSHKeyPath():                     clone.GetSSHKeyPath(),
+		d.publicSSHKeyPath():                  clone.publicSSHKeyPath(),
+		d.ResolveStorePath("boot2docker.iso"): clone.ResolveStorePath("boot2docker.iso"),
+	}
+	for src, dst := range files {
+		if err := mcnutils.CopyFile(src, dst); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Infof("Cloning VirtualBox VM...")
+	if err := d.vbm("clonevm", d.MachineName,
+		"--name", name,
+		"--basefolder", dir,
+		"--register"); err != nil {
+		return nil, err
+	}
+
+	if err := clone.vbm("storageattach", name,
+		"--storagectl", "SATA",
+		"--port", "0",
+		"--device", "0",
+		"--type", "dvddrive",
+		"--medium", clone.ResolveStorePath("boot2docker.iso")); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&clone)
+}
@@ -0,0 +1,53 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "virtualbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(storePath)
+
+	srcDir := filepath.Join(storePath, "machines", "default")
+	assert.NoError(t, os.MkdirAll(srcDir, 0700))
+	for _, file := range []string{"id_rsa", "id_rsa.pub", "boot2docker.iso"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(srcDir, file), []byte(file), 0600))
+	}
+
+	dstDir := filepath.Join(storePath, "machines", "copy")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"clonevm default --name copy --basefolder " + dstDir + " --register":                                                            "",
+			"storageattach copy --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(dstDir, "boot2docker.iso"): "",
+		},
+	}
+	driver := NewDriver("default", storePath)
+	driver.VBoxManager = vbm
+	driver.IPAddress = "192.168.99.100"
+	driver.SSHPort = 50000
+	driver.Memory = 4096
+
+	data, err := driver.Clone("copy")
+
+	assert.NoError(t, err)
+	assert.Len(t, vbm.calls, 2)
+
+	clone := NewDriver("", "")
+	assert.NoError(t, json.Unmarshal(data, clone))
+	assert.Equal(t, "copy", clone.MachineName)
+	assert.Equal(t, filepath.Join(dstDir, "id_rsa"), clone.SSHKeyPath)
+	assert.Empty(t, clone.IPAddress)
+	assert.Equal(t, 0, clone.SSHPort)
+	assert.Equal(t, 4096, clone.Memory)
+
+	key, err := ioutil.ReadFile(filepath.Join(dstDir, "id_rsa"))
+	assert.NoError(t, err)
+	assert.Equal(t, "id_rsa", string(key))
+}
@@ -5,11 +5,13 @@ import (
 
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/net/context"
 )
 
 // IPWaiter waits for an IP to be configured.
 type IPWaiter interface {
-	Wait(d *Driver) error
+	// Wait waits for the IP of the VM of d, until ctx is done.
+	Wait(ctx context.Context, d *Driver) error
 }
 
 func NewIPWaiter() IPWaiter {
@@ -18,14 +20,16 @@ func NewIPWaiter() IPWaiter {
 
 type sshIPWaiter struct{}
 
-func (w *sshIPWaiter) Wait(d *Driver) error {
+func (w *sshIPWaiter) Wait(ctx context.Context, d *Driver) error {
 	// Wait for SSH over NAT to be available before returning to user
-	if err := drivers.WaitForSSH(d); err != nil {
+	if err := drivers.WaitForSSHContext(ctx, d); err != nil {
 		return err
 	}
 
 	// Bail if we don't get an IP from DHCP after a given number of seconds.
-	if err := mcnutils.WaitForSpecific(d.hostOnlyIPAvailable, 5, 4*time.Second); err != nil {
+	if err := mcnutils.WaitForSpecificOrErrorContext(ctx, func() (bool, error) {
+		return d.hostOnlyIPAvailable(), nil
+	}, 5, 4*time.Second); err != nil {
 		return err
 	}
 
@@ -0,0 +1,158 @@
+package virtualbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+var errRenameWithSnapshots = errors.New("A machine with snapshots can not be renamed, remove them first")
+
+// step is an operation on the VM along with the one undoing it.
+type step struct {
+	do   func() error
+	undo func() error
+}
+
+// runSteps runs the steps in order. If one fails, the steps which succeeded
+// are undone in reverse order.
+func runSteps(steps []step) error {
+	for i, s := range steps {
+		if err := s.do(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if steps[j].undo == nil {
+					continue
+				}
+				if undoErr := steps[j].undo(); undoErr != nil {
+					log.Warnf("Error rolling back: %s", undoErr)
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseStoreDir unregisters the VM and its disks, which VirtualBox
+// references by their absolute paths, so that the store directory can be
+// moved. A machine with snapshots can not be renamed since they reference
+// the disks too.
+func (d *Driver) ReleaseStoreDir() error {
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		return errRenameWithSnapshots
+	}
+
+	disk, err := getVMDiskInfo(d.MachineName, d.VBoxManager)
+	if err != nil {
+		return err
+	}
+
+	isoPath := d.ResolveStorePath("boot2docker.iso")
+
+	return runSteps([]step{
+		{
+			do:   func() error { return d.attach(d.MachineName, "0", "dvddrive", "emptydrive") },
+			undo: func() error { return d.attach(d.MachineName, "0", "dvddrive", isoPath) },
+		},
+		{
+			do:   func() error { return d.attach(d.MachineName, "1", "hdd", "none") },
+			undo: func() error { return d.attach(d.MachineName, "1", "hdd", disk.Path) },
+		},
+		{
+			do: func() error { return d.vbm("closemedium", "dvd", isoPath) },
+		},
+		{
+			do: func() error { return d.vbm("closemedium", "disk", disk.Path) },
+		},
+		{
+			do: func() error { return d.vbm("unregistervm", d.MachineName) },
+		},
+	})
+}
+
+// UseStoreDir registers the VM and its disks again from the store directory
+// of the machine.
+func (d *Driver) UseStoreDir() error {
+	return runSteps(d.useStoreDirSteps(d.MachineName))
+}
+
+// useStoreDirSteps registers the VM vmName from the store directory of the
+// machine and attaches its disks.
+func (d *Driver) useStoreDirSteps(vmName string) []step {
+	isoPath := d.ResolveStorePath("boot2docker.iso")
+	diskPath := d.storeDiskPath()
+
+	return []step{
+		{
+			do:   func() error { return d.vbm("registervm", d.ResolveStorePath(filepath.Join(vmName, vmName+".vbox"))) },
+			undo: func() error { return d.vbm("unregistervm", vmName) },
+		},
+		{
+			do: func() error { return d.attach(vmName, "0", "dvddrive", isoPath) },
+			undo: func() error {
+				if err := d.attach(vmName, "0", "dvddrive", "emptydrive"); err != nil {
+					return err
+				}
+				return d.vbm("closemedium", "dvd", isoPath)
+			},
+		},
+		{
+			do: func() error { return d.attach(vmName, "1", "hdd", diskPath) },
+			undo: func() error {
+				if err := d.attach(vmName, "1", "hdd", "none"); err != nil {
+					return err
+				}
+				return d.vbm("closemedium", "disk", diskPath)
+			},
+		},
+	}
+}
+
+// Rename registers the VM from the store directory of name, where the store
+// moved it, and renames it. If it fails, the VM is left unregistered with
+// the configuration of the driver unchanged, so that UseStoreDir registers
+// it again once the directory is moved back.
+func (d *Driver) Rename(name string) error {
+	oldName, oldSSHKeyPath := d.MachineName, d.SSHKeyPath
+	d.SetMachineName(name)
+
+	log.Infof("Renaming VirtualBox VM...")
+	steps := append(d.useStoreDirSteps(oldName), step{
+		do: func() error { return d.vbm("modifyvm", oldName, "--name", name) },
+	})
+
+	if err := runSteps(steps); err != nil {
+		d.MachineName, d.SSHKeyPath = oldName, oldSSHKeyPath
+		return err
+	}
+
+	return nil
+}
+
+// storeDiskPath returns the path of the disk of the VM, which is converted
+// to VDI the first time it is grown.
+func (d *Driver) storeDiskPath() string {
+	vdiPath := d.ResolveStorePath("disk.vdi")
+	if _, err := os.Stat(vdiPath); err == nil {
+		return vdiPath
+	}
+
+	return d.diskPath()
+}
+
+// attach sets the medium on a port of the SATA controller of the VM.
+func (d *Driver) attach(vmName, port, mediumType, medium string) error {
+	return d.vbm("storageattach", vmName,
+		"--storagectl", "SATA",
+		"--port", port,
+		"--device", "0",
+		"--type", mediumType,
+		"--medium", medium)
+}
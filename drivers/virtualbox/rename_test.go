@@ -0,0 +1,137 @@
+package virtualbox
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseStoreDir(t *testing.T) {
+	dir := filepath.Join("/store", "machines", "default")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable":                                                         "",
+			"showvminfo default --machinereadable":                                                            `"SATA-1-0"="` + filepath.Join(dir, "disk.vmdk") + `"`,
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium emptydrive": "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium none":            "",
+			"closemedium dvd " + filepath.Join(dir, "boot2docker.iso"):                                        "",
+			"closemedium disk " + filepath.Join(dir, "disk.vmdk"):                                             "",
+			"unregistervm default": "",
+		},
+	}
+	driver := NewDriver("default", "/store")
+	driver.VBoxManager = vbm
+
+	err := driver.ReleaseStoreDir()
+
+	assert.NoError(t, err)
+	assert.Len(t, vbm.calls, 7)
+}
+
+func TestReleaseStoreDirRollsBack(t *testing.T) {
+	dir := filepath.Join("/store", "machines", "default")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable":                                                                                       "",
+			"showvminfo default --machinereadable":                                                                                          `"SATA-1-0"="` + filepath.Join(dir, "disk.vmdk") + `"`,
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium emptydrive":                               "",
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(dir, "boot2docker.iso"): "",
+		},
+	}
+	driver := NewDriver("default", "/store")
+	driver.VBoxManager = vbm
+
+	err := driver.ReleaseStoreDir()
+
+	assert.Error(t, err)
+	assert.Equal(t, "storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium "+filepath.Join(dir, "boot2docker.iso"), vbm.calls[len(vbm.calls)-1])
+}
+
+func TestReleaseStoreDirWithSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: snapshotList,
+	}
+
+	err := driver.ReleaseStoreDir()
+
+	assert.Equal(t, errRenameWithSnapshots, err)
+}
+
+func TestRename(t *testing.T) {
+	newDir := filepath.Join("/store", "machines", "dev")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"registervm " + filepath.Join(newDir, "default", "default.vbox"):                                                                   "",
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(newDir, "boot2docker.iso"): "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium " + filepath.Join(newDir, "disk.vmdk"):            "",
+			"modifyvm default --name dev": "",
+		},
+	}
+	driver := NewDriver("default", "/store")
+	driver.SSHKeyPath = driver.ResolveStorePath("id_rsa")
+	driver.VBoxManager = vbm
+
+	err := driver.Rename("dev")
+
+	assert.NoError(t, err)
+	assert.Len(t, vbm.calls, 4)
+	assert.Equal(t, "dev", driver.MachineName)
+	assert.Equal(t, filepath.Join(newDir, "id_rsa"), driver.SSHKeyPath)
+}
+
+func TestRenameRollsBack(t *testing.T) {
+	newDir := filepath.Join("/store", "machines", "dev")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"registervm " + filepath.Join(newDir, "default", "default.vbox"):                                                                   "",
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(newDir, "boot2docker.iso"): "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium " + filepath.Join(newDir, "disk.vmdk"):            "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium none":                                             "",
+			"closemedium disk " + filepath.Join(newDir, "disk.vmdk"):                                                                           "",
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium emptydrive":                                  "",
+			"closemedium dvd " + filepath.Join(newDir, "boot2docker.iso"):                                                                      "",
+			"unregistervm default": "",
+		},
+	}
+	driver := NewDriver("default", "/store")
+	driver.SSHKeyPath = driver.ResolveStorePath("id_rsa")
+	driver.VBoxManager = vbm
+
+	err := driver.Rename("dev")
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"registervm " + filepath.Join(newDir, "default", "default.vbox"),
+		"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(newDir, "boot2docker.iso"),
+		"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium " + filepath.Join(newDir, "disk.vmdk"),
+		"modifyvm default --name dev",
+		"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium none",
+		"closemedium disk " + filepath.Join(newDir, "disk.vmdk"),
+		"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium emptydrive",
+		"closemedium dvd " + filepath.Join(newDir, "boot2docker.iso"),
+		"unregistervm default",
+	}, vbm.calls)
+	assert.Equal(t, "default", driver.MachineName)
+	assert.Equal(t, filepath.Join("/store", "machines", "default", "id_rsa"), driver.SSHKeyPath)
+}
+
+func TestUseStoreDir(t *testing.T) {
+	dir := filepath.Join("/store", "machines", "default")
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"registervm " + filepath.Join(dir, "default", "default.vbox"):                                                                   "",
+			"storageattach default --storagectl SATA --port 0 --device 0 --type dvddrive --medium " + filepath.Join(dir, "boot2docker.iso"): "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium " + filepath.Join(dir, "disk.vmdk"):            "",
+		},
+	}
+	driver := NewDriver("default", "/store")
+	driver.VBoxManager = vbm
+
+	err := driver.UseStoreDir()
+
+	assert.NoError(t, err)
+	assert.Len(t, vbm.calls, 3)
+}
@@ -0,0 +1,108 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	errNoInstanceType      = errors.New("virtualbox machines have no instance type, use --cpus and --memory")
+	errResizeWithSnapshots = errors.New("The disk of a machine with snapshots can not be grown, remove them first")
+)
+
+// Resize changes the number of CPUs, the memory and the size of the disk of
+// the VM, which must be powered off. Only the disk grows: its partition and
+// file system keep their size until they are grown from inside the VM.
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	if opts.InstanceType != "" {
+		return errNoInstanceType
+	}
+
+	if opts.DiskSize != 0 && opts.DiskSize < d.DiskSize {
+		return fmt.Errorf("The disk can only grow, it is already %d MB", d.DiskSize)
+	}
+
+	// The snapshots reference the disk, which is replaced when it is
+	// converted and can not be resized while it has differencing images.
+	if opts.DiskSize > d.DiskSize {
+		snapshots, err := d.ListSnapshots()
+		if err != nil {
+			return err
+		}
+		if len(snapshots) > 0 {
+			return errResizeWithSnapshots
+		}
+	}
+
+	args := []string{"modifyvm", d.MachineName}
+	if opts.CPU > 0 {
+		args = append(args, "--cpus", strconv.Itoa(opts.CPU))
+	}
+	if opts.Memory > 0 {
+		args = append(args, "--memory", strconv.Itoa(opts.Memory))
+	}
+
+	if len(args) > 2 {
+		if err := d.vbm(args...); err != nil {
+			return err
+		}
+	}
+
+	if opts.CPU > 0 {
+		d.CPU = opts.CPU
+	}
+	if opts.Memory > 0 {
+		d.Memory = opts.Memory
+	}
+
+	if opts.DiskSize > d.DiskSize {
+		if err := d.resizeDisk(opts.DiskSize); err != nil {
+			return err
+		}
+		d.DiskSize = opts.DiskSize
+
+		log.Warnf("The disk of %q was grown to %d MB, but not its partition and file system. Grow them from the machine, e.g. with fdisk and resize2fs, to use the new space.", d.MachineName, d.DiskSize)
+	}
+
+	return nil
+}
+
+// resizeDisk grows the disk of the VM. VirtualBox can not resize VMDK disks,
+// which is the format of the disks we create, so the disk is converted to
+// VDI the first time.
+func (d *Driver) resizeDisk(size int) error {
+	disk, err := getVMDiskInfo(d.MachineName, d.VBoxManager)
+	if err != nil {
+		return err
+	}
+
+	path := disk.Path
+	if filepath.Ext(path) != ".vdi" {
+		vdiPath := d.ResolveStorePath("disk.vdi")
+
+		log.Infof("Converting the disk to VDI...")
+		if err := d.vbm("clonehd", path, vdiPath, "--format", "VDI"); err != nil {
+			return err
+		}
+
+		if err := d.attach(d.MachineName, "1", "hdd", vdiPath); err != nil {
+			if err := d.vbm("closemedium", "disk", vdiPath, "--delete"); err != nil {
+				log.Warnf("Error removing the VDI copy of the disk: %s", err)
+			}
+			return err
+		}
+
+		if err := d.vbm("closemedium", "disk", path, "--delete"); err != nil {
+			return err
+		}
+
+		path = vdiPath
+	}
+
+	return d.vbm("modifyhd", path, "--resize", strconv.Itoa(size))
+}
@@ -0,0 +1,93 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResizeCPUAndMemory(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args: "modifyvm default --cpus 2 --memory 2048",
+	}
+
+	err := driver.Resize(drivers.ResizeOptions{CPU: 2, Memory: 2048})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, driver.CPU)
+	assert.Equal(t, 2048, driver.Memory)
+}
+
+func TestResizeDiskConvertsToVDI(t *testing.T) {
+	vdiPath := "machines/default/disk.vdi"
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable":                                                    "",
+			"showvminfo default --machinereadable":                                                       `"SATA-1-0"="/tmp/default/disk.vmdk"`,
+			"clonehd /tmp/default/disk.vmdk " + vdiPath + " --format VDI":                                "",
+			"storageattach default --storagectl SATA --port 1 --device 0 --type hdd --medium " + vdiPath: "",
+			"closemedium disk /tmp/default/disk.vmdk --delete":                                           "",
+			"modifyhd " + vdiPath + " --resize 40000":                                                    "",
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbm
+
+	err := driver.Resize(drivers.ResizeOptions{DiskSize: 40000})
+
+	assert.NoError(t, err)
+	assert.Len(t, vbm.calls, 6)
+	assert.Equal(t, 40000, driver.DiskSize)
+}
+
+func TestResizeDiskWithSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: snapshotList,
+	}
+
+	err := driver.Resize(drivers.ResizeOptions{CPU: 2, DiskSize: 40000})
+
+	assert.Equal(t, errResizeWithSnapshots, err)
+	assert.Equal(t, 20000, driver.DiskSize)
+	assert.Equal(t, 1, driver.CPU)
+}
+
+func TestResizeDiskRemovesTheCopyOnFailure(t *testing.T) {
+	vdiPath := "machines/default/disk.vdi"
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable":                     "",
+			"showvminfo default --machinereadable":                        `"SATA-1-0"="/tmp/default/disk.vmdk"`,
+			"clonehd /tmp/default/disk.vmdk " + vdiPath + " --format VDI": "",
+			"closemedium disk " + vdiPath + " --delete":                   "",
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbm
+
+	err := driver.Resize(drivers.ResizeOptions{DiskSize: 40000})
+
+	assert.Error(t, err)
+	assert.Equal(t, "closemedium disk "+vdiPath+" --delete", vbm.calls[len(vbm.calls)-1])
+	assert.Equal(t, 20000, driver.DiskSize)
+}
+
+func TestResizeShrinkDisk(t *testing.T) {
+	driver := newTestDriver("default")
+
+	err := driver.Resize(drivers.ResizeOptions{DiskSize: 1000})
+
+	assert.EqualError(t, err, "The disk can only grow, it is already 20000 MB")
+}
+
+func TestResizeInstanceType(t *testing.T) {
+	driver := newTestDriver("default")
+
+	err := driver.Resize(drivers.ResizeOptions{InstanceType: "large"})
+
+	assert.Equal(t, errNoInstanceType, err)
+}
@@ -0,0 +1,126 @@
+package virtualbox
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const noSnapshots = "does not have any snapshots"
+
+var reSnapshotLine = regexp.MustCompile(`^([\w-]+)="(.*)"$`)
+
+// CreateSnapshot takes a snapshot of the VM, which can be running.
+func (d *Driver) CreateSnapshot(name string) error {
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return fmt.Errorf("Snapshot %q already exists", name)
+		}
+	}
+
+	return d.vbm("snapshot", d.MachineName, "take", name)
+}
+
+// ListSnapshots returns the snapshots of the VM, the oldest first.
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	stdout, stderr, err := d.vbmOutErr("snapshot", d.MachineName, "list", "--machinereadable")
+	if err != nil {
+		if strings.Contains(stdout, noSnapshots) || strings.Contains(stderr, noSnapshots) {
+			return []drivers.Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	snapshots := []drivers.Snapshot{}
+	current := ""
+
+	err = parseKeyValues(stdout, reSnapshotLine, func(key, val string) error {
+		switch {
+		case key == "CurrentSnapshotName":
+			current = val
+		case key == "SnapshotName" || strings.HasPrefix(key, "SnapshotName-"):
+			snapshots = append(snapshots, drivers.Snapshot{Name: val})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		snapshots[i].Current = snapshots[i].Name == current
+	}
+
+	return snapshots, nil
+}
+
+// RestoreSnapshot rolls the VM back to a snapshot. VirtualBox can only
+// restore a VM which is powered off, so a running VM is powered off first
+// and started again afterwards.
+func (d *Driver) RestoreSnapshot(name string) error {
+	if err := d.checkSnapshotExists(name); err != nil {
+		return err
+	}
+
+	s, err := d.GetState()
+	if err != nil {
+		return err
+	}
+
+	wasRunning := s == state.Running || s == state.Paused
+	if wasRunning {
+		log.Infof("Powering off the VM...")
+		if err := d.Kill(); err != nil {
+			return err
+		}
+		// vbox will not release it's lock immediately after the poweroff
+		d.sleeper.Sleep(1 * time.Second)
+	}
+
+	if err := d.vbm("snapshot", d.MachineName, "restore", name); err != nil {
+		return err
+	}
+
+	d.IPAddress = ""
+
+	if !wasRunning {
+		return nil
+	}
+
+	return d.Start()
+}
+
+// RemoveSnapshot deletes a snapshot. The state of the VM is left untouched.
+func (d *Driver) RemoveSnapshot(name string) error {
+	if err := d.checkSnapshotExists(name); err != nil {
+		return err
+	}
+
+	return d.vbm("snapshot", d.MachineName, "delete", name)
+}
+
+func (d *Driver) checkSnapshotExists(name string) error {
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Snapshot %q does not exist", name)
+}
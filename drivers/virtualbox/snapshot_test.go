@@ -0,0 +1,137 @@
+package virtualbox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/stretchr/testify/assert"
+)
+
+const snapshotList = `SnapshotName="clean"
+SnapshotUUID="8a5bc9a4-3bcb-4e6e-94e7-e4e1f4d9a7a1"
+SnapshotName-1="with-registry"
+SnapshotUUID-1="0f1aa5a2-c6c5-44f1-9fba-5b5f3c1f0c42"
+CurrentSnapshotName="with-registry"
+CurrentSnapshotUUID="0f1aa5a2-c6c5-44f1-9fba-5b5f3c1f0c42"
+CurrentSnapshotNode="SnapshotName-1"
+`
+
+// vbmCallsMock answers several commands and records the ones it runs.
+type vbmCallsMock struct {
+	outputs map[string]string
+	calls   []string
+}
+
+func (v *vbmCallsMock) vbm(args ...string) error {
+	_, _, err := v.vbmOutErr(args...)
+	return err
+}
+
+func (v *vbmCallsMock) vbmOut(args ...string) (string, error) {
+	stdout, _, err := v.vbmOutErr(args...)
+	return stdout, err
+}
+
+func (v *vbmCallsMock) vbmOutErr(args ...string) (string, string, error) {
+	cmd := strings.Join(args, " ")
+	v.calls = append(v.calls, cmd)
+
+	stdout, ok := v.outputs[cmd]
+	if !ok {
+		return "", "", errors.New("Invalid args")
+	}
+	return stdout, "", nil
+}
+
+func TestListSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: snapshotList,
+	}
+
+	snapshots, err := driver.ListSnapshots()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []drivers.Snapshot{
+		{Name: "clean"},
+		{Name: "with-registry", Current: true},
+	}, snapshots)
+}
+
+func TestListNoSnapshots(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: "This machine does not have any snapshots\n",
+		err:    errors.New("exit status 1"),
+	}
+
+	snapshots, err := driver.ListSnapshots()
+
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable": snapshotList,
+			"snapshot default take fresh":             "",
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbm
+
+	err := driver.CreateSnapshot("fresh")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot default take fresh", vbm.calls[len(vbm.calls)-1])
+}
+
+func TestCreateExistingSnapshot(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: snapshotList,
+	}
+
+	err := driver.CreateSnapshot("clean")
+
+	assert.EqualError(t, err, `Snapshot "clean" already exists`)
+}
+
+func TestRestoreSnapshotOfStoppedVM(t *testing.T) {
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"snapshot default list --machinereadable": snapshotList,
+			"showvminfo default --machinereadable":    `VMState="poweroff"`,
+			"snapshot default restore clean":          "",
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbm
+
+	err := driver.RestoreSnapshot("clean")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"snapshot default list --machinereadable",
+		"showvminfo default --machinereadable",
+		"snapshot default restore clean",
+	}, vbm.calls)
+}
+
+func TestRemoveMissingSnapshot(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args:   "snapshot default list --machinereadable",
+		stdOut: snapshotList,
+	}
+
+	err := driver.RemoveSnapshot("missing")
+
+	assert.EqualError(t, err, `Snapshot "missing" does not exist`)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -271,12 +272,22 @@ func (d *Driver) IsVTXDisabledInTheVM() (bool, error) {
 }
 
 func (d *Driver) Create() error {
+	return d.CreateContext(context.Background())
+}
+
+// CreateContext creates and starts the VM until ctx is done. The VM created so
+// far is left for Remove to delete then.
+func (d *Driver) CreateContext(ctx context.Context) error {
 	if err := d.CreateVM(); err != nil {
 		return err
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	log.Info("Starting the VM...")
-	return d.Start()
+	return d.startContext(ctx)
 }
 
 func (d *Driver) CreateVM() error {
@@ -471,6 +482,11 @@ func (d *Driver) hostOnlyIPAvailable() bool {
 }
 
 func (d *Driver) Start() error {
+	return d.startContext(context.Background())
+}
+
+// startContext starts the VM and waits for its IP until ctx is done.
+func (d *Driver) startContext(ctx context.Context) error {
 	s, err := d.GetState()
 	if err != nil {
 		return err
@@ -515,7 +531,7 @@ func (d *Driver) Start() error {
 	}
 
 	log.Infof("Waiting for an IP...")
-	if err := d.ipWaiter.Wait(d); err != nil {
+	if err := d.ipWaiter.Wait(ctx, d); err != nil {
 		return err
 	}
 
@@ -563,7 +579,7 @@ func (d *Driver) Start() error {
 	}
 
 	log.Infof("Waiting for an IP...")
-	return d.ipWaiter.Wait(d)
+	return d.ipWaiter.Wait(ctx, d)
 }
 
 func (d *Driver) Stop() error {
@@ -579,6 +595,12 @@ func (d *Driver) Stop() error {
 		log.Infof("Resuming VM ...")
 	}
 
+	// A suspended VM is not running, stopping it means dropping its state.
+	if currentState == state.Saved {
+		d.IPAddress = ""
+		return d.vbm("discardstate", d.MachineName)
+	}
+
 	if err := d.vbm("controlvm", d.MachineName, "acpipowerbutton"); err != nil {
 		return err
 	}
@@ -607,13 +629,27 @@ func (d *Driver) Restart() error {
 
 	d.IPAddress = ""
 
-	return d.ipWaiter.Wait(d)
+	return d.ipWaiter.Wait(context.Background(), d)
 }
 
 func (d *Driver) Kill() error {
 	return d.vbm("controlvm", d.MachineName, "poweroff")
 }
 
+// Pause freezes the VM. It keeps its memory and its IP address.
+func (d *Driver) Pause() error {
+	return d.vbm("controlvm", d.MachineName, "pause")
+}
+
+func (d *Driver) Unpause() error {
+	return d.vbm("controlvm", d.MachineName, "resume")
+}
+
+// Suspend saves the state of the VM to disk and stops it. Start resumes it.
+func (d *Driver) Suspend() error {
+	return d.vbm("controlvm", d.MachineName, "savestate")
+}
+
 func (d *Driver) Remove() error {
 	s, err := d.GetState()
 	if err != nil {
@@ -627,6 +663,10 @@ func (d *Driver) Remove() error {
 		if err := d.Stop(); err != nil {
 			return err
 		}
+	} else if s == state.Saved {
+		if err := d.vbm("discardstate", d.MachineName); err != nil {
+			return err
+		}
 	} else if s != state.Stopped {
 		if err := d.Kill(); err != nil {
 			return err
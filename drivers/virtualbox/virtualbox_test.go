@@ -13,6 +13,7 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
 type VBoxManagerMock struct {
@@ -263,7 +264,7 @@ func (v *MockCreateOperations) Read(path string) ([]string, error) {
 	return []string{}, err
 }
 
-func (v *MockCreateOperations) Wait(d *Driver) error {
+func (v *MockCreateOperations) Wait(ctx context.Context, d *Driver) error {
 	_, err := v.doCall("WaitIP")
 	return err
 }
@@ -478,3 +479,28 @@ VBoxNetworkName: HostInterfaceNetworking-VirtualBox Host-Only Ethernet Adapter`,
 
 	assert.NoError(t, err)
 }
+
+func TestSuspend(t *testing.T) {
+	driver := newTestDriver("default")
+	driver.VBoxManager = &VBoxManagerMock{
+		args: "controlvm default savestate",
+	}
+
+	assert.NoError(t, driver.Suspend())
+}
+
+func TestStopSuspendedVM(t *testing.T) {
+	vbm := &vbmCallsMock{
+		outputs: map[string]string{
+			"showvminfo default --machinereadable": `VMState="saved"`,
+			"discardstate default":                 "",
+		},
+	}
+	driver := newTestDriver("default")
+	driver.VBoxManager = vbm
+
+	err := driver.Stop()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"showvminfo default --machinereadable", "discardstate default"}, vbm.calls)
+}
@@ -0,0 +1,271 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/net/context"
+)
+
+// DefaultParallel is the number of machines acted upon at the same time
+// when no limit is given. Cloud providers tend to rate limit clients which
+// send too many requests at once.
+const DefaultParallel = 10
+
+// Action is an operation which can be applied to many machines at once.
+type Action string
+
+const (
+	Start           Action = "start"
+	Stop            Action = "stop"
+	Restart         Action = "restart"
+	Kill            Action = "kill"
+	Pause           Action = "pause"
+	Unpause         Action = "unpause"
+	Suspend         Action = "suspend"
+	Upgrade         Action = "upgrade"
+	RegenerateCerts Action = "regenerate-certs"
+	Remove          Action = "rm"
+)
+
+type Options struct {
+	// Parallel is the maximum number of machines acted upon at the same
+	// time. Zero or less means DefaultParallel.
+	Parallel int
+
+	// Force removes machines from the store even if the driver fails to
+	// remove them. It is only used by Remove.
+	Force bool
+}
+
+// Result is the outcome of an action on a single machine.
+type Result struct {
+	Name     string
+	Action   Action
+	Err      error
+	Duration time.Duration
+}
+
+func (r Result) Succeeded() bool {
+	return r.Err == nil
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	errMsg := ""
+	if r.Err != nil {
+		errMsg = r.Err.Error()
+	}
+
+	return json.Marshal(struct {
+		Name     string
+		Action   Action
+		Success  bool
+		Error    string
+		Duration float64
+	}{
+		Name:     r.Name,
+		Action:   r.Action,
+		Success:  r.Succeeded(),
+		Error:    errMsg,
+		Duration: r.Duration.Seconds(),
+	})
+}
+
+// Errors returns the errors of the failed results.
+func Errors(results []Result) []error {
+	errs := []error{}
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// ForEach applies fn to every host with at most parallel hosts in flight.
+// The results are in the same order as the hosts.
+func ForEach(ctx context.Context, hosts []*host.Host, parallel int, fn func(context.Context, *host.Host) error) []Result {
+	names := make([]string, len(hosts))
+	for i, h := range hosts {
+		names[i] = h.Name
+	}
+
+	return forEachName(ctx, names, parallel, func(ctx context.Context, i int) error {
+		return fn(ctx, hosts[i])
+	})
+}
+
+// Run loads the named machines from the api and applies the action to each
+// of them with at most opts.Parallel machines in flight. Machines are saved
+// back to the store after a successful action, and their drivers are closed
+// once they are done. The results are in the same order as the names.
+func Run(ctx context.Context, api libmachine.API, action Action, names []string, opts Options) []Result {
+	store := &lockedStore{api: api}
+
+	results := forEachName(ctx, names, opts.Parallel, func(ctx context.Context, i int) error {
+		if action == Remove {
+			return remove(ctx, store, names[i], opts.Force)
+		}
+
+		h, err := store.Load(names[i])
+		if err != nil {
+			return err
+		}
+		defer drivers.Close(h.Driver)
+
+		if action == Stop || action == Kill {
+			// Saved first so that a watcher does not take the machine
+			// for down while it stops.
+			h.RequestStop()
+			if err := store.Save(h); err != nil {
+				return fmt.Errorf("Error saving host to store: %s", err)
+			}
+		}
+
+		if err := runAction(ctx, action, h); err != nil {
+			return err
+		}
+
+		if err := store.Save(h); err != nil {
+			return fmt.Errorf("Error saving host to store: %s", err)
+		}
+
+		return nil
+	})
+
+	for i := range results {
+		results[i].Action = action
+	}
+
+	return results
+}
+
+func runAction(ctx context.Context, action Action, h *host.Host) error {
+	log.Debugf("command=%s machine=%s", action, h.Name)
+
+	switch action {
+	case Start:
+		return h.StartContext(ctx)
+	case Stop:
+		return h.StopContext(ctx)
+	case Restart:
+		return h.RestartContext(ctx)
+	case Kill:
+		return h.KillContext(ctx)
+	case Pause:
+		return h.PauseContext(ctx)
+	case Unpause:
+		return h.UnpauseContext(ctx)
+	case Suspend:
+		return h.SuspendContext(ctx)
+	case Upgrade:
+		return mcnutils.RunWithContext(ctx, h.Upgrade)
+	case RegenerateCerts:
+		return mcnutils.RunWithContext(ctx, h.ConfigureAuth)
+	}
+
+	return fmt.Errorf("Unknown action %q", action)
+}
+
+func remove(ctx context.Context, store *lockedStore, name string, force bool) error {
+	h, err := store.Load(name)
+	if err == nil {
+		err = h.RemoveContext(ctx)
+		drivers.Close(h.Driver)
+	}
+
+	if err != nil {
+		if !force {
+			return err
+		}
+		log.Errorf("Error removing host %q: %s", name, err)
+	}
+
+	exists, _ := store.Exists(name)
+	if !exists {
+		return fmt.Errorf("%s does not exist.", name)
+	}
+
+	if err := store.Remove(name); err != nil {
+		return fmt.Errorf("Can't remove %q from the store: %s", name, err)
+	}
+
+	return nil
+}
+
+func forEachName(ctx context.Context, names []string, parallel int, fn func(context.Context, int) error) []Result {
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+
+	var (
+		results = make([]Result, len(names))
+		slots   = make(chan struct{}, parallel)
+		wg      sync.WaitGroup
+	)
+
+	for i, name := range names {
+		results[i].Name = name
+
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			start := time.Now()
+			results[i].Err = fn(ctx, i)
+			results[i].Duration = time.Since(start)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// lockedStore serializes the accesses to the store of the API, which is not
+// required to be safe for concurrent use.
+type lockedStore struct {
+	lock sync.RWMutex
+	api  libmachine.API
+}
+
+func (s *lockedStore) Load(name string) (*host.Host, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.api.Load(name)
+}
+
+func (s *lockedStore) Exists(name string) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.api.Exists(name)
+}
+
+func (s *lockedStore) Save(h *host.Host) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.api.Save(h)
+}
+
+func (s *lockedStore) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.api.Remove(name)
+}
@@ -0,0 +1,193 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type failingRemoveDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *failingRemoveDriver) Remove() error {
+	return errors.New("unable to remove")
+}
+
+type closingDriver struct {
+	*fakedriver.Driver
+	closed bool
+}
+
+func (d *closingDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func newHost(name string, s state.State) *host.Host {
+	return &host.Host{
+		Name: name,
+		Driver: &fakedriver.Driver{
+			MockState: s,
+		},
+	}
+}
+
+func TestRunResults(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			newHost("foo", state.Running),
+			newHost("bar", state.Running),
+		},
+	}
+
+	results := Run(context.Background(), api, Stop, []string{"foo", "missing", "bar"}, Options{})
+
+	assert.Len(t, results, 3)
+	assert.Equal(t, "foo", results[0].Name)
+	assert.Equal(t, "missing", results[1].Name)
+	assert.Equal(t, "bar", results[2].Name)
+	for _, result := range results {
+		assert.Equal(t, Stop, result.Action)
+	}
+
+	assert.True(t, results[0].Succeeded())
+	assert.False(t, results[1].Succeeded())
+	assert.True(t, results[2].Succeeded())
+	assert.Len(t, Errors(results), 1)
+
+	assert.Equal(t, state.Stopped, libmachinetest.State(api, "foo"))
+	assert.Equal(t, state.Stopped, libmachinetest.State(api, "bar"))
+}
+
+func TestRunStartStop(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			newHost("foo", state.Stopped),
+			newHost("bar", state.Stopped),
+			newHost("baz", state.Stopped),
+		},
+	}
+	names := []string{"foo", "bar", "baz"}
+
+	assert.Empty(t, Errors(Run(context.Background(), api, Start, names, Options{})))
+	for _, name := range names {
+		assert.Equal(t, state.Running, libmachinetest.State(api, name))
+	}
+
+	assert.Empty(t, Errors(Run(context.Background(), api, Stop, names, Options{})))
+	for _, name := range names {
+		assert.Equal(t, state.Stopped, libmachinetest.State(api, name))
+	}
+}
+
+func TestRunClosesDrivers(t *testing.T) {
+	driver := &closingDriver{Driver: &fakedriver.Driver{MockState: state.Running}}
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{{Name: "foo", Driver: driver}},
+	}
+
+	results := Run(context.Background(), api, Stop, []string{"foo"}, Options{})
+
+	assert.Empty(t, Errors(results))
+	assert.True(t, driver.closed)
+}
+
+func TestRunCancelled(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			newHost("foo", state.Running),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := Run(ctx, api, Kill, []string{"foo"}, Options{})
+
+	assert.Equal(t, context.Canceled, results[0].Err)
+	assert.Equal(t, state.Running, libmachinetest.State(api, "foo"))
+}
+
+func TestRunRemove(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			newHost("foo", state.Running),
+			{
+				Name:   "bar",
+				Driver: &failingRemoveDriver{&fakedriver.Driver{}},
+			},
+		},
+	}
+
+	results := Run(context.Background(), api, Remove, []string{"foo", "bar"}, Options{})
+
+	assert.NoError(t, results[0].Err)
+	assert.EqualError(t, results[1].Err, "unable to remove")
+	assert.False(t, libmachinetest.Exists(api, "foo"))
+	assert.True(t, libmachinetest.Exists(api, "bar"))
+}
+
+func TestRunRemoveForce(t *testing.T) {
+	api := &libmachinetest.FakeAPI{
+		Hosts: []*host.Host{
+			{
+				Name:   "bar",
+				Driver: &failingRemoveDriver{&fakedriver.Driver{}},
+			},
+		},
+	}
+
+	results := Run(context.Background(), api, Remove, []string{"bar"}, Options{Force: true})
+
+	assert.NoError(t, results[0].Err)
+	assert.False(t, libmachinetest.Exists(api, "bar"))
+}
+
+func TestForEachParallelLimit(t *testing.T) {
+	hosts := []*host.Host{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		hosts = append(hosts, newHost(name, state.Running))
+	}
+
+	var (
+		lock     sync.Mutex
+		inFlight int
+		maximum  int
+		release  = make(chan struct{})
+	)
+
+	go func() {
+		for range hosts {
+			release <- struct{}{}
+		}
+	}()
+
+	results := ForEach(context.Background(), hosts, 2, func(ctx context.Context, h *host.Host) error {
+		lock.Lock()
+		inFlight++
+		if inFlight > maximum {
+			maximum = inFlight
+		}
+		lock.Unlock()
+
+		<-release
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+
+		return nil
+	})
+
+	assert.Len(t, results, len(hosts))
+	assert.Empty(t, Errors(results))
+	assert.True(t, maximum <= 2)
+}
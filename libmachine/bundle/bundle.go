@@ -0,0 +1,471 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/secret"
+)
+
+// Version is the version of the bundle format. It needs to be bumped if
+// older versions of Docker Machine can not import the bundles anymore.
+const Version = 1
+
+const (
+	manifestName  = "manifest.json"
+	configName    = "config.json"
+	certsPrefix   = "certs/"
+	machinePrefix = "machine/"
+)
+
+// localDrivers are the drivers whose VM is registered with a hypervisor of
+// the host, which can not be moved along with the bundle.
+var localDrivers = []string{"hyperv", "virtualbox", "vmwarefusion"}
+
+// vmArtifacts are the extensions of the disks and images of VMs, which are
+// not exported.
+var vmArtifacts = []string{".iso", ".img", ".qcow2", ".vdi", ".vhd", ".vhdx", ".vmdk", ".vmem", ".vmsn", ".vmss", ".vmx", ".nvram", ".sav"}
+
+var (
+	errBundleFromFuture = errors.New("Bundle version is from the future, please upgrade your Docker Machine client.")
+	errNoManifest       = errors.New("Invalid bundle: the manifest must be its first entry")
+	errNoConfig         = errors.New("Invalid bundle: no machine configuration")
+)
+
+// ErrLocalDriver is returned when exporting a machine whose VM runs on the
+// host.
+type ErrLocalDriver struct {
+	DriverName string
+}
+
+func (e ErrLocalDriver) Error() string {
+	return fmt.Sprintf("Machines of the %s driver can not be exported, their VM is registered with the hypervisor of this host", e.DriverName)
+}
+
+// ErrHooks is returned when importing a machine with hooks unless they are
+// allowed, since the commands of the hooks are run on this host.
+type ErrHooks struct {
+	Name  string
+	Hooks []string
+}
+
+func (e ErrHooks) Error() string {
+	return fmt.Sprintf("Machine %q has hooks, which run commands when it is managed: %s", e.Name, strings.Join(e.Hooks, ", "))
+}
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	Version    int
+	Name       string
+	DriverName string
+
+	// StorePath is the path of the store the machine has been exported
+	// from. Every path under it is rewritten when the machine is imported.
+	StorePath string
+
+	// Redacted is true if the credentials of the driver have been removed.
+	Redacted bool
+
+	// CAKey is true if the private key of the CA is part of the bundle.
+	CAKey bool
+}
+
+type ExportOptions struct {
+	// Redact removes the credentials from the configuration of the driver.
+	Redact bool
+
+	// IncludeCAKey adds the private key of the CA to the bundle, so that
+	// the certificates of the machine can be regenerated once imported.
+	IncludeCAKey bool
+}
+
+type ImportOptions struct {
+	// AllowHooks imports the hooks of the machine, which run commands on
+	// this host.
+	AllowHooks bool
+}
+
+// Export writes the configuration, the client certificates and the content
+// of the directory of a machine as a gzipped tarball. The disks and images of
+// VMs are left out, and the machines of the drivers running a VM on the host
+// can not be exported.
+func Export(w io.Writer, store persist.Store, storePath, name string, opts ExportOptions) error {
+	h, err := store.Load(name)
+	if err != nil {
+		return err
+	}
+
+	for _, driverName := range localDrivers {
+		if h.DriverName == driverName {
+			return ErrLocalDriver{DriverName: h.DriverName}
+		}
+	}
+
+	rawDriver, err := json.Marshal(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	if opts.Redact {
+		if rawDriver, err = secret.RedactFields(h.DriverName, rawDriver); err != nil {
+			return err
+		}
+	}
+
+	exported := *h
+	exported.Driver = &host.RawDataDriver{Data: rawDriver}
+
+	config, err := json.MarshalIndent(exported, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(Manifest{
+		Version:    Version,
+		Name:       h.Name,
+		DriverName: h.DriverName,
+		StorePath:  storePath,
+		Redacted:   opts.Redact,
+		CAKey:      opts.IncludeCAKey,
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeEntry(tw, manifestName, manifest, 0600); err != nil {
+		return err
+	}
+
+	if err := writeEntry(tw, configName, config, 0600); err != nil {
+		return err
+	}
+
+	if authOptions := h.AuthOptions(); authOptions != nil {
+		certs := []struct {
+			name, path string
+		}{
+			{"ca.pem", authOptions.CaCertPath},
+			{"cert.pem", authOptions.ClientCertPath},
+			{"key.pem", authOptions.ClientKeyPath},
+		}
+
+		if opts.IncludeCAKey {
+			if authOptions.CaPrivateKeyPath == "" {
+				return errors.New("The machine has no CA private key to export")
+			}
+			certs = append(certs, struct {
+				name, path string
+			}{"ca-key.pem", authOptions.CaPrivateKeyPath})
+		}
+
+		for _, cert := range certs {
+			if cert.path == "" {
+				continue
+			}
+			if err := writeFile(tw, certsPrefix+cert.name, cert.path); err != nil {
+				return fmt.Errorf("Error exporting certificate %s: %s", cert.path, err)
+			}
+		}
+	}
+
+	machineDir := filepath.Join(storePath, "machines", name)
+	if err := filepath.Walk(machineDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(machineDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		// The configuration has its own entry.
+		if rel == "." || strings.HasPrefix(rel, configName) {
+			return nil
+		}
+
+		entryName := machinePrefix + filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			return tw.WriteHeader(&tar.Header{
+				Name:     entryName + "/",
+				Mode:     int64(info.Mode().Perm()),
+				ModTime:  info.ModTime(),
+				Typeflag: tar.TypeDir,
+			})
+		}
+
+		if !info.Mode().IsRegular() || isVMArtifact(filePath) {
+			return nil
+		}
+
+		return writeFile(tw, entryName, filePath)
+	}); err != nil {
+		return fmt.Errorf("Error exporting the directory of the machine: %s", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+func isVMArtifact(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	for _, artifact := range vmArtifacts {
+		if ext == artifact {
+			return true
+		}
+	}
+	return false
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, mode os.FileMode) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     int64(mode),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeFile(tw *tar.Writer, name, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Import reads a bundle written by Export and adds the machine to the
+// store. Every path of its configuration is rewritten for storePath and the
+// client certificates of the bundle are installed in the directory of the
+// machine, since they are not signed by the CA of this store. So is the
+// private key of the CA if it is part of the bundle, otherwise the machine
+// has none and its certificates can not be regenerated. A machine with hooks
+// is only imported if opts allow them.
+func Import(r io.Reader, store persist.Store, storePath string, opts ImportOptions) (*Manifest, *host.Host, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid bundle: %s", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := manifest.Name
+	if !host.ValidateHostName(name) {
+		return nil, nil, mcnerror.ErrInvalidHostname
+	}
+
+	machinesDir := filepath.Join(storePath, "machines")
+	machineDir := filepath.Join(machinesDir, name)
+
+	exists, err := store.Exists(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := os.Stat(machineDir); exists || err == nil {
+		return nil, nil, mcnerror.ErrHostAlreadyExists{Name: name}
+	}
+
+	if err := os.MkdirAll(machinesDir, 0700); err != nil {
+		return nil, nil, err
+	}
+
+	// Extract next to the final directory so that a failed import leaves
+	// nothing behind.
+	stagingDir, err := ioutil.TempDir(machinesDir, ".import-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	config, err := extract(tr, stagingDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config == nil {
+		return nil, nil, errNoConfig
+	}
+
+	config, err = persist.RewritePaths(config, manifest.StorePath, storePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error rewriting the paths of the machine: %s", err)
+	}
+
+	h, _, err := host.MigrateHost(&host.Host{Name: name}, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading the configuration of the machine: %s", err)
+	}
+	h.Name = name
+
+	if hooks := h.Hooks(); hooks != nil && len(hooks.Hooks) > 0 && !opts.AllowHooks {
+		e := ErrHooks{Name: name}
+		for _, hk := range hooks.Hooks {
+			e.Hooks = append(e.Hooks, hk.String())
+		}
+		return nil, nil, e
+	}
+
+	if authOptions := h.AuthOptions(); authOptions != nil {
+		certsDir := filepath.Join(machineDir, "certs")
+		authOptions.CertDir = certsDir
+		authOptions.CaCertPath = filepath.Join(certsDir, "ca.pem")
+		authOptions.CaPrivateKeyPath = ""
+		if _, err := os.Stat(filepath.Join(stagingDir, "certs", "ca-key.pem")); err == nil {
+			authOptions.CaPrivateKeyPath = filepath.Join(certsDir, "ca-key.pem")
+		}
+		authOptions.ClientCertPath = filepath.Join(certsDir, "cert.pem")
+		authOptions.ClientKeyPath = filepath.Join(certsDir, "key.pem")
+	}
+
+	if err := os.Rename(stagingDir, machineDir); err != nil {
+		return nil, nil, err
+	}
+
+	if err := store.Save(h); err != nil {
+		os.RemoveAll(machineDir)
+		return nil, nil, fmt.Errorf("Error saving the imported machine: %s", err)
+	}
+
+	return manifest, h, nil
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid bundle: %s", err)
+	}
+
+	if header.Name != manifestName {
+		return nil, errNoManifest
+	}
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("Invalid bundle manifest: %s", err)
+	}
+
+	if manifest.Version > Version {
+		return nil, errBundleFromFuture
+	}
+
+	return manifest, nil
+}
+
+// extract writes the certificates and the files of the machine to dir and
+// returns the configuration of the machine.
+func extract(tr *tar.Reader, dir string) ([]byte, error) {
+	var config []byte
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return config, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bundle: %s", err)
+		}
+
+		if header.Name == configName {
+			if config, err = ioutil.ReadAll(tr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var rel string
+		switch {
+		case strings.HasPrefix(header.Name, certsPrefix):
+			rel = header.Name
+		case strings.HasPrefix(header.Name, machinePrefix):
+			rel = strings.TrimPrefix(header.Name, machinePrefix)
+		default:
+			continue
+		}
+
+		// Never write outside of the directory of the machine.
+		rel = path.Clean("/" + rel)[1:]
+		if rel == "" {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := extractFile(tr, target, os.FileMode(header.Mode).Perm()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,293 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T) (*persist.Filestore, func()) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return persist.NewFilestore(tmpDir, "", ""), func() { os.RemoveAll(tmpDir) }
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func saveTestHost(t *testing.T, store *persist.Filestore) *host.Host {
+	machineDir := filepath.Join(store.GetMachinesDir(), "dev")
+	certsDir := filepath.Join(store.Path, "certs")
+
+	writeTestFile(t, filepath.Join(certsDir, "ca.pem"), "ca")
+	writeTestFile(t, filepath.Join(certsDir, "cert.pem"), "cert")
+	writeTestFile(t, filepath.Join(certsDir, "key.pem"), "key")
+	writeTestFile(t, filepath.Join(machineDir, "id_rsa"), "ssh key")
+	writeTestFile(t, filepath.Join(machineDir, "server.pem"), "server cert")
+
+	h := &host.Host{
+		ConfigVersion: version.ConfigVersion,
+		Name:          "dev",
+		DriverName:    "openstack",
+		Driver: &host.RawDataDriver{
+			Data: []byte(`{"MachineName":"dev","StorePath":"` + store.Path + `","SSHKeyPath":"` + filepath.Join(machineDir, "id_rsa") + `","Password":"hunter2"}`),
+		},
+		HostOptions: &host.Options{
+			AuthOptions: &auth.Options{
+				CertDir:          certsDir,
+				CaCertPath:       filepath.Join(certsDir, "ca.pem"),
+				CaPrivateKeyPath: filepath.Join(certsDir, "ca-key.pem"),
+				ClientCertPath:   filepath.Join(certsDir, "cert.pem"),
+				ClientKeyPath:    filepath.Join(certsDir, "key.pem"),
+				ServerCertPath:   filepath.Join(machineDir, "server.pem"),
+				StorePath:        machineDir,
+			},
+		},
+	}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestExportImport(t *testing.T) {
+	src, cleanupSrc := newTestStore(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestStore(t)
+	defer cleanupDst()
+
+	saveTestHost(t, src)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, src, src.Path, "dev", ExportOptions{}))
+
+	manifest, h, err := Import(buf, dst, dst.Path, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", manifest.Name)
+	assert.Equal(t, src.Path, manifest.StorePath)
+	assert.False(t, manifest.Redacted)
+
+	machineDir := filepath.Join(dst.GetMachinesDir(), "dev")
+	certsDir := filepath.Join(machineDir, "certs")
+
+	authOptions := h.AuthOptions()
+	assert.Equal(t, certsDir, authOptions.CertDir)
+	assert.Equal(t, filepath.Join(certsDir, "ca.pem"), authOptions.CaCertPath)
+	assert.Empty(t, authOptions.CaPrivateKeyPath)
+	assert.Equal(t, filepath.Join(certsDir, "cert.pem"), authOptions.ClientCertPath)
+	assert.Equal(t, filepath.Join(certsDir, "key.pem"), authOptions.ClientKeyPath)
+	assert.Equal(t, filepath.Join(machineDir, "server.pem"), authOptions.ServerCertPath)
+	assert.Equal(t, machineDir, authOptions.StorePath)
+
+	for file, content := range map[string]string{
+		filepath.Join(certsDir, "ca.pem"):       "ca",
+		filepath.Join(certsDir, "key.pem"):      "key",
+		filepath.Join(machineDir, "id_rsa"):     "ssh key",
+		filepath.Join(machineDir, "server.pem"): "server cert",
+	} {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	loaded, err := dst.Load("dev")
+	assert.NoError(t, err)
+
+	var driver map[string]string
+	assert.NoError(t, json.Unmarshal(loaded.RawDriver, &driver))
+	assert.Equal(t, filepath.Join(machineDir, "id_rsa"), driver["SSHKeyPath"])
+	assert.Equal(t, dst.Path, driver["StorePath"])
+	assert.Equal(t, "hunter2", driver["Password"])
+}
+
+func TestExportRedact(t *testing.T) {
+	src, cleanupSrc := newTestStore(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestStore(t)
+	defer cleanupDst()
+
+	saveTestHost(t, src)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, src, src.Path, "dev", ExportOptions{Redact: true}))
+	assert.False(t, bytes.Contains(gunzip(t, buf.Bytes()), []byte("hunter2")))
+
+	manifest, h, err := Import(buf, dst, dst.Path, ImportOptions{})
+	assert.NoError(t, err)
+	assert.True(t, manifest.Redacted)
+	assert.Equal(t, "dev", h.Name)
+}
+
+func TestExportCAKey(t *testing.T) {
+	src, cleanupSrc := newTestStore(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestStore(t)
+	defer cleanupDst()
+
+	saveTestHost(t, src)
+	writeTestFile(t, filepath.Join(src.Path, "certs", "ca-key.pem"), "ca key")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, src, src.Path, "dev", ExportOptions{IncludeCAKey: true}))
+
+	manifest, h, err := Import(buf, dst, dst.Path, ImportOptions{})
+	assert.NoError(t, err)
+	assert.True(t, manifest.CAKey)
+
+	caKeyPath := filepath.Join(dst.GetMachinesDir(), "dev", "certs", "ca-key.pem")
+	assert.Equal(t, caKeyPath, h.AuthOptions().CaPrivateKeyPath)
+
+	data, err := ioutil.ReadFile(caKeyPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "ca key", string(data))
+}
+
+func TestExportSkipsVMArtifacts(t *testing.T) {
+	src, cleanupSrc := newTestStore(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestStore(t)
+	defer cleanupDst()
+
+	saveTestHost(t, src)
+	writeTestFile(t, filepath.Join(src.GetMachinesDir(), "dev", "disk.vmdk"), "disk")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, src, src.Path, "dev", ExportOptions{}))
+
+	_, _, err := Import(buf, dst, dst.Path, ImportOptions{})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dst.GetMachinesDir(), "dev", "disk.vmdk"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestImportHooks(t *testing.T) {
+	src, cleanupSrc := newTestStore(t)
+	defer cleanupSrc()
+	dst, cleanupDst := newTestStore(t)
+	defer cleanupDst()
+
+	h := saveTestHost(t, src)
+	h.HostOptions.HookOptions = &hook.Options{
+		Hooks: []hook.Hook{{Event: hook.PostCreate, Command: "touch /tmp/pwned"}},
+	}
+	assert.NoError(t, src.Save(h))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, src, src.Path, "dev", ExportOptions{}))
+	bundle := buf.Bytes()
+
+	_, _, err := Import(bytes.NewReader(bundle), dst, dst.Path, ImportOptions{})
+	assert.Equal(t, ErrHooks{Name: "dev", Hooks: []string{`post-create command "touch /tmp/pwned"`}}, err)
+
+	exists, err := dst.Exists("dev")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	_, imported, err := Import(bytes.NewReader(bundle), dst, dst.Path, ImportOptions{AllowHooks: true})
+	assert.NoError(t, err)
+	assert.Len(t, imported.Hooks().Hooks, 1)
+}
+
+func TestExportLocalDriver(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	h := saveTestHost(t, store)
+	h.DriverName = "virtualbox"
+	assert.NoError(t, store.Save(h))
+
+	err := Export(&bytes.Buffer{}, store, store.Path, "dev", ExportOptions{})
+
+	assert.Equal(t, ErrLocalDriver{DriverName: "virtualbox"}, err)
+}
+
+func TestImportExistingMachine(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	saveTestHost(t, store)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Export(buf, store, store.Path, "dev", ExportOptions{}))
+
+	_, _, err := Import(buf, store, store.Path, ImportOptions{})
+	assert.Equal(t, mcnerror.ErrHostAlreadyExists{Name: "dev"}, err)
+}
+
+func TestImportStaysInMachineDir(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, writeEntry(tw, manifestName, []byte(`{"Version":1,"Name":"evil","StorePath":"/old"}`), 0600))
+	assert.NoError(t, writeEntry(tw, configName, []byte(`{"ConfigVersion":3,"DriverName":"none","Driver":{},"HostOptions":{"AuthOptions":{}}}`), 0600))
+	assert.NoError(t, writeEntry(tw, machinePrefix+"../../escaped", []byte("boom"), 0600))
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+
+	_, _, err := Import(buf, store, store.Path, ImportOptions{})
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(store.Path, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(store.GetMachinesDir(), "evil", "escaped"))
+	assert.NoError(t, err)
+}
+
+func TestImportFromFuture(t *testing.T) {
+	store, cleanup := newTestStore(t)
+	defer cleanup()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, writeEntry(tw, manifestName, []byte(`{"Version":42,"Name":"dev"}`), 0600))
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+
+	_, _, err := Import(buf, store, store.Path, ImportOptions{})
+	assert.Equal(t, errBundleFromFuture, err)
+}
+
+func gunzip(t *testing.T, data []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []byte(strings.TrimSpace(string(content)))
+}
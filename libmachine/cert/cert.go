@@ -236,6 +236,58 @@ func (xcg *X509CertGenerator) ReadTLSConfig(addr string, authOptions *auth.Optio
 	return xcg.getTLSConfig(caCert, serverCert, serverKey, false)
 }
 
+// ReadClientTLSConfig reads the tls config used to authenticate with the
+// client certificate against a server whose certificate is signed by the CA.
+func ReadClientTLSConfig(authOptions *auth.Options) (*tls.Config, error) {
+	caCert, cert, key, err := readCertificates(authOptions.CaCertPath, authOptions.ClientCertPath, authOptions.ClientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return (&X509CertGenerator{}).getTLSConfig(caCert, cert, key, false)
+}
+
+// ReadServerTLSConfig reads the tls config of a server which only accepts
+// clients presenting a certificate signed by the CA.
+func ReadServerTLSConfig(authOptions *auth.Options) (*tls.Config, error) {
+	caCert, cert, key, err := readCertificates(authOptions.CaCertPath, authOptions.ServerCertPath, authOptions.ServerKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := (&X509CertGenerator{}).getTLSConfig(caCert, cert, key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig.ClientCAs = tlsConfig.RootCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return tlsConfig, nil
+}
+
+func readCertificates(caCertPath, certPath, keyPath string) ([]byte, []byte, []byte, error) {
+	log.Debugf("Reading CA certificate from %s", caCertPath)
+	caCert, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	log.Debugf("Reading certificate from %s", certPath)
+	cert, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	log.Debugf("Reading key from %s", keyPath)
+	key, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return caCert, cert, key, nil
+}
+
 // ValidateCertificate validate the certificate installed on the vm.
 func (xcg *X509CertGenerator) ValidateCertificate(addr string, authOptions *auth.Options) (bool, error) {
 	tlsConfig, err := xcg.ReadTLSConfig(addr, authOptions)
@@ -40,6 +40,10 @@ type ConnChecker interface {
 type MachineConnChecker struct{}
 
 func (mcc *MachineConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Options, error) {
+	if err := h.CheckNotPaused(); err != nil {
+		return "", &auth.Options{}, err
+	}
+
 	dockerHost, err := h.Driver.GetURL()
 	if err != nil {
 		return "", &auth.Options{}, err
@@ -6,8 +6,12 @@ import (
 
 	"crypto/tls"
 
+	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,3 +62,16 @@ func TestCheckCert(t *testing.T) {
 		assert.Equal(t, c.expectedErr, err)
 	}
 }
+
+func TestCheckSuspendedMachine(t *testing.T) {
+	h := &host.Host{
+		Name: "default",
+		Driver: &fakedriver.Driver{
+			MockState: state.Saved,
+		},
+	}
+
+	_, _, err := DefaultConnChecker.Check(h, false)
+
+	assert.Equal(t, mcnerror.ErrHostPaused{Name: "default", State: state.Saved}, err)
+}
@@ -3,6 +3,7 @@ package drivers
 import (
 	"errors"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -83,3 +84,15 @@ func (d *BaseDriver) SetSwarmConfigFromFlags(flags DriverOptions) {
 	d.SwarmHost = flags.String("swarm-host")
 	d.SwarmDiscovery = flags.String("swarm-discovery")
 }
+
+// SetMachineName updates the name of the machine and the path of its SSH key
+// once the store moved its directory to the one of name.
+func (d *BaseDriver) SetMachineName(name string) {
+	oldDir := d.ResolveStorePath("")
+	newDir := filepath.Join(d.StorePath, "machines", name)
+
+	if strings.HasPrefix(d.SSHKeyPath, oldDir+string(filepath.Separator)) {
+		d.SSHKeyPath = filepath.Join(newDir, d.SSHKeyPath[len(oldDir)+1:])
+	}
+	d.MachineName = name
+}
@@ -2,6 +2,7 @@ package drivers
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,3 +27,16 @@ func TestIP(t *testing.T) {
 		assert.Equal(t, c.expectedErr, err)
 	}
 }
+
+func TestSetMachineName(t *testing.T) {
+	d := &BaseDriver{
+		MachineName: "old",
+		StorePath:   "/store",
+	}
+	d.SSHKeyPath = d.ResolveStorePath("id_rsa")
+
+	d.SetMachineName("new")
+
+	assert.Equal(t, "new", d.MachineName)
+	assert.Equal(t, filepath.Join("/store", "machines", "new", "id_rsa"), d.SSHKeyPath)
+}
@@ -0,0 +1,77 @@
+package drivers
+
+// The optional features of a driver, which are the optional interfaces it
+// implements.
+const (
+	CapabilitySnapshots = "snapshots"
+	CapabilityPausing   = "pausing"
+	CapabilityResizing  = "resizing"
+	CapabilityCloning   = "cloning"
+	CapabilityRenaming  = "renaming"
+	CapabilityCanceling = "canceling"
+)
+
+// CapabilityReporter is implemented by drivers which implement every
+// optional interface on behalf of another driver, such as the RPC client
+// driver, to tell which ones the other driver really supports.
+//
+// A type assertion to an optional interface therefore always succeeds on
+// such a driver, which is what libmachine hands out. Callers must go through
+// GetSnapshotter, GetPauser, GetResizer, GetCloner, GetRenamer, Cancel or
+// Supports, which check the capabilities, instead of asserting the optional
+// interfaces themselves.
+type CapabilityReporter interface {
+	// Capabilities returns the optional features supported by the driver,
+	// and false if it can not tell.
+	Capabilities() ([]string, bool)
+}
+
+// Capabilities returns the optional features supported by d, and false if
+// it can not tell.
+func Capabilities(d Driver) ([]string, bool) {
+	if r, ok := d.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+
+	capabilities := []string{}
+	if _, ok := d.(Snapshotter); ok {
+		capabilities = append(capabilities, CapabilitySnapshots)
+	}
+	if _, ok := d.(Pauser); ok {
+		capabilities = append(capabilities, CapabilityPausing)
+	}
+	if _, ok := d.(Resizer); ok {
+		capabilities = append(capabilities, CapabilityResizing)
+	}
+	if _, ok := d.(Cloner); ok {
+		capabilities = append(capabilities, CapabilityCloning)
+	}
+	if _, ok := d.(Renamer); ok {
+		capabilities = append(capabilities, CapabilityRenaming)
+	}
+	_, canceler := d.(Canceler)
+	_, contextCreator := d.(ContextCreator)
+	if canceler || contextCreator {
+		capabilities = append(capabilities, CapabilityCanceling)
+	}
+
+	return capabilities, true
+}
+
+// Supports returns true unless d is known not to support the optional
+// feature. A driver which can not tell is assumed to support it, and its
+// calls then fail with a FeatureNotSupported error if it does not.
+func Supports(d Driver, capability string) bool {
+	capabilities, known := Capabilities(d)
+	if !known {
+		return true
+	}
+
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockSnapshotter struct {
+	MockDriver
+}
+
+func (d *MockSnapshotter) CreateSnapshot(name string) error   { return nil }
+func (d *MockSnapshotter) ListSnapshots() ([]Snapshot, error) { return nil, nil }
+func (d *MockSnapshotter) RestoreSnapshot(name string) error  { return nil }
+func (d *MockSnapshotter) RemoveSnapshot(name string) error   { return nil }
+
+// MockReporter implements Snapshotter on behalf of another driver, like the
+// RPC client driver does.
+type MockReporter struct {
+	MockSnapshotter
+	capabilities []string
+	known        bool
+}
+
+func (d *MockReporter) Capabilities() ([]string, bool) {
+	return d.capabilities, d.known
+}
+
+func newMockReporter(capabilities []string, known bool) *MockReporter {
+	return &MockReporter{
+		MockSnapshotter: MockSnapshotter{MockDriver{calls: &CallRecorder{}, driverName: "mock"}},
+		capabilities:    capabilities,
+		known:           known,
+	}
+}
+
+func TestCapabilitiesFromInterfaces(t *testing.T) {
+	capabilities, known := Capabilities(&MockDriver{calls: &CallRecorder{}})
+	assert.True(t, known)
+	assert.Empty(t, capabilities)
+
+	capabilities, known = Capabilities(&MockSnapshotter{MockDriver{calls: &CallRecorder{}}})
+	assert.True(t, known)
+	assert.Equal(t, []string{CapabilitySnapshots}, capabilities)
+}
+
+func TestCapabilitiesReported(t *testing.T) {
+	d := newMockReporter([]string{CapabilityPausing}, true)
+
+	assert.False(t, Supports(d, CapabilitySnapshots))
+	assert.True(t, Supports(d, CapabilityPausing))
+
+	_, err := GetSnapshotter(d)
+	assert.Equal(t, FeatureNotSupported{DriverName: "mock", Feature: CapabilitySnapshots}, err)
+}
+
+func TestCapabilitiesUnknown(t *testing.T) {
+	d := newMockReporter(nil, false)
+
+	assert.True(t, Supports(d, CapabilitySnapshots))
+
+	_, err := GetSnapshotter(d)
+	assert.NoError(t, err)
+}
+
+func TestSerialDriverCapabilities(t *testing.T) {
+	d := newSerialDriverWithLock(newMockReporter([]string{CapabilitySnapshots}, true), &MockLocker{calls: &CallRecorder{}})
+
+	capabilities, known := Capabilities(d)
+
+	assert.True(t, known)
+	assert.Equal(t, []string{CapabilitySnapshots}, capabilities)
+	assert.False(t, Supports(d, CapabilityResizing))
+}
+
+func TestWrappersNeedTheHelpers(t *testing.T) {
+	d := newSerialDriverWithLock(&MockDriver{calls: &CallRecorder{}, driverName: "mock"}, &MockLocker{calls: &CallRecorder{}})
+
+	_, ok := d.(Pauser)
+	assert.True(t, ok)
+
+	_, err := GetPauser(d)
+	assert.Equal(t, FeatureNotSupported{DriverName: "mock", Feature: CapabilityPausing}, err)
+}
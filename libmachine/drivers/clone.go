@@ -0,0 +1,41 @@
+package drivers
+
+import "golang.org/x/net/context"
+
+// Cloner is an optional interface for drivers which are able to duplicate
+// their machine. Clone is only called on stopped machines. It creates the
+// machine name as a copy of this one, with its files in the store directory
+// of the new machine, and returns the serialized configuration of the
+// driver of the copy. Callers get it with GetCloner, see CapabilityReporter.
+type Cloner interface {
+	Clone(name string) ([]byte, error)
+}
+
+// GetCloner returns d as a Cloner, or a FeatureNotSupported error if the
+// driver can not clone machines.
+func GetCloner(d Driver) (Cloner, error) {
+	c, ok := d.(Cloner)
+	if !ok || !Supports(d, CapabilityCloning) {
+		return nil, FeatureNotSupported{DriverName: d.DriverName(), Feature: CapabilityCloning}
+	}
+	return c, nil
+}
+
+func CloneWithContext(ctx context.Context, d Driver, name string) ([]byte, error) {
+	c, err := GetCloner(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = callWithContext(ctx, d, func() error {
+		var err error
+		data, err = c.Clone(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
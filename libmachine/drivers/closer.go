@@ -0,0 +1,13 @@
+package drivers
+
+import "io"
+
+// Close releases what the driver holds once its machine is no longer
+// managed, e.g. the plugin binary serving it. Drivers holding nothing do not
+// implement io.Closer.
+func Close(d Driver) error {
+	if c, ok := d.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
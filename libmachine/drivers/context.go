@@ -0,0 +1,332 @@
+package drivers
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
+)
+
+// Canceler is an optional interface for drivers which are able to abort the
+// operation they are currently performing, e.g. a Create which is polling a
+// cloud provider. The RPC client driver implements it by forwarding the
+// request to the plugin server.
+type Canceler interface {
+	Cancel() error
+}
+
+// Cancel asks the driver to abort its current operation if it knows how to.
+func Cancel(d Driver) {
+	c, ok := d.(Canceler)
+	if !ok || !Supports(d, CapabilityCanceling) {
+		return
+	}
+
+	if err := c.Cancel(); err != nil {
+		log.Debugf("Error cancelling driver operation: %s", err)
+	}
+}
+
+// ContextCreator is an optional interface for drivers whose Create stops
+// once ctx is done, e.g. by polling the cloud provider with it. The driver
+// returns ctx.Err() once it has undone or given up on what it started.
+type ContextCreator interface {
+	CreateContext(ctx context.Context) error
+}
+
+// CreateWithContext creates the machine of d until ctx is done. Unlike the
+// other operations it does not return before the driver does, so that the
+// machine is not removed while the driver is still creating it.
+func CreateWithContext(ctx context.Context, d Driver) error {
+	if c, ok := d.(ContextCreator); ok {
+		return c.CreateContext(ctx)
+	}
+	return CreateAndCancel(ctx, d)
+}
+
+// CreateAndCancel runs the Create of d, asks the driver to abort it once ctx
+// is done and waits for it to return. It is the fallback of the drivers which
+// do not implement ContextCreator.
+func CreateAndCancel(ctx context.Context, d Driver) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.Create()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		log.Info("Waiting for the driver to abort machine creation...")
+		Cancel(d)
+		<-errCh
+		return ctx.Err()
+	}
+}
+
+// callWithContext runs a driver operation until it returns or ctx is done.
+// In the latter case the driver is asked to abort the operation and ctx.Err()
+// is returned right away. The call is abandoned rather than stopped: unlike
+// Create, the other operations do not take a context, so the drivers which
+// do not implement Canceler keep running them in the background.
+func callWithContext(ctx context.Context, d Driver, operation func() error) error {
+	err := mcnutils.RunWithContext(ctx, operation)
+	if err != nil && err == ctx.Err() {
+		Cancel(d)
+	}
+	return err
+}
+
+func StartWithContext(ctx context.Context, d Driver) error {
+	return callWithContext(ctx, d, d.Start)
+}
+
+func StopWithContext(ctx context.Context, d Driver) error {
+	return callWithContext(ctx, d, d.Stop)
+}
+
+func RestartWithContext(ctx context.Context, d Driver) error {
+	return callWithContext(ctx, d, d.Restart)
+}
+
+func KillWithContext(ctx context.Context, d Driver) error {
+	return callWithContext(ctx, d, d.Kill)
+}
+
+func RemoveWithContext(ctx context.Context, d Driver) error {
+	return callWithContext(ctx, d, d.Remove)
+}
+
+func WaitForSSHContext(ctx context.Context, d Driver) error {
+	if err := mcnutils.WaitForContext(ctx, sshAvailableFunc(d)); err != nil {
+		if err == ctx.Err() {
+			return err
+		}
+		return fmt.Errorf("Too many retries waiting for SSH to be available.  Last error: %s", err)
+	}
+	return nil
+}
+
+// contextDriver fails the calls of a driver which reach the machine once its
+// context is done, so that whatever is driving the machine, e.g. a
+// provisioner, gives up at its next step. Like SerialDriver, it implements
+// the optional interfaces on behalf of the wrapped driver and reports its
+// capabilities.
+type contextDriver struct {
+	Driver
+	ctx context.Context
+}
+
+// WithContext returns d, bound to ctx. Use ContextOf to wait with it.
+func WithContext(ctx context.Context, d Driver) Driver {
+	return &contextDriver{Driver: d, ctx: ctx}
+}
+
+// ContextOf returns the context d was bound to with WithContext, or
+// context.Background().
+func ContextOf(d Driver) context.Context {
+	if c, ok := d.(*contextDriver); ok {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+func (d *contextDriver) GetIP() (string, error) {
+	if err := d.ctx.Err(); err != nil {
+		return "", err
+	}
+	return d.Driver.GetIP()
+}
+
+func (d *contextDriver) GetSSHHostname() (string, error) {
+	if err := d.ctx.Err(); err != nil {
+		return "", err
+	}
+	return d.Driver.GetSSHHostname()
+}
+
+func (d *contextDriver) GetState() (state.State, error) {
+	if err := d.ctx.Err(); err != nil {
+		return state.Error, err
+	}
+	return d.Driver.GetState()
+}
+
+func (d *contextDriver) Start() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.Driver.Start()
+}
+
+func (d *contextDriver) Stop() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.Driver.Stop()
+}
+
+func (d *contextDriver) Restart() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.Driver.Restart()
+}
+
+func (d *contextDriver) Kill() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return d.Driver.Kill()
+}
+
+// Cancel asks the wrapped driver to abort its current operation.
+func (d *contextDriver) Cancel() error {
+	Cancel(d.Driver)
+	return nil
+}
+
+// CreateContext creates the machine until ctx or the context of d is done.
+func (d *contextDriver) CreateContext(ctx context.Context) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return CreateWithContext(ctx, d.Driver)
+}
+
+// Capabilities returns the optional features of the wrapped driver.
+func (d *contextDriver) Capabilities() ([]string, bool) {
+	return Capabilities(d.Driver)
+}
+
+func (d *contextDriver) CreateSnapshot(name string) error {
+	s, err := d.snapshotter()
+	if err != nil {
+		return err
+	}
+	return s.CreateSnapshot(name)
+}
+
+func (d *contextDriver) ListSnapshots() ([]Snapshot, error) {
+	s, err := d.snapshotter()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListSnapshots()
+}
+
+func (d *contextDriver) RestoreSnapshot(name string) error {
+	s, err := d.snapshotter()
+	if err != nil {
+		return err
+	}
+	return s.RestoreSnapshot(name)
+}
+
+func (d *contextDriver) RemoveSnapshot(name string) error {
+	s, err := d.snapshotter()
+	if err != nil {
+		return err
+	}
+	return s.RemoveSnapshot(name)
+}
+
+func (d *contextDriver) snapshotter() (Snapshotter, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return GetSnapshotter(d.Driver)
+}
+
+func (d *contextDriver) Pause() error {
+	p, err := d.pauser()
+	if err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+func (d *contextDriver) Unpause() error {
+	p, err := d.pauser()
+	if err != nil {
+		return err
+	}
+	return p.Unpause()
+}
+
+func (d *contextDriver) Suspend() error {
+	p, err := d.pauser()
+	if err != nil {
+		return err
+	}
+	return p.Suspend()
+}
+
+func (d *contextDriver) pauser() (Pauser, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return GetPauser(d.Driver)
+}
+
+func (d *contextDriver) Resize(opts ResizeOptions) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+
+	r, err := GetResizer(d.Driver)
+	if err != nil {
+		return err
+	}
+	return r.Resize(opts)
+}
+
+func (d *contextDriver) Clone(name string) ([]byte, error) {
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c, err := GetCloner(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return c.Clone(name)
+}
+
+func (d *contextDriver) Rename(name string) error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+
+	r, err := GetRenamer(d.Driver)
+	if err != nil {
+		return err
+	}
+	return r.Rename(name)
+}
+
+func (d *contextDriver) ReleaseStoreDir() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return ReleaseStoreDir(d.Driver)
+}
+
+func (d *contextDriver) UseStoreDir() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	return UseStoreDir(d.Driver)
+}
+
+// Close releases what the wrapped driver holds, even once the context is
+// done.
+func (d *contextDriver) Close() error {
+	return Close(d.Driver)
+}
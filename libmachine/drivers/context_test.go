@@ -0,0 +1,157 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type BlockingDriver struct {
+	*MockDriver
+	released  chan struct{}
+	cancelled bool
+}
+
+func (d *BlockingDriver) Start() error {
+	d.calls.record("Start")
+	<-d.released
+	return nil
+}
+
+func (d *BlockingDriver) Cancel() error {
+	d.cancelled = true
+	close(d.released)
+	return nil
+}
+
+func TestStartWithContextAlreadyCancelled(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := StartWithContext(ctx, driver)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, driver.calls.calls)
+}
+
+func TestStartWithContextCancelsDriver(t *testing.T) {
+	driver := &BlockingDriver{
+		MockDriver: &MockDriver{calls: &CallRecorder{}},
+		released:   make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+
+	err := StartWithContext(ctx, driver)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, driver.cancelled)
+}
+
+func TestSerialDriverCancelDoesNotLock(t *testing.T) {
+	callRecorder := &CallRecorder{}
+	driver := &BlockingDriver{
+		MockDriver: &MockDriver{calls: callRecorder},
+		released:   make(chan struct{}),
+	}
+	serialDriver := newSerialDriverWithLock(driver, &MockLocker{calls: callRecorder})
+
+	assert.NoError(t, serialDriver.(Canceler).Cancel())
+	assert.True(t, driver.cancelled)
+	assert.Empty(t, callRecorder.calls)
+}
+
+type BlockingCreateDriver struct {
+	*BlockingDriver
+	started chan struct{}
+	created bool
+}
+
+func (d *BlockingCreateDriver) Create() error {
+	close(d.started)
+	<-d.released
+	d.created = true
+	return nil
+}
+
+func TestCreateWithContextWaitsForTheDriver(t *testing.T) {
+	driver := &BlockingCreateDriver{
+		BlockingDriver: &BlockingDriver{
+			MockDriver: &MockDriver{calls: &CallRecorder{}},
+			released:   make(chan struct{}),
+		},
+		started: make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-driver.started
+		cancel()
+	}()
+
+	err := CreateWithContext(ctx, driver)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, driver.cancelled)
+	assert.True(t, driver.created)
+}
+
+type ContextCreatorDriver struct {
+	*MockDriver
+	ctx context.Context
+}
+
+func (d *ContextCreatorDriver) CreateContext(ctx context.Context) error {
+	d.ctx = ctx
+	return nil
+}
+
+func TestCreateWithContextCreator(t *testing.T) {
+	driver := &ContextCreatorDriver{MockDriver: &MockDriver{calls: &CallRecorder{}}}
+	ctx := context.Background()
+
+	assert.NoError(t, CreateWithContext(ctx, driver))
+	assert.Equal(t, ctx, driver.ctx)
+	assert.Empty(t, driver.calls.calls)
+	assert.True(t, Supports(driver, CapabilityCanceling))
+}
+
+func TestWithContext(t *testing.T) {
+	driver := &MockDriver{calls: &CallRecorder{}, sshHostname: "host"}
+	ctx, cancel := context.WithCancel(context.Background())
+	bound := WithContext(ctx, driver)
+
+	hostname, err := bound.GetSSHHostname()
+	assert.NoError(t, err)
+	assert.Equal(t, "host", hostname)
+	assert.Equal(t, ctx, ContextOf(bound))
+	assert.Equal(t, context.Background(), ContextOf(driver))
+
+	cancel()
+
+	_, err = bound.GetSSHHostname()
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, bound.Start())
+	assert.Equal(t, []string{"GetSSHHostname"}, driver.calls.calls)
+}
+
+func TestWithContextForwardsCapabilities(t *testing.T) {
+	ctx := context.Background()
+
+	snapshotter := &MockSnapshotter{MockDriver{calls: &CallRecorder{}, driverName: "mock"}}
+	_, err := GetSnapshotter(WithContext(ctx, snapshotter))
+	assert.NoError(t, err)
+
+	driver := &MockDriver{calls: &CallRecorder{}, driverName: "mock"}
+	capabilities, _ := Capabilities(driver)
+	bound := WithContext(ctx, driver)
+	boundCapabilities, _ := Capabilities(bound)
+	assert.Equal(t, capabilities, boundCapabilities)
+
+	_, err = GetSnapshotter(bound)
+	assert.Equal(t, FeatureNotSupported{DriverName: "mock", Feature: CapabilitySnapshots}, err)
+}
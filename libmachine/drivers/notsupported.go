@@ -20,6 +20,17 @@ func (e NotSupported) Error() string {
 	return fmt.Sprintf("Driver %q not supported on this platform.", e.DriverName)
 }
 
+// FeatureNotSupported is returned when an optional capability, e.g.
+// snapshots, is used on a driver which does not implement it.
+type FeatureNotSupported struct {
+	DriverName string
+	Feature    string
+}
+
+func (e FeatureNotSupported) Error() string {
+	return fmt.Sprintf("Driver %q does not support %s", e.DriverName, e.Feature)
+}
+
 // NewDriverNotSupported creates a placeholder Driver that replaces
 // a driver that is not supported on a given platform. eg fusion on linux.
 func NewDriverNotSupported(driverName, hostName, storePath string) Driver {
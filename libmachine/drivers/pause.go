@@ -0,0 +1,53 @@
+package drivers
+
+import "golang.org/x/net/context"
+
+// Pauser is an optional interface for drivers which are able to freeze a
+// machine in memory and to save its state to disk. A suspended machine is
+// resumed by Start. Like Snapshotter, it is always implemented by the RPC
+// client driver, so GetPauser tells whether a driver supports it.
+type Pauser interface {
+	// Pause freezes the machine, which ends up Paused.
+	Pause() error
+
+	// Unpause resumes a Paused machine.
+	Unpause() error
+
+	// Suspend saves the state of the machine and stops it, which ends up
+	// Saved.
+	Suspend() error
+}
+
+// GetPauser returns d as a Pauser, or a FeatureNotSupported error if the
+// driver can not pause machines.
+func GetPauser(d Driver) (Pauser, error) {
+	p, ok := d.(Pauser)
+	if !ok || !Supports(d, CapabilityPausing) {
+		return nil, FeatureNotSupported{DriverName: d.DriverName(), Feature: CapabilityPausing}
+	}
+	return p, nil
+}
+
+func PauseWithContext(ctx context.Context, d Driver) error {
+	p, err := GetPauser(d)
+	if err != nil {
+		return err
+	}
+	return callWithContext(ctx, d, p.Pause)
+}
+
+func UnpauseWithContext(ctx context.Context, d Driver) error {
+	p, err := GetPauser(d)
+	if err != nil {
+		return err
+	}
+	return callWithContext(ctx, d, p.Unpause)
+}
+
+func SuspendWithContext(ctx context.Context, d Driver) error {
+	p, err := GetPauser(d)
+	if err != nil {
+		return err
+	}
+	return callWithContext(ctx, d, p.Suspend)
+}
@@ -0,0 +1,288 @@
+// Package installer installs driver plugin binaries in the plugin directory
+// of the machine store, once their checksum or signature is verified.
+package installer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	ErrUnverified   = errors.New("Refusing to install a driver whose checksum or signature is not given")
+	ErrNoPluginDir  = errors.New("No plugin directory is set")
+	validDriverName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+)
+
+// ErrNotInstalled is returned when a driver is not installed in the plugin
+// directory.
+type ErrNotInstalled struct {
+	Name string
+}
+
+func (e ErrNotInstalled) Error() string {
+	return fmt.Sprintf("Driver %q is not installed", e.Name)
+}
+
+// ErrAlreadyInstalled is returned when a driver to install already is.
+type ErrAlreadyInstalled struct {
+	Name string
+}
+
+func (e ErrAlreadyInstalled) Error() string {
+	return fmt.Sprintf("Driver %q is already installed, use upgrade to replace it", e.Name)
+}
+
+// Verifier checks that a binary is the expected one, with its SHA-256
+// checksum or with a detached signature of the binary made with the private
+// key matching PublicKey, e.g. with `openssl dgst -sha256 -sign key.pem`.
+type Verifier struct {
+	// SHA256 is the hex encoded checksum of the binary.
+	SHA256 string
+
+	// Signature is the detached signature of the binary, and PublicKey the
+	// PEM encoded RSA or ECDSA key it is checked with.
+	Signature []byte
+	PublicKey []byte
+}
+
+// Verify returns an error unless the binary matches every check of the
+// verifier. A verifier without any check fails.
+func (v *Verifier) Verify(data []byte) error {
+	if v.SHA256 == "" && len(v.Signature) == 0 {
+		return ErrUnverified
+	}
+
+	digest := sha256.Sum256(data)
+
+	if v.SHA256 != "" {
+		if expected := strings.ToLower(strings.TrimSpace(v.SHA256)); hex.EncodeToString(digest[:]) != expected {
+			return fmt.Errorf("Checksum mismatch: expected %s, got %x", expected, digest)
+		}
+	}
+
+	if len(v.Signature) > 0 {
+		if err := verifySignature(digest[:], v.Signature, v.PublicKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func verifySignature(digest, signature, publicKey []byte) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return errors.New("Invalid public key: no PEM data found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("Invalid public key: %s", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature); err != nil {
+			return errors.New("Invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		var sig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(signature, &sig); err != nil || !ecdsa.Verify(key, digest, sig.R, sig.S) {
+			return errors.New("Invalid signature")
+		}
+	default:
+		return fmt.Errorf("Unsupported public key type %T", key)
+	}
+
+	return nil
+}
+
+// Fetch reads a local file or downloads an HTTP(S) URL.
+func Fetch(source string) ([]byte, error) {
+	if !IsURL(source) {
+		return ioutil.ReadFile(source)
+	}
+
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error downloading %s: %s", source, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// IsURL returns true if Fetch downloads source rather than reading a file.
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// DriverName returns the name of the driver served by a plugin binary, from
+// its file name or URL, e.g. foo for docker-machine-driver-foo.
+func DriverName(source string) (string, error) {
+	base := source[strings.LastIndexAny(source, `/\`)+1:]
+	if i := strings.IndexAny(base, "?#"); i >= 0 {
+		base = base[:i]
+	}
+
+	name := strings.TrimSuffix(base, ".exe")
+	if !strings.HasPrefix(name, "docker-machine-driver-") {
+		return "", fmt.Errorf("Unable to guess the driver name from %q, the binary is not named docker-machine-driver-NAME", source)
+	}
+
+	name = strings.TrimPrefix(name, "docker-machine-driver-")
+	if err := ValidateDriverName(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// ValidateDriverName checks that a plugin can be installed with the name.
+func ValidateDriverName(name string) error {
+	if !validDriverName.MatchString(name) {
+		return fmt.Errorf("Invalid driver name %q", name)
+	}
+
+	if localbinary.IsCoreDriver(name) {
+		return fmt.Errorf("%q is a core driver, it can not be replaced by a plugin", name)
+	}
+
+	return nil
+}
+
+// Install writes the plugin binary of a driver in the plugin directory and
+// returns its path. The binary of an installed driver is only replaced if
+// upgrade is true, and the driver must then be installed. Once in place, the
+// binary is checked with check, if not nil. If the check fails, the binary
+// is removed and the previous one is restored.
+func Install(name string, data []byte, upgrade bool, check func(path string) error) (string, error) {
+	if err := ValidateDriverName(name); err != nil {
+		return "", err
+	}
+
+	path := localbinary.ManagedBinaryPath(name)
+	if path == "" {
+		return "", ErrNoPluginDir
+	}
+
+	_, err := os.Stat(path)
+	installed := err == nil
+	switch {
+	case installed && !upgrade:
+		return "", ErrAlreadyInstalled{name}
+	case !installed && upgrade:
+		return "", ErrNotInstalled{name}
+	}
+
+	if err := os.MkdirAll(localbinary.PluginDir, 0700); err != nil {
+		return "", err
+	}
+
+	// Write to a temp file first then rename it, so that a running plugin
+	// is never replaced by a partial binary.
+	f, err := ioutil.TempFile(localbinary.PluginDir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return "", err
+	}
+
+	// Keep the previous binary until the new one passed the check. Moving
+	// it away also lets Windows, which can not rename over an existing
+	// file, replace it.
+	previous := path + ".previous"
+	if installed {
+		if err := os.Rename(path, previous); err != nil {
+			return "", err
+		}
+	}
+
+	restore := func() {
+		if !installed {
+			return
+		}
+		if err := os.Rename(previous, path); err != nil {
+			log.Warnf("Error restoring the previous binary of driver %q from %s: %s", name, previous, err)
+		}
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		restore()
+		return "", err
+	}
+
+	if check != nil {
+		if err := check(path); err != nil {
+			if removeErr := os.Remove(path); removeErr != nil {
+				log.Warnf("Error removing %s: %s", path, removeErr)
+			}
+			restore()
+			return "", err
+		}
+	}
+
+	if installed {
+		if err := os.Remove(previous); err != nil {
+			log.Warnf("Error removing the previous binary of driver %q: %s", name, err)
+		}
+	}
+
+	return path, nil
+}
+
+// Uninstall removes the plugin binary of a driver from the plugin directory.
+func Uninstall(name string) error {
+	if !validDriverName.MatchString(name) {
+		return fmt.Errorf("Invalid driver name %q", name)
+	}
+
+	path := localbinary.ManagedBinaryPath(name)
+	if path == "" {
+		return ErrNoPluginDir
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotInstalled{name}
+		}
+		return err
+	}
+
+	return nil
+}
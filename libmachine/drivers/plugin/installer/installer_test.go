@@ -0,0 +1,172 @@
+package installer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/stretchr/testify/assert"
+)
+
+var binary = []byte("#!/bin/sh\necho foo\n")
+
+func publicKeyPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyRequiresACheck(t *testing.T) {
+	err := (&Verifier{}).Verify(binary)
+
+	assert.Equal(t, ErrUnverified, err)
+}
+
+func TestVerifySHA256(t *testing.T) {
+	digest := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(digest[:])
+	wrong := strings.Repeat("0", 64)
+
+	assert.NoError(t, (&Verifier{SHA256: checksum}).Verify(binary))
+	assert.NoError(t, (&Verifier{SHA256: strings.ToUpper(checksum)}).Verify(binary))
+	assert.EqualError(t, (&Verifier{SHA256: wrong}).Verify(binary), "Checksum mismatch: expected "+wrong+", got "+checksum)
+}
+
+func TestVerifyRSASignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256(binary)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &Verifier{Signature: signature, PublicKey: publicKeyPEM(t, &key.PublicKey)}
+
+	assert.NoError(t, verifier.Verify(binary))
+	assert.EqualError(t, verifier.Verify([]byte("tampered")), "Invalid signature")
+}
+
+func TestVerifyECDSASignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256(binary)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &Verifier{Signature: signature, PublicKey: publicKeyPEM(t, &key.PublicKey)}
+
+	assert.NoError(t, verifier.Verify(binary))
+	assert.EqualError(t, verifier.Verify([]byte("tampered")), "Invalid signature")
+}
+
+func TestVerifyInvalidPublicKey(t *testing.T) {
+	err := (&Verifier{Signature: []byte("sig"), PublicKey: []byte("not a key")}).Verify(binary)
+
+	assert.EqualError(t, err, "Invalid public key: no PEM data found")
+}
+
+func TestDriverName(t *testing.T) {
+	name, err := DriverName("https://example.com/releases/docker-machine-driver-foo?raw=true")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", name)
+
+	name, err = DriverName(`C:\Downloads\docker-machine-driver-bar.exe`)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", name)
+
+	_, err = DriverName("/tmp/foo")
+	assert.Error(t, err)
+
+	_, err = DriverName("/tmp/docker-machine-driver-virtualbox")
+	assert.EqualError(t, err, `"virtualbox" is a core driver, it can not be replaced by a plugin`)
+}
+
+func TestInstallUpgradeUninstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(previous string) { localbinary.PluginDir = previous }(localbinary.PluginDir)
+	localbinary.PluginDir = dir
+
+	_, err = Install("foo", binary, true, nil)
+	assert.Equal(t, ErrNotInstalled{"foo"}, err)
+
+	path, err := Install("foo", binary, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, localbinary.ManagedBinaryPath("foo"), path)
+
+	_, err = Install("foo", binary, false, nil)
+	assert.Equal(t, ErrAlreadyInstalled{"foo"}, err)
+
+	_, err = Install("foo", []byte("v2"), true, nil)
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "v2", string(data))
+
+	assert.NoError(t, Uninstall("foo"))
+	assert.Equal(t, ErrNotInstalled{"foo"}, Uninstall("foo"))
+	assert.Error(t, Uninstall("../foo"))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestInstallFailedCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(previous string) { localbinary.PluginDir = previous }(localbinary.PluginDir)
+	localbinary.PluginDir = dir
+
+	failing := func(path string) error { return errors.New("can not be used") }
+
+	_, err = Install("foo", binary, false, failing)
+	assert.EqualError(t, err, "can not be used")
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+
+	path, err := Install("foo", binary, false, func(path string) error { return nil })
+	assert.NoError(t, err)
+
+	_, err = Install("foo", []byte("v2"), true, failing)
+	assert.EqualError(t, err, "can not be used")
+
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, string(binary), string(data))
+	files, _ = ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+}
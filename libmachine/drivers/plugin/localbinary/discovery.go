@@ -0,0 +1,140 @@
+package localbinary
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Binary is a driver found on the system.
+type Binary struct {
+	Name string
+
+	// Path is where the binary serving the driver is, empty if it could not
+	// be found.
+	Path string
+
+	// Core is true for the drivers built in docker-machine, which are
+	// served by the docker-machine binary itself.
+	Core bool
+
+	// Managed is true for the plugins installed in PluginDir.
+	Managed bool
+}
+
+// ManagedBinaryPath returns where the plugin binary of a driver is installed
+// in PluginDir, empty if there is no PluginDir.
+func ManagedBinaryPath(driverName string) string {
+	if PluginDir == "" {
+		return ""
+	}
+
+	name := pluginBinaryPrefix + driverName
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	return filepath.Join(PluginDir, name)
+}
+
+// FindDriver returns the path of the binary serving a driver.
+func FindDriver(driverName string) (string, error) {
+	binaryPath, err := exec.LookPath(driverPath(driverName))
+	if err != nil {
+		return "", ErrPluginBinaryNotFound{driverName}
+	}
+
+	// os.Args[0] may be relative to the working directory.
+	if absPath, err := filepath.Abs(binaryPath); err == nil {
+		binaryPath = absPath
+	}
+
+	return binaryPath, nil
+}
+
+// FindDrivers returns the core drivers and the plugin drivers found in
+// PluginDir and in the PATH, sorted by name. As with FindDriver, a plugin
+// binary shadowed by one earlier in the search, or by a core driver, is
+// ignored.
+func FindDrivers() []Binary {
+	found := map[string]Binary{}
+
+	for _, name := range CoreDrivers {
+		path, _ := FindDriver(name)
+		found[name] = Binary{Name: name, Path: path, Core: true}
+	}
+
+	dirs := filepath.SplitList(os.Getenv("PATH"))
+	if PluginDir != "" {
+		dirs = append([]string{PluginDir}, dirs...)
+	}
+
+	for i, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			name, ok := pluginDriverName(file)
+			if !ok {
+				continue
+			}
+			if _, exists := found[name]; exists {
+				continue
+			}
+
+			found[name] = Binary{
+				Name:    name,
+				Path:    filepath.Join(dir, file.Name()),
+				Managed: PluginDir != "" && i == 0,
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	binaries := []Binary{}
+	for _, name := range names {
+		binaries = append(binaries, found[name])
+	}
+
+	return binaries
+}
+
+// pluginDriverName returns the name of the driver served by a plugin binary,
+// and false if the file is not one.
+func pluginDriverName(file os.FileInfo) (string, bool) {
+	name := file.Name()
+	if file.IsDir() || !strings.HasPrefix(name, pluginBinaryPrefix) {
+		return "", false
+	}
+
+	if runtime.GOOS == "windows" {
+		if !strings.HasSuffix(strings.ToLower(name), ".exe") {
+			return "", false
+		}
+		name = name[:len(name)-len(".exe")]
+	} else if file.Mode()&0111 == 0 {
+		return "", false
+	}
+
+	name = strings.TrimPrefix(name, pluginBinaryPrefix)
+	if name == "" {
+		return "", false
+	}
+
+	return name, true
+}
@@ -0,0 +1,66 @@
+// +build !windows
+
+package localbinary
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBinary(t *testing.T, dir, name string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindDrivers(t *testing.T) {
+	first, err := ioutil.TempDir("", "machine-drivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(first)
+
+	second, err := ioutil.TempDir("", "machine-drivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(second)
+
+	machine := writeBinary(t, first, "docker-machine", 0755)
+	foo := writeBinary(t, first, "docker-machine-driver-foo", 0755)
+	writeBinary(t, first, "docker-machine-driver-notexecutable", 0644)
+	writeBinary(t, second, "docker-machine-driver-foo", 0755)
+	writeBinary(t, second, "docker-machine-driver-virtualbox", 0755)
+	bar := writeBinary(t, second, "docker-machine-driver-bar", 0755)
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", first+string(filepath.ListSeparator)+second)
+
+	binaries := FindDrivers()
+
+	byName := map[string]Binary{}
+	for _, binary := range binaries {
+		byName[binary.Name] = binary
+	}
+
+	assert.Len(t, binaries, len(CoreDrivers)+2)
+	assert.Equal(t, "amazonec2", binaries[0].Name)
+	assert.Equal(t, Binary{Name: "virtualbox", Path: machine, Core: true}, byName["virtualbox"])
+	assert.Equal(t, Binary{Name: "foo", Path: foo}, byName["foo"])
+	assert.Equal(t, Binary{Name: "bar", Path: bar}, byName["bar"])
+}
+
+func TestFindDriverNotFound(t *testing.T) {
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+
+	_, err := FindDriver("foo")
+
+	assert.Equal(t, ErrPluginBinaryNotFound{"foo"}, err)
+}
@@ -21,6 +21,10 @@ var (
 		"exoscale", "generic", "google", "hyperv", "none", "openstack",
 		"rackspace", "softlayer", "virtualbox", "vmwarefusion",
 		"vmwarevcloudair", "vmwarevsphere"}
+
+	// PluginDir is the directory of the plugin binaries installed with
+	// `docker-machine driver install`, searched before the PATH.
+	PluginDir = ""
 )
 
 const (
@@ -29,6 +33,7 @@ const (
 	PluginEnvKey        = "MACHINE_PLUGIN_TOKEN"
 	PluginEnvVal        = "42"
 	PluginEnvDriverName = "MACHINE_PLUGIN_DRIVER_NAME"
+	pluginBinaryPrefix  = "docker-machine-driver-"
 )
 
 type PluginStreamer interface {
@@ -94,27 +99,41 @@ func (e ErrPluginBinaryNotFound) Error() string {
 // driverPath finds the path of a driver binary by its name.
 //  + If the driver is a core driver, there is no separate driver binary. We reuse current binary if it's `docker-machine`
 // or we assume `docker-machine` is in the PATH.
-//  + If the driver is NOT a core driver, then the separate binary must be in the PATH and it's name must be
-// `docker-machine-driver-driverName`
+//  + If the driver is NOT a core driver, then the separate binary must be in PluginDir or in the PATH and it's
+// name must be `docker-machine-driver-driverName`
 func driverPath(driverName string) string {
+	if IsCoreDriver(driverName) {
+		if CurrentBinaryIsDockerMachine {
+			return os.Args[0]
+		}
+
+		return "docker-machine"
+	}
+
+	if managedPath := ManagedBinaryPath(driverName); managedPath != "" {
+		if _, err := os.Stat(managedPath); err == nil {
+			return managedPath
+		}
+	}
+
+	return pluginBinaryPrefix + driverName
+}
+
+// IsCoreDriver returns true if the driver is built in docker-machine.
+func IsCoreDriver(driverName string) bool {
 	for _, coreDriver := range CoreDrivers {
 		if coreDriver == driverName {
-			if CurrentBinaryIsDockerMachine {
-				return os.Args[0]
-			}
-
-			return "docker-machine"
+			return true
 		}
 	}
 
-	return fmt.Sprintf("docker-machine-driver-%s", driverName)
+	return false
 }
 
 func NewPlugin(driverName string) (*Plugin, error) {
-	driverPath := driverPath(driverName)
-	binaryPath, err := exec.LookPath(driverPath)
+	binaryPath, err := FindDriver(driverName)
 	if err != nil {
-		return nil, ErrPluginBinaryNotFound{driverName}
+		return nil, err
 	}
 
 	log.Debugf("Found binary path at %s", binaryPath)
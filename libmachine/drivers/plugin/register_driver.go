@@ -19,7 +19,20 @@ var (
 	heartbeatTimeout = 10 * time.Second
 )
 
+// RegisterDriver serves d for a single machine. The client starts another
+// plugin binary for each machine.
 func RegisterDriver(d drivers.Driver) {
+	serve(d, nil)
+}
+
+// RegisterDriverFactory serves the drivers returned by newDriver, one per
+// machine, so that the client needs a single plugin binary for every machine
+// of the driver.
+func RegisterDriverFactory(newDriver func() drivers.Driver) {
+	serve(newDriver(), newDriver)
+}
+
+func serve(d drivers.Driver, newDriver func() drivers.Driver) {
 	if os.Getenv(localbinary.PluginEnvKey) != localbinary.PluginEnvVal {
 		fmt.Fprintf(os.Stderr, `This is a Docker Machine plugin binary.
 Plugin binaries are not intended to be invoked directly.
@@ -29,12 +42,18 @@ Please use this plugin through the main 'docker-machine' binary.
 		os.Exit(1)
 	}
 
+	logger := log.NewRecordMachineLogger(log.NewFmtMachineLogger())
+	log.SetMachineLogger(logger)
 	log.SetDebug(true)
 	os.Setenv("MACHINE_DEBUG", "1")
 
 	rpcd := rpcdriver.NewRPCServerDriver(d)
+	rpcd.Logger = logger
 	rpc.RegisterName(rpcdriver.RPCServiceNameV0, rpcd)
 	rpc.RegisterName(rpcdriver.RPCServiceNameV1, rpcd)
+	if newDriver != nil {
+		rpc.RegisterName(rpcdriver.RPCServicePoolName, rpcdriver.NewRPCServerDriverPool(rpcd, newDriver, rpc.RegisterName))
+	}
 	rpc.HandleHTTP()
 
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
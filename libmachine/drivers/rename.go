@@ -0,0 +1,62 @@
+package drivers
+
+// Renamer is an optional interface for drivers which are able to rename
+// their machine. Rename is only called on stopped machines, once the store
+// moved the directory of the machine to the one of name. It renames the
+// machine at the provider and updates the configuration of the driver, e.g.
+// with BaseDriver.SetMachineName, which must be saved afterwards. If it
+// fails, it leaves the machine and the driver as they were, and the store
+// moves the directory back. Callers get it with GetRenamer, see
+// CapabilityReporter.
+type Renamer interface {
+	Rename(name string) error
+}
+
+// StoreDirUser is an optional interface for the Renamers whose machine uses
+// files of its store directory at the provider, e.g. the disks of a VM.
+// ReleaseStoreDir stops using them before the directory is moved, and fails
+// if the machine can not be renamed. UseStoreDir uses them again from the
+// directory of the machine if it could not be renamed, while Rename uses
+// them from the new directory.
+type StoreDirUser interface {
+	ReleaseStoreDir() error
+	UseStoreDir() error
+}
+
+// GetRenamer returns d as a Renamer, or a FeatureNotSupported error if the
+// driver can not rename machines.
+func GetRenamer(d Driver) (Renamer, error) {
+	r, ok := d.(Renamer)
+	if !ok || !Supports(d, CapabilityRenaming) {
+		return nil, FeatureNotSupported{DriverName: d.DriverName(), Feature: CapabilityRenaming}
+	}
+	return r, nil
+}
+
+// ReleaseStoreDir makes d stop using the files of the store directory of its
+// machine, if it uses any.
+func ReleaseStoreDir(d Driver) error {
+	if u, ok := d.(StoreDirUser); ok {
+		return ignoreNotSupported(u.ReleaseStoreDir())
+	}
+	return nil
+}
+
+// UseStoreDir makes d use the files of the store directory of its machine
+// again, if it uses any.
+func UseStoreDir(d Driver) error {
+	if u, ok := d.(StoreDirUser); ok {
+		return ignoreNotSupported(u.UseStoreDir())
+	}
+	return nil
+}
+
+// ignoreNotSupported ignores the error of the drivers which wrap another one,
+// such as the RPC client driver, when the wrapped driver does not use the
+// store directory.
+func ignoreNotSupported(err error) error {
+	if _, ok := err.(FeatureNotSupported); ok {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package drivers
+
+import "golang.org/x/net/context"
+
+// ResizeOptions are the new resources of a machine. Zero values are left
+// unchanged.
+type ResizeOptions struct {
+	CPU int
+
+	// Memory is in MB.
+	Memory int
+
+	// DiskSize is in MB. Disks can only grow.
+	DiskSize int
+
+	// InstanceType is the instance or machine type of the cloud drivers.
+	InstanceType string
+}
+
+// IsZero returns true if no resource is changed.
+func (o ResizeOptions) IsZero() bool {
+	return o == ResizeOptions{}
+}
+
+// Resizer is an optional interface for drivers which are able to change the
+// resources of an existing machine. Resize is only called on stopped
+// machines and updates the configuration of the driver, which must be saved
+// afterwards. Drivers return an error for the options which make no sense
+// to them, e.g. an instance type for a local VM. Callers get it with
+// GetResizer, see CapabilityReporter.
+type Resizer interface {
+	Resize(opts ResizeOptions) error
+}
+
+// GetResizer returns d as a Resizer, or a FeatureNotSupported error if the
+// driver can not resize machines.
+func GetResizer(d Driver) (Resizer, error) {
+	r, ok := d.(Resizer)
+	if !ok || !Supports(d, CapabilityResizing) {
+		return nil, FeatureNotSupported{DriverName: d.DriverName(), Feature: CapabilityResizing}
+	}
+	return r, nil
+}
+
+func ResizeWithContext(ctx context.Context, d Driver, opts ResizeOptions) error {
+	r, err := GetResizer(d)
+	if err != nil {
+		return err
+	}
+	return callWithContext(ctx, d, func() error {
+		return r.Resize(opts)
+	})
+}
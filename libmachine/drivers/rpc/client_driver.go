@@ -3,17 +3,17 @@ package rpcdriver
 import (
 	"fmt"
 	"net/rpc"
+	"strings"
 	"sync"
 	"time"
 
 	"io"
 
 	"github.com/docker/machine/libmachine/drivers"
-	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
-	"github.com/docker/machine/libmachine/version"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -25,22 +25,46 @@ type RPCClientDriverFactory interface {
 	io.Closer
 }
 
+// DefaultRPCClientDriverFactory starts the plugin binaries of the drivers.
+// The binaries which support it serve every machine of their driver.
 type DefaultRPCClientDriverFactory struct {
-	openedDrivers     []*RPCClientDriver
-	openedDriversLock sync.Locker
+	openedPlugins     []*pluginProcess
+	openedPluginsLock sync.Locker
+
+	// sharedPlugins are the plugin binaries serving several machines, by
+	// driver name, nil for the drivers whose binaries can not.
+	sharedPlugins map[string]*pluginProcess
 }
 
 func NewRPCClientDriverFactory() RPCClientDriverFactory {
 	return &DefaultRPCClientDriverFactory{
-		openedDrivers:     []*RPCClientDriver{},
-		openedDriversLock: &sync.Mutex{},
+		openedPlugins:     []*pluginProcess{},
+		openedPluginsLock: &sync.Mutex{},
+		sharedPlugins:     map[string]*pluginProcess{},
 	}
 }
 
 type RPCClientDriver struct {
-	plugin          localbinary.DriverPlugin
-	heartbeatDoneCh chan bool
-	Client          *InternalClient
+	apiVersion int
+	Client     *InternalClient
+
+	// capabilities are the optional features of the actual driver, unknown
+	// for plugins built before they were reported.
+	capabilities      []string
+	capabilitiesKnown bool
+
+	// release tells the factory the driver is no longer used.
+	release func()
+}
+
+// ErrIncompatibleAPIVersion is returned when a plugin binary speaks another
+// version of the RPC protocol.
+type ErrIncompatibleAPIVersion struct {
+	Version int
+}
+
+func (e ErrIncompatibleAPIVersion) Error() string {
+	return fmt.Sprintf("Driver binary uses an incompatible API version (%d)", e.Version)
 }
 
 type RPCCall struct {
@@ -61,6 +85,9 @@ const (
 
 	HeartbeatMethod          = `.Heartbeat`
 	GetVersionMethod         = `.GetVersion`
+	GetMachineVersionMethod  = `.GetMachineVersion`
+	GetCapabilitiesMethod    = `.GetCapabilities`
+	GetLogsMethod            = `.GetLogs`
 	CloseMethod              = `.Close`
 	GetCreateFlagsMethod     = `.GetCreateFlags`
 	SetConfigRawMethod       = `.SetConfigRaw`
@@ -77,21 +104,41 @@ const (
 	GetStateMethod           = `.GetState`
 	PreCreateCheckMethod     = `.PreCreateCheck`
 	CreateMethod             = `.Create`
+	CreateContextMethod      = `.CreateContext`
 	RemoveMethod             = `.Remove`
 	StartMethod              = `.Start`
 	StopMethod               = `.Stop`
 	RestartMethod            = `.Restart`
 	KillMethod               = `.Kill`
 	UpgradeMethod            = `.Upgrade`
+	CancelMethod             = `.Cancel`
+	CreateSnapshotMethod     = `.CreateSnapshot`
+	ListSnapshotsMethod      = `.ListSnapshots`
+	RestoreSnapshotMethod    = `.RestoreSnapshot`
+	RemoveSnapshotMethod     = `.RemoveSnapshot`
+	PauseMethod              = `.Pause`
+	UnpauseMethod            = `.Unpause`
+	SuspendMethod            = `.Suspend`
+	ResizeMethod             = `.Resize`
+	CloneMethod              = `.Clone`
+	RenameMethod             = `.Rename`
+	ReleaseStoreDirMethod    = `.ReleaseStoreDir`
+	UseStoreDirMethod        = `.UseStoreDir`
 )
 
 func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
-	if serviceMethod != HeartbeatMethod {
+	if serviceMethod != HeartbeatMethod && serviceMethod != GetLogsMethod {
 		log.Debugf("(%s) Calling %+v", ic.MachineName, serviceMethod)
 	}
 	return ic.RPCClient.Call(ic.rpcServiceName+serviceMethod, args, reply)
 }
 
+// Go calls serviceMethod asynchronously, like rpc.Client.Go.
+func (ic *InternalClient) Go(serviceMethod string, args interface{}, reply interface{}) *rpc.Call {
+	log.Debugf("(%s) Calling %+v", ic.MachineName, serviceMethod)
+	return ic.RPCClient.Go(ic.rpcServiceName+serviceMethod, args, reply, make(chan *rpc.Call, 1))
+}
+
 func (ic *InternalClient) switchToV0() {
 	ic.rpcServiceName = RPCServiceNameV0
 }
@@ -104,94 +151,100 @@ func NewInternalClient(rpcclient *rpc.Client) *InternalClient {
 }
 
 func (f *DefaultRPCClientDriverFactory) Close() error {
-	f.openedDriversLock.Lock()
-	defer f.openedDriversLock.Unlock()
+	f.openedPluginsLock.Lock()
+	defer f.openedPluginsLock.Unlock()
 
-	for _, openedDriver := range f.openedDrivers {
-		if err := openedDriver.close(); err != nil {
+	for _, openedPlugin := range f.openedPlugins {
+		if err := openedPlugin.close(); err != nil {
 			log.Warnf("Error closing a plugin driver: %s", err)
 		}
 	}
-	f.openedDrivers = []*RPCClientDriver{}
+	f.openedPlugins = []*pluginProcess{}
+	f.sharedPlugins = map[string]*pluginProcess{}
 
 	return nil
 }
 
 func (f *DefaultRPCClientDriverFactory) NewRPCClientDriver(driverName string, rawDriver []byte) (*RPCClientDriver, error) {
-	mcnName := ""
+	f.openedPluginsLock.Lock()
+	defer f.openedPluginsLock.Unlock()
+
+	if p := f.sharedPlugins[driverName]; p != nil {
+		serviceName, err := p.newService(rawDriver)
+		if err == nil {
+			// The pool configured the driver with rawDriver, or shares the
+			// one of the machine if another client uses it, which must not
+			// be reset.
+			c, err := p.newDriver(serviceName, nil)
+			if err != nil {
+				p.releaseNewService(serviceName)
+				return nil, err
+			}
+			c.release = func() { f.release(p, c) }
+			return c, nil
+		}
 
-	p, err := localbinary.NewPlugin(driverName)
-	if err != nil {
-		return nil, err
+		log.Debugf("The plugin of driver %q can not serve several machines, starting another one: %s", driverName, err)
+		f.sharedPlugins[driverName] = nil
 	}
 
-	go func() {
-		if err := p.Serve(); err != nil {
-			// TODO: Is this best approach?
-			log.Warn(err)
-			return
-		}
-	}()
-
-	addr, err := p.Address()
+	p, err := startPlugin(driverName)
+	if p != nil {
+		f.openedPlugins = append(f.openedPlugins, p)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("Error attempting to get plugin server address for RPC: %s", err)
+		return nil, err
 	}
 
-	rpcclient, err := rpc.DialHTTP("tcp", addr)
+	c, err := p.newDriver(p.client.rpcServiceName, rawDriver)
 	if err != nil {
 		return nil, err
 	}
+	c.release = func() { f.release(p, c) }
 
-	c := &RPCClientDriver{
-		Client:          NewInternalClient(rpcclient),
-		heartbeatDoneCh: make(chan bool),
-	}
+	p.forwardLogs()
 
-	f.openedDriversLock.Lock()
-	f.openedDrivers = append(f.openedDrivers, c)
-	f.openedDriversLock.Unlock()
-
-	var serverVersion int
-	if err := c.Client.Call(GetVersionMethod, struct{}{}, &serverVersion); err != nil {
-		// this is the first call we make to the server. We try to play nice with old pre 0.5.1 client,
-		// by gracefully trying old RPCServiceName, we do this only once, and keep the result for future calls.
-		log.Debugf(err.Error())
-		log.Debugf("Client (%s) with %s does not work, re-attempting with %s", c.Client.MachineName, RPCServiceNameV1, RPCServiceNameV0)
-		c.Client.switchToV0()
-		if err := c.Client.Call(GetVersionMethod, struct{}{}, &serverVersion); err != nil {
-			return nil, err
-		}
+	if _, known := f.sharedPlugins[driverName]; !known {
+		f.sharedPlugins[driverName] = p
 	}
 
-	if serverVersion != version.APIVersion {
-		return nil, fmt.Errorf("Driver binary uses an incompatible API version (%d)", serverVersion)
+	return c, nil
+}
+
+// release forgets the driver c served by the plugin binary p, and closes the
+// binary once it serves no other driver.
+func (f *DefaultRPCClientDriverFactory) release(p *pluginProcess, c *RPCClientDriver) {
+	f.openedPluginsLock.Lock()
+	defer f.openedPluginsLock.Unlock()
+
+	if p.removeDriver(c) > 0 {
+		p.releaseService(c.Client.MachineName)
+		return
 	}
-	log.Debug("Using API Version ", serverVersion)
-
-	go func(c *RPCClientDriver) {
-		for {
-			select {
-			case <-c.heartbeatDoneCh:
-				return
-			case <-time.After(heartbeatInterval):
-				if err := c.Client.Call(HeartbeatMethod, struct{}{}, nil); err != nil {
-					log.Warnf("Error attempting heartbeat call to plugin server: %s", err)
-				}
+
+	for i, openedPlugin := range f.openedPlugins {
+		if openedPlugin == p {
+			f.openedPlugins = append(f.openedPlugins[:i], f.openedPlugins[i+1:]...)
+			if err := p.close(); err != nil {
+				log.Warnf("Error closing a plugin driver: %s", err)
 			}
+			break
 		}
-	}(c)
-
-	if err := c.SetConfigRaw(rawDriver); err != nil {
-		return nil, err
 	}
 
-	mcnName = c.GetMachineName()
-	p.MachineName = mcnName
-	c.Client.MachineName = mcnName
-	c.plugin = p
+	if f.sharedPlugins[p.driverName] == p {
+		delete(f.sharedPlugins, p.driverName)
+	}
+}
 
-	return c, nil
+// Close releases the driver, closing the plugin binary serving it unless it
+// serves other drivers. The driver can not be used afterwards.
+func (c *RPCClientDriver) Close() error {
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+	return nil
 }
 
 func (c *RPCClientDriver) MarshalJSON() ([]byte, error) {
@@ -202,37 +255,52 @@ func (c *RPCClientDriver) UnmarshalJSON(data []byte) error {
 	return c.SetConfigRaw(data)
 }
 
-func (c *RPCClientDriver) close() error {
-	c.heartbeatDoneCh <- true
-	close(c.heartbeatDoneCh)
-
-	log.Debug("Making call to close driver server")
+// Helper method to make requests which take no arguments and return simply a
+// string, e.g. "GetIP".
+func (c *RPCClientDriver) rpcStringCall(method string) (string, error) {
+	var info string
 
-	if err := c.Client.Call(CloseMethod, struct{}{}, nil); err != nil {
-		return err
+	if err := c.Client.Call(method, struct{}{}, &info); err != nil {
+		return "", err
 	}
 
-	log.Debug("Successfully made call to close driver server")
-
-	log.Debug("Making call to close connection to plugin binary")
+	return info, nil
+}
 
-	if err := c.plugin.Close(); err != nil {
-		return err
+// negotiateCapabilities asks the plugin for the optional features of its
+// driver. Older plugins can not tell, and every feature is then tried.
+func (c *RPCClientDriver) negotiateCapabilities() {
+	var capabilities []string
+	if err := c.Client.Call(GetCapabilitiesMethod, struct{}{}, &capabilities); err != nil {
+		log.Debugf("Plugin does not report its capabilities, assuming every optional feature is supported: %s", err)
+		return
 	}
 
-	return nil
+	log.Debugf("Driver capabilities: %v", capabilities)
+	c.capabilities = capabilities
+	c.capabilitiesKnown = true
 }
 
-// Helper method to make requests which take no arguments and return simply a
-// string, e.g. "GetIP".
-func (c *RPCClientDriver) rpcStringCall(method string) (string, error) {
-	var info string
+// Capabilities returns the optional features of the driver served by the
+// plugin, and false if the plugin can not tell.
+func (c *RPCClientDriver) Capabilities() ([]string, bool) {
+	return c.capabilities, c.capabilitiesKnown
+}
 
-	if err := c.Client.Call(method, struct{}{}, &info); err != nil {
+// APIVersion returns the version of the RPC protocol spoken by the plugin.
+func (c *RPCClientDriver) APIVersion() int {
+	return c.apiVersion
+}
+
+// MachineVersion returns the version of Docker Machine the plugin was built
+// with. Plugins built before the method existed return FeatureNotSupported.
+func (c *RPCClientDriver) MachineVersion() (string, error) {
+	var machineVersion string
+	if err := c.featureCall("version", GetMachineVersionMethod, struct{}{}, &machineVersion); err != nil {
 		return "", err
 	}
 
-	return info, nil
+	return machineVersion, nil
 }
 
 func (c *RPCClientDriver) GetCreateFlags() []mcnflag.Flag {
@@ -342,6 +410,39 @@ func (c *RPCClientDriver) Create() error {
 	return c.Client.Call(CreateMethod, struct{}{}, nil)
 }
 
+// CreateContext creates the machine until ctx is done, in which case the
+// plugin server is asked to cancel the call and ctx.Err() is returned once it
+// did. The deadline of ctx is sent along, so that the server gives up by
+// itself should the client be gone. Plugins built before the method existed
+// are asked to cancel their Create.
+func (c *RPCClientDriver) CreateContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	args := ContextArgs{}
+	if deadline, ok := ctx.Deadline(); ok {
+		args.Deadline = deadline
+	}
+
+	call := c.Client.Go(CreateContextMethod, args, nil)
+
+	select {
+	case <-call.Done:
+		if unknownMethod(call.Error) {
+			return drivers.CreateAndCancel(ctx, c)
+		}
+		return call.Error
+	case <-ctx.Done():
+		log.Info("Waiting for the driver to abort machine creation...")
+		if err := c.Cancel(); err != nil {
+			log.Debugf("Error cancelling driver operation: %s", err)
+		}
+		<-call.Done
+		return ctx.Err()
+	}
+}
+
 func (c *RPCClientDriver) Remove() error {
 	return c.Client.Call(RemoveMethod, struct{}{}, nil)
 }
@@ -365,3 +466,92 @@ func (c *RPCClientDriver) Kill() error {
 func (c *RPCClientDriver) Upgrade() error {
 	return c.Client.Call(UpgradeMethod, struct{}{}, nil)
 }
+
+// Cancel asks the plugin server to abort the operation currently in flight.
+// Plugins built against an older libmachine do not know this method, in which
+// case the operation simply runs to completion on the server side.
+func (c *RPCClientDriver) Cancel() error {
+	return c.Client.Call(CancelMethod, struct{}{}, nil)
+}
+
+// unknownMethod returns true if err is the error of a plugin built before the
+// method called existed.
+func unknownMethod(err error) bool {
+	serverErr, ok := err.(rpc.ServerError)
+	return ok && strings.HasPrefix(string(serverErr), "rpc: can't find method ")
+}
+
+// featureCall calls a method of an optional driver interface. The plugin
+// server reports that its driver does not implement the interface with the
+// message of a FeatureNotSupported error, which is turned back into one. So
+// is the error of a plugin built before the method existed.
+func (c *RPCClientDriver) featureCall(feature, serviceMethod string, args interface{}, reply interface{}) error {
+	err := c.Client.Call(serviceMethod, args, reply)
+
+	if serverErr, ok := err.(rpc.ServerError); ok {
+		notSupported := drivers.FeatureNotSupported{DriverName: c.DriverName(), Feature: feature}
+		if string(serverErr) == notSupported.Error() || unknownMethod(err) {
+			return notSupported
+		}
+	}
+
+	return err
+}
+
+func (c *RPCClientDriver) CreateSnapshot(name string) error {
+	return c.featureCall("snapshots", CreateSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) ListSnapshots() ([]drivers.Snapshot, error) {
+	var snapshots []drivers.Snapshot
+	if err := c.featureCall("snapshots", ListSnapshotsMethod, struct{}{}, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *RPCClientDriver) RestoreSnapshot(name string) error {
+	return c.featureCall("snapshots", RestoreSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) RemoveSnapshot(name string) error {
+	return c.featureCall("snapshots", RemoveSnapshotMethod, name, nil)
+}
+
+func (c *RPCClientDriver) Pause() error {
+	return c.featureCall("pausing", PauseMethod, struct{}{}, nil)
+}
+
+func (c *RPCClientDriver) Unpause() error {
+	return c.featureCall("pausing", UnpauseMethod, struct{}{}, nil)
+}
+
+func (c *RPCClientDriver) Suspend() error {
+	return c.featureCall("pausing", SuspendMethod, struct{}{}, nil)
+}
+
+func (c *RPCClientDriver) Resize(opts drivers.ResizeOptions) error {
+	return c.featureCall("resizing", ResizeMethod, opts, nil)
+}
+
+func (c *RPCClientDriver) Clone(name string) ([]byte, error) {
+	var data []byte
+	if err := c.featureCall("cloning", CloneMethod, name, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (c *RPCClientDriver) Rename(name string) error {
+	return c.featureCall("renaming", RenameMethod, name, nil)
+}
+
+func (c *RPCClientDriver) ReleaseStoreDir() error {
+	return c.featureCall("renaming", ReleaseStoreDirMethod, struct{}{}, nil)
+}
+
+func (c *RPCClientDriver) UseStoreDir() error {
+	return c.featureCall("renaming", UseStoreDirMethod, struct{}{}, nil)
+}
@@ -0,0 +1,274 @@
+package rpcdriver
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/version"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// newTestClientDriver serves d over an in-memory connection, the way a plugin
+// binary does.
+func newTestClientDriver(t *testing.T, d drivers.Driver) *RPCClientDriver {
+	server := rpc.NewServer()
+	if err := server.RegisterName(RPCServiceNameV1, NewRPCServerDriver(d)); err != nil {
+		t.Fatal(err)
+	}
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return &RPCClientDriver{
+		Client: &InternalClient{
+			MachineName:    "test",
+			RPCClient:      rpc.NewClient(clientConn),
+			rpcServiceName: RPCServiceNameV1,
+		},
+	}
+}
+
+func TestRPCClientDriverSnapshotsNotSupported(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	_, err := driver.ListSnapshots()
+
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "Driver", Feature: "snapshots"}, err)
+}
+
+func TestRPCClientDriverOldPlugin(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	err := driver.featureCall("snapshots", ".Unknown", struct{}{}, nil)
+
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "Driver", Feature: "snapshots"}, err)
+}
+
+func TestRPCClientDriverMachineVersion(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	machineVersion, err := driver.MachineVersion()
+
+	assert.NoError(t, err)
+	assert.Equal(t, version.FullVersion(), machineVersion)
+}
+
+func TestRPCClientDriverCapabilities(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	driver.negotiateCapabilities()
+	capabilities, known := driver.Capabilities()
+
+	assert.True(t, known)
+	assert.Equal(t, []string{drivers.CapabilityPausing, drivers.CapabilityResizing, drivers.CapabilityCloning, drivers.CapabilityRenaming}, capabilities)
+
+	_, err := drivers.GetSnapshotter(driver)
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "Driver", Feature: "snapshots"}, err)
+}
+
+func TestRPCClientDriverCapabilitiesOldPlugin(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	driver.Client.rpcServiceName = RPCServiceNameV0
+	driver.negotiateCapabilities()
+	_, known := driver.Capabilities()
+
+	assert.False(t, known)
+	assert.True(t, drivers.Supports(driver, drivers.CapabilitySnapshots))
+}
+
+// newTestPluginProcess serves fake drivers over an in-memory connection, the
+// way a plugin binary registered with a driver factory does if shared.
+func newTestPluginProcess(t *testing.T, shared bool) *pluginProcess {
+	server := rpc.NewServer()
+	primary := NewRPCServerDriver(&fakedriver.Driver{})
+	if err := server.RegisterName(RPCServiceNameV1, primary); err != nil {
+		t.Fatal(err)
+	}
+	if shared {
+		newDriver := func() drivers.Driver { return &fakedriver.Driver{} }
+		if err := server.RegisterName(RPCServicePoolName, NewRPCServerDriverPool(primary, newDriver, server.RegisterName)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return &pluginProcess{
+		driverName: "fakedriver",
+		plugin:     &localbinary.Plugin{},
+		client:     NewInternalClient(rpc.NewClient(clientConn)),
+	}
+}
+
+func TestPluginProcessSharedDrivers(t *testing.T) {
+	p := newTestPluginProcess(t, true)
+	defer p.client.RPCClient.Close()
+
+	first, err := p.newDriver(RPCServiceNameV1, []byte(`{"MockName": "first"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "first", p.logPrefix())
+
+	serviceName, err := p.newService([]byte(`{"MockName": "second"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, RPCServiceNameV1+"1", serviceName)
+
+	second, err := p.newDriver(serviceName, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "first", first.GetMachineName())
+	assert.Equal(t, "second", second.GetMachineName())
+	assert.Equal(t, "fakedriver", p.logPrefix())
+	assert.Equal(t, "fakedriver", p.plugin.MachineName)
+}
+
+func TestRPCClientDriverClose(t *testing.T) {
+	p := newTestPluginProcess(t, true)
+	defer p.client.RPCClient.Close()
+
+	f := NewRPCClientDriverFactory().(*DefaultRPCClientDriverFactory)
+	f.openedPlugins = []*pluginProcess{p}
+	f.sharedPlugins["fakedriver"] = p
+
+	first, err := f.NewRPCClientDriver("fakedriver", []byte(`{"MockName": "first"}`))
+	assert.NoError(t, err)
+	second, err := f.NewRPCClientDriver("fakedriver", []byte(`{"MockName": "second"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "fakedriver", p.logPrefix())
+
+	assert.NoError(t, first.Close())
+	assert.NoError(t, first.Close())
+
+	assert.Equal(t, "second", p.logPrefix())
+	assert.Equal(t, []*pluginProcess{p}, f.openedPlugins)
+	assert.Equal(t, p, f.sharedPlugins["fakedriver"])
+	assert.Equal(t, "second", second.GetMachineName())
+}
+
+func TestPluginProcessReleaseService(t *testing.T) {
+	p := newTestPluginProcess(t, true)
+	defer p.client.RPCClient.Close()
+
+	_, err := p.newDriver(RPCServiceNameV1, []byte(`{"MockName": "first"}`))
+	assert.NoError(t, err)
+
+	second, err := p.newService([]byte(`{"MockName": "second"}`))
+	assert.NoError(t, err)
+	again, err := p.newService([]byte(`{"MockName": "second"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, second, again)
+
+	p.releaseService("second")
+	p.releaseService("second")
+
+	third, err := p.newService([]byte(`{"MockName": "third"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, second, third)
+
+	p.releaseService("first")
+
+	fourth, err := p.newService([]byte(`{"MockName": "fourth"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, RPCServiceNameV1, fourth)
+}
+
+func TestPluginProcessReleaseNewService(t *testing.T) {
+	p := newTestPluginProcess(t, true)
+	defer p.client.RPCClient.Close()
+
+	_, err := p.newDriver(RPCServiceNameV1, []byte(`{"MockName": "first"}`))
+	assert.NoError(t, err)
+
+	second, err := p.newService([]byte(`{"MockName": "second"}`))
+	assert.NoError(t, err)
+
+	p.releaseNewService(second)
+
+	third, err := p.newService([]byte(`{"MockName": "third"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, second, third)
+}
+
+func TestRPCClientDriverSharedConfig(t *testing.T) {
+	p := newTestPluginProcess(t, true)
+	defer p.client.RPCClient.Close()
+
+	f := NewRPCClientDriverFactory().(*DefaultRPCClientDriverFactory)
+	f.openedPlugins = []*pluginProcess{p}
+	f.sharedPlugins["fakedriver"] = p
+
+	_, err := f.NewRPCClientDriver("fakedriver", []byte(`{"MockName": "first"}`))
+	assert.NoError(t, err)
+	second, err := f.NewRPCClientDriver("fakedriver", []byte(`{"MockName": "second", "MockState": 1}`))
+	assert.NoError(t, err)
+	again, err := f.NewRPCClientDriver("fakedriver", []byte(`{"MockName": "second", "MockState": 2}`))
+	assert.NoError(t, err)
+
+	st, err := second.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Running, st)
+
+	st, err = again.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Running, st)
+}
+
+func TestPluginProcessNotShared(t *testing.T) {
+	p := newTestPluginProcess(t, false)
+	defer p.client.RPCClient.Close()
+
+	_, err := p.newService([]byte(`{"MockName": "second"}`))
+
+	assert.Error(t, err)
+}
+
+type contextCreatorDriver struct {
+	*fakedriver.Driver
+	deadline time.Time
+	created  chan struct{}
+}
+
+func (d *contextCreatorDriver) CreateContext(ctx context.Context) error {
+	d.deadline, _ = ctx.Deadline()
+	close(d.created)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRPCClientDriverCreateContextCancelled(t *testing.T) {
+	actual := &contextCreatorDriver{Driver: &fakedriver.Driver{}, created: make(chan struct{})}
+	driver := newTestClientDriver(t, actual)
+	defer driver.Client.RPCClient.Close()
+
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	go func() {
+		<-actual.created
+		cancel()
+	}()
+
+	err := driver.CreateContext(ctx)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, deadline.Equal(actual.deadline))
+}
+
+func TestRPCClientDriverCreateContextDone(t *testing.T) {
+	driver := newTestClientDriver(t, &fakedriver.Driver{})
+	defer driver.Client.RPCClient.Close()
+
+	assert.NoError(t, driver.CreateContext(context.Background()))
+}
@@ -0,0 +1,134 @@
+package rpcdriver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	// logPollTimeout is how long GetLogs waits for a record before it
+	// replies with none.
+	logPollTimeout = time.Second
+
+	// maxQueuedLogs bounds the records kept for a client which does not
+	// fetch them; the oldest are dropped first.
+	maxQueuedLogs = 1000
+)
+
+// logQueue keeps the log records of a plugin until the client fetches them.
+type logQueue struct {
+	lock    sync.Mutex
+	records []log.Record
+	ready   chan struct{}
+}
+
+func newLogQueue() *logQueue {
+	return &logQueue{
+		ready: make(chan struct{}, 1),
+	}
+}
+
+func (q *logQueue) push(r log.Record) {
+	q.lock.Lock()
+	if len(q.records) >= maxQueuedLogs {
+		q.records = q.records[1:]
+	}
+	q.records = append(q.records, r)
+	q.lock.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the queued records, waiting up to timeout for one if there is
+// none yet.
+func (q *logQueue) pop(timeout time.Duration) []log.Record {
+	if records := q.take(); len(records) > 0 {
+		return records
+	}
+
+	select {
+	case <-q.ready:
+	case <-time.After(timeout):
+	}
+
+	return q.take()
+}
+
+// flush returns the queued records without waiting, and wakes a pop waiting
+// for one so that it replies at once.
+func (q *logQueue) flush() []log.Record {
+	records := q.take()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return records
+}
+
+func (q *logQueue) take() []log.Record {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	records := q.records
+	q.records = nil
+	return records
+}
+
+// machineCalls counts the calls in flight of the drivers of each machine
+// served by a plugin binary. The drivers log through the same logger, so a
+// record is tagged with a machine only while a single one has calls in
+// flight.
+type machineCalls struct {
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func newMachineCalls() *machineCalls {
+	return &machineCalls{
+		calls: map[string]int{},
+	}
+}
+
+// start counts a call for the driver of machine until the returned function
+// is called.
+func (c *machineCalls) start(machine string) func() {
+	c.lock.Lock()
+	c.calls[machine]++
+	c.lock.Unlock()
+
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		c.calls[machine]--
+		if c.calls[machine] == 0 {
+			delete(c.calls, machine)
+		}
+	}
+}
+
+// machine returns the machine whose driver has calls in flight, or "" if
+// there is none or several.
+func (c *machineCalls) machine() string {
+	if c == nil {
+		return ""
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.calls) != 1 {
+		return ""
+	}
+	for machine := range c.calls {
+		return machine
+	}
+	return ""
+}
@@ -0,0 +1,286 @@
+package rpcdriver
+
+import (
+	"fmt"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/version"
+)
+
+// pluginProcess is a running plugin binary. The drivers of the machines it
+// serves share its connection, heartbeat and logs.
+type pluginProcess struct {
+	driverName      string
+	plugin          *localbinary.Plugin
+	client          *InternalClient
+	apiVersion      int
+	heartbeatDoneCh chan bool
+
+	lock        sync.Mutex
+	drivers     []*RPCClientDriver
+	logsStopped bool
+	logsDone    chan struct{}
+}
+
+// startPlugin starts the plugin binary of a driver and checks that it speaks
+// the same version of the RPC protocol. The process is returned along with
+// the error once it is running, so that it can be closed.
+func startPlugin(driverName string) (*pluginProcess, error) {
+	p, err := localbinary.NewPlugin(driverName)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := p.Serve(); err != nil {
+			// TODO: Is this best approach?
+			log.Warn(err)
+			return
+		}
+	}()
+
+	addr, err := p.Address()
+	if err != nil {
+		return nil, fmt.Errorf("Error attempting to get plugin server address for RPC: %s", err)
+	}
+
+	rpcclient, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	pp := &pluginProcess{
+		driverName:      driverName,
+		plugin:          p,
+		client:          NewInternalClient(rpcclient),
+		heartbeatDoneCh: make(chan bool),
+	}
+
+	var serverVersion int
+	if err := pp.client.Call(GetVersionMethod, struct{}{}, &serverVersion); err != nil {
+		// this is the first call we make to the server. We try to play nice with old pre 0.5.1 client,
+		// by gracefully trying old RPCServiceName, we do this only once, and keep the result for future calls.
+		log.Debug(err)
+		log.Debugf("Client (%s) with %s does not work, re-attempting with %s", driverName, RPCServiceNameV1, RPCServiceNameV0)
+		pp.client.switchToV0()
+		if err := pp.client.Call(GetVersionMethod, struct{}{}, &serverVersion); err != nil {
+			return pp, err
+		}
+	}
+
+	if serverVersion != version.APIVersion {
+		return pp, ErrIncompatibleAPIVersion{serverVersion}
+	}
+	log.Debug("Using API Version ", serverVersion)
+	pp.apiVersion = serverVersion
+
+	go func(pp *pluginProcess) {
+		for {
+			select {
+			case <-pp.heartbeatDoneCh:
+				return
+			case <-time.After(heartbeatInterval):
+				if err := pp.client.Call(HeartbeatMethod, struct{}{}, nil); err != nil {
+					log.Warnf("Error attempting heartbeat call to plugin server: %s", err)
+				}
+			}
+		}
+	}(pp)
+
+	return pp, nil
+}
+
+// newService asks the plugin binary to serve the machine configured by
+// rawDriver, and returns the name of the RPC service of its driver. It fails
+// for the binaries which serve a single machine.
+func (p *pluginProcess) newService(rawDriver []byte) (string, error) {
+	log.Debugf("Asking the plugin of driver %q to serve another machine", p.driverName)
+
+	var serviceName string
+	if err := p.client.RPCClient.Call(RPCServicePoolName+NewDriverMethod, rawDriver, &serviceName); err != nil {
+		return "", err
+	}
+
+	return serviceName, nil
+}
+
+// releaseService tells the plugin binary that the driver of machineName is
+// no longer used, so that it serves another machine with it.
+func (p *pluginProcess) releaseService(machineName string) {
+	if err := p.client.RPCClient.Call(RPCServicePoolName+ReleaseMethod, machineName, nil); err != nil {
+		log.Debugf("Error releasing the driver of %q in the plugin of driver %q: %s", machineName, p.driverName, err)
+	}
+}
+
+// releaseNewService releases the service serviceName returned by newService
+// when no driver could be made of it. The pool knows the service by the name
+// of its machine, which is asked to the service.
+func (p *pluginProcess) releaseNewService(serviceName string) {
+	var machineName string
+	if err := p.client.RPCClient.Call(serviceName+GetMachineNameMethod, struct{}{}, &machineName); err != nil {
+		log.Debugf("Error getting the machine of the service %q in the plugin of driver %q: %s", serviceName, p.driverName, err)
+		return
+	}
+
+	p.releaseService(machineName)
+}
+
+// newDriver returns the driver served as the RPC service serviceName, once
+// configured with rawDriver unless it is nil, when the service already is.
+func (p *pluginProcess) newDriver(serviceName string, rawDriver []byte) (*RPCClientDriver, error) {
+	c := &RPCClientDriver{
+		apiVersion: p.apiVersion,
+		Client: &InternalClient{
+			RPCClient:      p.client.RPCClient,
+			rpcServiceName: serviceName,
+		},
+	}
+
+	c.negotiateCapabilities()
+
+	if rawDriver != nil {
+		if err := c.SetConfigRaw(rawDriver); err != nil {
+			return nil, err
+		}
+	}
+
+	c.Client.MachineName = c.GetMachineName()
+
+	p.lock.Lock()
+	p.drivers = append(p.drivers, c)
+	p.lock.Unlock()
+
+	p.setLogPrefix()
+
+	return c, nil
+}
+
+// removeDriver forgets a driver served by the plugin binary, and returns the
+// number of drivers it still serves.
+func (p *pluginProcess) removeDriver(c *RPCClientDriver) int {
+	p.lock.Lock()
+	for i, d := range p.drivers {
+		if d == c {
+			p.drivers = append(p.drivers[:i], p.drivers[i+1:]...)
+			break
+		}
+	}
+	remaining := len(p.drivers)
+	p.lock.Unlock()
+
+	if remaining > 0 {
+		p.setLogPrefix()
+	}
+
+	return remaining
+}
+
+func (p *pluginProcess) setLogPrefix() {
+	p.client.MachineName = p.logPrefix()
+	p.plugin.MachineName = p.client.MachineName
+}
+
+// logPrefix is the name of the machine served by the plugin binary, or the
+// name of the driver once it serves several, since the output and some of
+// the log records do not tell which machine they are about.
+func (p *pluginProcess) logPrefix() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.drivers) == 1 {
+		return p.drivers[0].Client.MachineName
+	}
+	return p.driverName
+}
+
+// forwardLogs logs the records of the plugin with their level in the
+// background, until flushLogs is called or the connection is closed. Plugins
+// built before the records were sent keep printing them to their output
+// instead.
+func (p *pluginProcess) forwardLogs() {
+	p.logsDone = make(chan struct{})
+
+	go func() {
+		defer close(p.logsDone)
+
+		for !p.logsAreStopped() {
+			var records []log.Record
+			if err := p.client.Call(GetLogsMethod, GetLogsArgs{}, &records); err != nil {
+				log.Debugf("(%s) Stopped forwarding the plugin logs: %s", p.logPrefix(), err)
+				return
+			}
+
+			p.logRecords(records)
+		}
+	}()
+}
+
+// flushLogs stops forwarding the logs of the plugin and logs the records it
+// still queues, which would be lost once it is closed.
+func (p *pluginProcess) flushLogs() {
+	if p.logsDone == nil {
+		return
+	}
+
+	p.lock.Lock()
+	p.logsStopped = true
+	p.lock.Unlock()
+
+	var records []log.Record
+	err := p.client.Call(GetLogsMethod, GetLogsArgs{Flush: true}, &records)
+
+	// Wait for the records of the call in flight, which come first.
+	<-p.logsDone
+
+	if err != nil {
+		return
+	}
+	p.logRecords(records)
+}
+
+func (p *pluginProcess) logsAreStopped() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.logsStopped
+}
+
+// logRecords logs the records prefixed with the machine they are about, or
+// with logPrefix if the plugin does not tell.
+func (p *pluginProcess) logRecords(records []log.Record) {
+	fallback := p.logPrefix()
+	for _, r := range records {
+		prefix := r.Machine
+		if prefix == "" {
+			prefix = fallback
+		}
+		r.Message = fmt.Sprintf("(%s) %s", prefix, r.Message)
+		log.Log(r)
+	}
+}
+
+func (p *pluginProcess) close() error {
+	close(p.heartbeatDoneCh)
+
+	p.flushLogs()
+
+	log.Debug("Making call to close driver server")
+
+	if err := p.client.Call(CloseMethod, struct{}{}, nil); err != nil {
+		return err
+	}
+
+	log.Debug("Successfully made call to close driver server")
+
+	log.Debug("Making call to close connection to plugin binary")
+
+	if err := p.plugin.Close(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"sync"
+	"time"
 
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/version"
+	machineversion "github.com/docker/machine/version"
+	"golang.org/x/net/context"
 )
 
 type Stacker interface {
@@ -84,6 +88,33 @@ type RPCServerDriver struct {
 	ActualDriver drivers.Driver
 	CloseCh      chan bool
 	HeartbeatCh  chan bool
+
+	// Logger is the logger of the plugin, whose records are sent to the
+	// client once it asks for them.
+	Logger *log.RecordMachineLogger
+
+	logs        *logQueue
+	forwardOnce sync.Once
+
+	// calls tells which machine the records are about. It is shared by the
+	// drivers of the machines served by the plugin binary.
+	calls *machineCalls
+
+	// cancels are the cancel functions of the contexts of the calls in
+	// flight, called by Cancel.
+	cancels     map[int]context.CancelFunc
+	cancelsLock sync.Mutex
+	lastCall    int
+
+	// actualLock guards ActualDriver, which a pool replaces while other
+	// calls may be in flight once the driver serves another machine.
+	actualLock sync.RWMutex
+}
+
+// ContextArgs carries the deadline of the context of a call, zero if it has
+// none. The context is cancelled by calling Cancel.
+type ContextArgs struct {
+	Deadline time.Time
 }
 
 func NewRPCServerDriver(d drivers.Driver) *RPCServerDriver {
@@ -91,6 +122,7 @@ func NewRPCServerDriver(d drivers.Driver) *RPCServerDriver {
 		ActualDriver: d,
 		CloseCh:      make(chan bool),
 		HeartbeatCh:  make(chan bool),
+		calls:        newMachineCalls(),
 	}
 }
 
@@ -104,8 +136,71 @@ func (r *RPCServerDriver) GetVersion(_ *struct{}, reply *int) error {
 	return nil
 }
 
+func (r *RPCServerDriver) GetMachineVersion(_ *struct{}, reply *string) error {
+	*reply = machineversion.FullVersion()
+	return nil
+}
+
+func (r *RPCServerDriver) GetCapabilities(_ *struct{}, reply *[]string) error {
+	*reply, _ = drivers.Capabilities(r.actual())
+	return nil
+}
+
+// GetLogsArgs are the arguments of GetLogs.
+type GetLogsArgs struct {
+	// Flush replies at once with the records left, e.g. before the client
+	// closes the plugin, and ends the call waiting for one.
+	Flush bool
+}
+
+// GetLogs replies with the log records of the plugin, waiting for one if
+// there is none yet. The records are printed to the output of the plugin
+// until the first call, so that they are not lost with older clients.
+func (r *RPCServerDriver) GetLogs(args *GetLogsArgs, reply *[]log.Record) error {
+	r.forwardOnce.Do(func() {
+		r.logs = newLogQueue()
+		if r.Logger != nil {
+			r.Logger.Forward(func(record log.Record) {
+				record.Machine = r.calls.machine()
+				r.logs.push(record)
+			})
+		}
+	})
+
+	if args != nil && args.Flush {
+		*reply = r.logs.flush()
+		return nil
+	}
+
+	*reply = r.logs.pop(logPollTimeout)
+	return nil
+}
+
+// actual returns the driver the calls go to.
+func (r *RPCServerDriver) actual() drivers.Driver {
+	r.actualLock.RLock()
+	defer r.actualLock.RUnlock()
+	return r.ActualDriver
+}
+
+// setActual makes the calls go to d from now on.
+func (r *RPCServerDriver) setActual(d drivers.Driver) {
+	r.actualLock.Lock()
+	defer r.actualLock.Unlock()
+	r.ActualDriver = d
+}
+
+// track marks a call of the driver in flight until the returned function is
+// called, so that the records logged meanwhile are tagged with its machine.
+func (r *RPCServerDriver) track() func() {
+	if r.calls == nil {
+		return func() {}
+	}
+	return r.calls.start(r.actual().GetMachineName())
+}
+
 func (r *RPCServerDriver) GetConfigRaw(_ *struct{}, reply *[]byte) error {
-	driverData, err := json.Marshal(r.ActualDriver)
+	driverData, err := json.Marshal(r.actual())
 	if err != nil {
 		return err
 	}
@@ -116,11 +211,13 @@ func (r *RPCServerDriver) GetConfigRaw(_ *struct{}, reply *[]byte) error {
 }
 
 func (r *RPCServerDriver) GetCreateFlags(_ *struct{}, reply *[]mcnflag.Flag) error {
-	*reply = r.ActualDriver.GetCreateFlags()
+	*reply = r.actual().GetCreateFlags()
 	return nil
 }
 
 func (r *RPCServerDriver) SetConfigRaw(data []byte, _ *struct{}) error {
+	r.actualLock.Lock()
+	defer r.actualLock.Unlock()
 	return json.Unmarshal(data, &r.ActualDriver)
 }
 
@@ -136,89 +233,292 @@ func (r *RPCServerDriver) Create(_, _ *struct{}) (err error) {
 	// and do not crash the RPC server completely in the case of a panic
 	// during create.
 	defer trapPanic(&err)
+	defer r.track()()
 
-	err = r.ActualDriver.Create()
+	err = r.actual().Create()
 
 	return err
 }
 
+// CreateContext creates the machine until the client calls Cancel or the
+// deadline of its context expires. The drivers which do not take a context
+// are only asked to abort by Cancel.
+func (r *RPCServerDriver) CreateContext(args *ContextArgs, _ *struct{}) (err error) {
+	c, ok := r.actual().(drivers.ContextCreator)
+	if !ok {
+		return r.Create(nil, nil)
+	}
+
+	defer trapPanic(&err)
+	defer r.track()()
+
+	ctx, done := r.callContext(args)
+	defer done()
+
+	return c.CreateContext(ctx)
+}
+
+// callContext returns the context of a call, and the function to call once
+// the call returns.
+func (r *RPCServerDriver) callContext(args *ContextArgs) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !args.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(context.Background(), args.Deadline)
+	}
+
+	r.cancelsLock.Lock()
+	defer r.cancelsLock.Unlock()
+
+	if r.cancels == nil {
+		r.cancels = map[int]context.CancelFunc{}
+	}
+	r.lastCall++
+	call := r.lastCall
+	r.cancels[call] = cancel
+
+	return ctx, func() {
+		r.cancelsLock.Lock()
+		defer r.cancelsLock.Unlock()
+
+		delete(r.cancels, call)
+		cancel()
+	}
+}
+
 func (r *RPCServerDriver) DriverName(_ *struct{}, reply *string) error {
-	*reply = r.ActualDriver.DriverName()
+	*reply = r.actual().DriverName()
 	return nil
 }
 
 func (r *RPCServerDriver) GetIP(_ *struct{}, reply *string) error {
-	ip, err := r.ActualDriver.GetIP()
+	defer r.track()()
+
+	ip, err := r.actual().GetIP()
 	*reply = ip
 	return err
 }
 
 func (r *RPCServerDriver) GetMachineName(_ *struct{}, reply *string) error {
-	*reply = r.ActualDriver.GetMachineName()
+	*reply = r.actual().GetMachineName()
 	return nil
 }
 
 func (r *RPCServerDriver) GetSSHHostname(_ *struct{}, reply *string) error {
-	hostname, err := r.ActualDriver.GetSSHHostname()
+	defer r.track()()
+
+	hostname, err := r.actual().GetSSHHostname()
 	*reply = hostname
 	return err
 }
 
 func (r *RPCServerDriver) GetSSHKeyPath(_ *struct{}, reply *string) error {
-	*reply = r.ActualDriver.GetSSHKeyPath()
+	*reply = r.actual().GetSSHKeyPath()
 	return nil
 }
 
 // GetSSHPort returns port for use with ssh
 func (r *RPCServerDriver) GetSSHPort(_ *struct{}, reply *int) error {
-	port, err := r.ActualDriver.GetSSHPort()
+	defer r.track()()
+
+	port, err := r.actual().GetSSHPort()
 	*reply = port
 	return err
 }
 
 func (r *RPCServerDriver) GetSSHUsername(_ *struct{}, reply *string) error {
-	*reply = r.ActualDriver.GetSSHUsername()
+	*reply = r.actual().GetSSHUsername()
 	return nil
 }
 
 func (r *RPCServerDriver) GetURL(_ *struct{}, reply *string) error {
-	info, err := r.ActualDriver.GetURL()
+	defer r.track()()
+
+	info, err := r.actual().GetURL()
 	*reply = info
 	return err
 }
 
 func (r *RPCServerDriver) GetState(_ *struct{}, reply *state.State) error {
-	s, err := r.ActualDriver.GetState()
+	defer r.track()()
+
+	s, err := r.actual().GetState()
 	*reply = s
 	return err
 }
 
 func (r *RPCServerDriver) Kill(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Kill()
+	defer r.track()()
+
+	return r.actual().Kill()
 }
 
 func (r *RPCServerDriver) PreCreateCheck(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.PreCreateCheck()
+	defer r.track()()
+
+	return r.actual().PreCreateCheck()
 }
 
 func (r *RPCServerDriver) Remove(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Remove()
+	defer r.track()()
+
+	return r.actual().Remove()
 }
 
 func (r *RPCServerDriver) Restart(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Restart()
+	defer r.track()()
+
+	return r.actual().Restart()
 }
 
 func (r *RPCServerDriver) SetConfigFromFlags(flags *drivers.DriverOptions, _ *struct{}) error {
-	return r.ActualDriver.SetConfigFromFlags(*flags)
+	defer r.track()()
+
+	return r.actual().SetConfigFromFlags(*flags)
 }
 
 func (r *RPCServerDriver) Start(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Start()
+	defer r.track()()
+
+	return r.actual().Start()
 }
 
 func (r *RPCServerDriver) Stop(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Stop()
+	defer r.track()()
+
+	return r.actual().Stop()
+}
+
+// Cancel cancels the context of the calls in flight, and asks the driver to
+// abort the operation it is performing.
+func (r *RPCServerDriver) Cancel(_ *struct{}, _ *struct{}) error {
+	r.cancelsLock.Lock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	r.cancelsLock.Unlock()
+
+	if c, ok := r.actual().(drivers.Canceler); ok {
+		return c.Cancel()
+	}
+	return nil
+}
+
+func (r *RPCServerDriver) CreateSnapshot(name *string, _ *struct{}) error {
+	defer r.track()()
+
+	s, err := drivers.GetSnapshotter(r.actual())
+	if err != nil {
+		return err
+	}
+	return s.CreateSnapshot(*name)
+}
+
+func (r *RPCServerDriver) ListSnapshots(_ *struct{}, reply *[]drivers.Snapshot) error {
+	defer r.track()()
+
+	s, err := drivers.GetSnapshotter(r.actual())
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := s.ListSnapshots()
+	*reply = snapshots
+	return err
+}
+
+func (r *RPCServerDriver) RestoreSnapshot(name *string, _ *struct{}) error {
+	defer r.track()()
+
+	s, err := drivers.GetSnapshotter(r.actual())
+	if err != nil {
+		return err
+	}
+	return s.RestoreSnapshot(*name)
+}
+
+func (r *RPCServerDriver) RemoveSnapshot(name *string, _ *struct{}) error {
+	defer r.track()()
+
+	s, err := drivers.GetSnapshotter(r.actual())
+	if err != nil {
+		return err
+	}
+	return s.RemoveSnapshot(*name)
+}
+
+func (r *RPCServerDriver) Pause(_ *struct{}, _ *struct{}) error {
+	defer r.track()()
+
+	p, err := drivers.GetPauser(r.actual())
+	if err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+func (r *RPCServerDriver) Unpause(_ *struct{}, _ *struct{}) error {
+	defer r.track()()
+
+	p, err := drivers.GetPauser(r.actual())
+	if err != nil {
+		return err
+	}
+	return p.Unpause()
+}
+
+func (r *RPCServerDriver) Suspend(_ *struct{}, _ *struct{}) error {
+	defer r.track()()
+
+	p, err := drivers.GetPauser(r.actual())
+	if err != nil {
+		return err
+	}
+	return p.Suspend()
+}
+
+func (r *RPCServerDriver) Resize(opts *drivers.ResizeOptions, _ *struct{}) error {
+	defer r.track()()
+
+	resizer, err := drivers.GetResizer(r.actual())
+	if err != nil {
+		return err
+	}
+	return resizer.Resize(*opts)
+}
+
+func (r *RPCServerDriver) Clone(name *string, reply *[]byte) error {
+	defer r.track()()
+
+	cloner, err := drivers.GetCloner(r.actual())
+	if err != nil {
+		return err
+	}
+
+	data, err := cloner.Clone(*name)
+	*reply = data
+	return err
+}
+
+func (r *RPCServerDriver) Rename(name *string, _ *struct{}) error {
+	defer r.track()()
+
+	renamer, err := drivers.GetRenamer(r.actual())
+	if err != nil {
+		return err
+	}
+	return renamer.Rename(*name)
+}
+
+func (r *RPCServerDriver) ReleaseStoreDir(_ *struct{}, _ *struct{}) error {
+	defer r.track()()
+
+	return drivers.ReleaseStoreDir(r.actual())
+}
+
+func (r *RPCServerDriver) UseStoreDir(_ *struct{}, _ *struct{}) error {
+	defer r.track()()
+
+	return drivers.UseStoreDir(r.actual())
 }
 
 func (r *RPCServerDriver) Heartbeat(_ *struct{}, _ *struct{}) error {
@@ -3,8 +3,10 @@ package rpcdriver
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,3 +75,100 @@ func TestRPCServerDriverCreate(t *testing.T) {
 		assert.Equal(t, tc.expectedErr, tc.serverDriver.Create(nil, nil))
 	}
 }
+
+func TestRPCServerDriverGetLogs(t *testing.T) {
+	logPollTimeout = 10 * time.Millisecond
+	defer func() { logPollTimeout = time.Second }()
+
+	logger := log.NewRecordMachineLogger(log.NewFmtMachineLogger())
+	serverDriver := &RPCServerDriver{
+		ActualDriver: &fakedriver.Driver{},
+		Logger:       logger,
+	}
+
+	var records []log.Record
+	assert.NoError(t, serverDriver.GetLogs(nil, &records))
+	assert.Empty(t, records)
+
+	logger.Info("Creating VM...")
+	logger.Debugf("Using disk %s", "disk.vmdk")
+
+	assert.NoError(t, serverDriver.GetLogs(nil, &records))
+	assert.Equal(t, []log.Record{
+		{Level: log.InfoLevel, Message: "Creating VM..."},
+		{Level: log.DebugLevel, Message: "Using disk disk.vmdk"},
+	}, records)
+}
+
+func TestRPCServerDriverGetLogsFlush(t *testing.T) {
+	logPollTimeout = time.Hour
+	defer func() { logPollTimeout = time.Second }()
+
+	logger := log.NewRecordMachineLogger(log.NewFmtMachineLogger())
+	serverDriver := &RPCServerDriver{
+		ActualDriver: &fakedriver.Driver{},
+		Logger:       logger,
+	}
+
+	polled := make(chan []log.Record)
+	go func() {
+		var records []log.Record
+		serverDriver.GetLogs(&GetLogsArgs{}, &records)
+		polled <- records
+	}()
+
+	var records []log.Record
+	for len(records) == 0 {
+		logger.Info("Stopping VM...")
+		assert.NoError(t, serverDriver.GetLogs(&GetLogsArgs{Flush: true}, &records))
+	}
+
+	select {
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetLogs kept waiting after the flush")
+	}
+}
+
+func TestRPCServerDriverGetLogsMachine(t *testing.T) {
+	logPollTimeout = 10 * time.Millisecond
+	defer func() { logPollTimeout = time.Second }()
+
+	logger := log.NewRecordMachineLogger(log.NewFmtMachineLogger())
+	first := NewRPCServerDriver(&fakedriver.Driver{MockName: "first"})
+	first.Logger = logger
+	second := &RPCServerDriver{
+		ActualDriver: &fakedriver.Driver{MockName: "second"},
+		calls:        first.calls,
+	}
+
+	var records []log.Record
+	assert.NoError(t, first.GetLogs(nil, &records))
+
+	done := second.track()
+	logger.Info("Starting VM...")
+	doneFirst := first.track()
+	logger.Info("Checking state...")
+	done()
+	doneFirst()
+	logger.Info("Waiting...")
+
+	assert.NoError(t, first.GetLogs(nil, &records))
+	assert.Equal(t, []log.Record{
+		{Level: log.InfoLevel, Message: "Starting VM...", Machine: "second"},
+		{Level: log.InfoLevel, Message: "Checking state..."},
+		{Level: log.InfoLevel, Message: "Waiting..."},
+	}, records)
+}
+
+func TestLogQueueDropsOldest(t *testing.T) {
+	maxQueuedLogs = 2
+	defer func() { maxQueuedLogs = 1000 }()
+
+	q := newLogQueue()
+	q.push(log.Record{Message: "1"})
+	q.push(log.Record{Message: "2"})
+	q.push(log.Record{Message: "3"})
+
+	assert.Equal(t, []log.Record{{Message: "2"}, {Message: "3"}}, q.pop(0))
+}
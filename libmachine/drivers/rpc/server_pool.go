@@ -0,0 +1,143 @@
+package rpcdriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+const (
+	// RPCServicePoolName is the name of the RPC service which serves the
+	// drivers of several machines from one plugin binary.
+	RPCServicePoolName = `RPCServerDriverPool`
+
+	NewDriverMethod = `.NewDriver`
+	ReleaseMethod   = `.Release`
+)
+
+// poolDriver is a driver registered as an RPC service by the pool, along
+// with the number of clients using it.
+type poolDriver struct {
+	serviceName string
+	driver      *RPCServerDriver
+	refs        int
+}
+
+// RPCServerDriverPool creates a driver for each machine served by the plugin
+// binary. Each driver is registered as its own RPC service, whose name is
+// handed to the client, so that it is called like the driver of a plugin
+// serving a single machine. Services can not be unregistered, so the drivers
+// released by the client are kept to serve the next machines.
+type RPCServerDriverPool struct {
+	primary   *RPCServerDriver
+	newDriver func() drivers.Driver
+	register  func(name string, rcvr interface{}) error
+
+	lock          sync.Mutex
+	machines      map[string]*poolDriver
+	released      []*poolDriver
+	primaryPooled bool
+	created       int
+}
+
+// NewRPCServerDriverPool returns a pool of drivers created with newDriver,
+// registered with register, e.g. rpc.RegisterName. They share the heartbeat
+// and close channels of primary, which is the driver of the first machine.
+func NewRPCServerDriverPool(primary *RPCServerDriver, newDriver func() drivers.Driver, register func(name string, rcvr interface{}) error) *RPCServerDriverPool {
+	return &RPCServerDriverPool{
+		primary:   primary,
+		newDriver: newDriver,
+		register:  register,
+		machines:  map[string]*poolDriver{},
+	}
+}
+
+// NewDriver replies with the name of the RPC service of the driver of the
+// machine configured by rawDriver. The driver is shared with the clients
+// already using the machine, if any, and then keeps its configuration.
+func (p *RPCServerDriverPool) NewDriver(rawDriver []byte, reply *string) error {
+	d := p.newDriver()
+	if err := json.Unmarshal(rawDriver, &d); err != nil {
+		return err
+	}
+	machineName := d.GetMachineName()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.poolPrimary()
+
+	if pd, ok := p.machines[machineName]; ok {
+		pd.refs++
+		*reply = pd.serviceName
+		return nil
+	}
+
+	var pd *poolDriver
+	if n := len(p.released); n > 0 {
+		pd = p.released[n-1]
+		p.released = p.released[:n-1]
+		pd.driver.setActual(d)
+	} else {
+		pd = &poolDriver{
+			serviceName: fmt.Sprintf("%s%d", RPCServiceNameV1, p.created+1),
+			driver: &RPCServerDriver{
+				ActualDriver: d,
+				CloseCh:      p.primary.CloseCh,
+				HeartbeatCh:  p.primary.HeartbeatCh,
+				calls:        p.primary.calls,
+			},
+		}
+		if err := p.register(pd.serviceName, pd.driver); err != nil {
+			return err
+		}
+		p.created++
+	}
+
+	pd.refs = 1
+	p.machines[machineName] = pd
+	*reply = pd.serviceName
+	return nil
+}
+
+// Release tells that a client no longer uses the driver of machineName. The
+// driver is reset and kept to serve another machine once no client uses it.
+func (p *RPCServerDriverPool) Release(machineName *string, _ *struct{}) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.poolPrimary()
+
+	pd, ok := p.machines[*machineName]
+	if !ok {
+		return fmt.Errorf("No driver serves the machine %q", *machineName)
+	}
+
+	pd.refs--
+	if pd.refs > 0 {
+		return nil
+	}
+
+	delete(p.machines, *machineName)
+	pd.driver.setActual(p.newDriver())
+	p.released = append(p.released, pd)
+	return nil
+}
+
+// poolPrimary adds the primary driver to the machines of the pool. Its
+// machine is only known once the client configured it, which it does before
+// asking the pool for another one.
+func (p *RPCServerDriverPool) poolPrimary() {
+	if p.primaryPooled {
+		return
+	}
+
+	p.machines[p.primary.actual().GetMachineName()] = &poolDriver{
+		serviceName: RPCServiceNameV1,
+		driver:      p.primary,
+		refs:        1,
+	}
+	p.primaryPooled = true
+}
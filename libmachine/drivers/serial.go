@@ -7,6 +7,7 @@ import (
 
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
 )
 
 var stdLock = &sync.Mutex{}
@@ -167,6 +168,170 @@ func (d *SerialDriver) Stop() error {
 	return d.Driver.Stop()
 }
 
+// Cancel asks the wrapped driver to abort its current operation. It does not
+// take the lock since the operation being cancelled is holding it.
+func (d *SerialDriver) Cancel() error {
+	Cancel(d.Driver)
+	return nil
+}
+
+// CreateContext creates a host until ctx is done.
+func (d *SerialDriver) CreateContext(ctx context.Context) error {
+	d.Lock()
+	defer d.Unlock()
+	return CreateWithContext(ctx, d.Driver)
+}
+
+// Capabilities returns the optional features of the wrapped driver.
+func (d *SerialDriver) Capabilities() ([]string, bool) {
+	return Capabilities(d.Driver)
+}
+
+// CreateSnapshot saves the state of the host if the wrapped driver handles
+// snapshots
+func (d *SerialDriver) CreateSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	s, err := GetSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return s.CreateSnapshot(name)
+}
+
+// ListSnapshots returns the snapshots of the host
+func (d *SerialDriver) ListSnapshots() ([]Snapshot, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	s, err := GetSnapshotter(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return s.ListSnapshots()
+}
+
+// RestoreSnapshot rolls the host back to a snapshot
+func (d *SerialDriver) RestoreSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	s, err := GetSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return s.RestoreSnapshot(name)
+}
+
+// RemoveSnapshot deletes a snapshot of the host
+func (d *SerialDriver) RemoveSnapshot(name string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	s, err := GetSnapshotter(d.Driver)
+	if err != nil {
+		return err
+	}
+	return s.RemoveSnapshot(name)
+}
+
+// Pause freezes the host if the wrapped driver is able to
+func (d *SerialDriver) Pause() error {
+	d.Lock()
+	defer d.Unlock()
+
+	p, err := GetPauser(d.Driver)
+	if err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+// Unpause resumes a paused host
+func (d *SerialDriver) Unpause() error {
+	d.Lock()
+	defer d.Unlock()
+
+	p, err := GetPauser(d.Driver)
+	if err != nil {
+		return err
+	}
+	return p.Unpause()
+}
+
+// Suspend saves the state of the host and stops it
+func (d *SerialDriver) Suspend() error {
+	d.Lock()
+	defer d.Unlock()
+
+	p, err := GetPauser(d.Driver)
+	if err != nil {
+		return err
+	}
+	return p.Suspend()
+}
+
+// Resize changes the resources of the host if the wrapped driver is able to
+func (d *SerialDriver) Resize(opts ResizeOptions) error {
+	d.Lock()
+	defer d.Unlock()
+
+	r, err := GetResizer(d.Driver)
+	if err != nil {
+		return err
+	}
+	return r.Resize(opts)
+}
+
+// Clone duplicates the host if the wrapped driver is able to
+func (d *SerialDriver) Clone(name string) ([]byte, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	c, err := GetCloner(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return c.Clone(name)
+}
+
+// Rename renames the host if the wrapped driver is able to
+func (d *SerialDriver) Rename(name string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	r, err := GetRenamer(d.Driver)
+	if err != nil {
+		return err
+	}
+	return r.Rename(name)
+}
+
+// ReleaseStoreDir releases the store directory if the wrapped driver uses it
+func (d *SerialDriver) ReleaseStoreDir() error {
+	d.Lock()
+	defer d.Unlock()
+
+	return ReleaseStoreDir(d.Driver)
+}
+
+// UseStoreDir uses the store directory again if the wrapped driver uses it
+func (d *SerialDriver) UseStoreDir() error {
+	d.Lock()
+	defer d.Unlock()
+
+	return UseStoreDir(d.Driver)
+}
+
+// Close releases what the wrapped driver holds
+func (d *SerialDriver) Close() error {
+	d.Lock()
+	defer d.Unlock()
+
+	return Close(d.Driver)
+}
+
 func (d *SerialDriver) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.Driver)
 }
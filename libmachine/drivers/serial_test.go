@@ -294,3 +294,45 @@ func TestSerialDriverStop(t *testing.T) {
 
 	assert.Equal(t, []string{"Lock", "Stop", "Unlock"}, callRecorder.calls)
 }
+
+type MockSnapshotDriver struct {
+	*MockDriver
+}
+
+func (d *MockSnapshotDriver) CreateSnapshot(name string) error {
+	d.calls.record("CreateSnapshot")
+	return nil
+}
+
+func (d *MockSnapshotDriver) ListSnapshots() ([]Snapshot, error) {
+	d.calls.record("ListSnapshots")
+	return nil, nil
+}
+
+func (d *MockSnapshotDriver) RestoreSnapshot(name string) error {
+	d.calls.record("RestoreSnapshot")
+	return nil
+}
+
+func (d *MockSnapshotDriver) RemoveSnapshot(name string) error {
+	d.calls.record("RemoveSnapshot")
+	return nil
+}
+
+func TestSerialDriverCreateSnapshot(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockSnapshotDriver{&MockDriver{calls: callRecorder}}, &MockLocker{calls: callRecorder})
+	driver.(Snapshotter).CreateSnapshot("snapshot")
+
+	assert.Equal(t, []string{"Lock", "CreateSnapshot", "Unlock"}, callRecorder.calls)
+}
+
+func TestSerialDriverSnapshotsNotSupported(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockDriver{calls: callRecorder, driverName: "mock"}, &MockLocker{calls: callRecorder})
+	err := driver.(Snapshotter).RestoreSnapshot("snapshot")
+
+	assert.Equal(t, FeatureNotSupported{DriverName: "mock", Feature: "snapshots"}, err)
+}
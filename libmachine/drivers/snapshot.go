@@ -0,0 +1,32 @@
+package drivers
+
+// Snapshot describes a saved state of a machine.
+type Snapshot struct {
+	Name string
+
+	// Current is true for the snapshot the machine has been created or
+	// restored from last.
+	Current bool
+}
+
+// Snapshotter is an optional interface for drivers which are able to save
+// the state of a machine and roll it back later. The RPC client driver
+// implements it by forwarding the requests to the plugin server, and reports
+// whether the actual driver does with CapabilityReporter: use GetSnapshotter
+// rather than a type assertion.
+type Snapshotter interface {
+	CreateSnapshot(name string) error
+	ListSnapshots() ([]Snapshot, error)
+	RestoreSnapshot(name string) error
+	RemoveSnapshot(name string) error
+}
+
+// GetSnapshotter returns d as a Snapshotter, or a FeatureNotSupported error
+// if the driver does not handle snapshots.
+func GetSnapshotter(d Driver) (Snapshotter, error) {
+	s, ok := d.(Snapshotter)
+	if !ok || !Supports(d, CapabilitySnapshots) {
+		return nil, FeatureNotSupported{DriverName: d.DriverName(), Feature: CapabilitySnapshots}
+	}
+	return s, nil
+}
@@ -0,0 +1,204 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+// Type describes where in its lifecycle a stage is.
+type Type string
+
+const (
+	Started  Type = "started"
+	Finished Type = "finished"
+	Failed   Type = "failed"
+
+	// Changed is published when the health of a machine changes. Unlike
+	// the other types, it is not part of an operation.
+	Changed Type = "changed"
+)
+
+// Stage identifies a unit of work performed on a machine.
+type Stage string
+
+const (
+	PreCreateCheck    Stage = "pre-create-check"
+	Create            Stage = "create"
+	WaitForRunning    Stage = "wait-for-running"
+	WaitForSSH        Stage = "wait-for-ssh"
+	DetectProvisioner Stage = "detect-provisioner"
+	Provision         Stage = "provision"
+	CheckConnection   Stage = "check-connection"
+	Start             Stage = "start"
+	Stop              Stage = "stop"
+	Kill              Stage = "kill"
+	Restart           Stage = "restart"
+	Upgrade           Stage = "upgrade"
+	Pause             Stage = "pause"
+	Unpause           Stage = "unpause"
+	Suspend           Stage = "suspend"
+	Resize            Stage = "resize"
+	Clone             Stage = "clone"
+	Rename            Stage = "rename"
+	Hook              Stage = "hook"
+	RestartDocker     Stage = "restart-docker"
+	Health            Stage = "health"
+)
+
+// Event is a typed notification about the progress of a stage on a machine.
+type Event struct {
+	Type       Type
+	Stage      Stage
+	HostName   string
+	DriverName string
+
+	// Detail holds optional stage specific information, e.g. the name of
+	// the provisioner being used.
+	Detail string
+
+	Time time.Time
+
+	// Duration is only set on Finished and Failed events.
+	Duration time.Duration
+
+	// Err is only set on Failed events.
+	Err error
+}
+
+// Handler is called synchronously for every published event.
+type Handler func(Event)
+
+// Dispatcher delivers events to its subscribers.
+type Dispatcher struct {
+	lock     sync.RWMutex
+	nextID   int
+	handlers map[int]Handler
+	order    []int
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: map[int]Handler{},
+	}
+}
+
+// Subscribe registers a handler and returns a function which removes it.
+func (d *Dispatcher) Subscribe(handler Handler) func() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	id := d.nextID
+	d.nextID++
+	d.handlers[id] = handler
+	d.order = append(d.order, id)
+
+	return func() {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		delete(d.handlers, id)
+		for i, orderedID := range d.order {
+			if orderedID == id {
+				d.order = append(d.order[:i], d.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish delivers the event to every subscriber in subscription order.
+func (d *Dispatcher) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	d.lock.RLock()
+	handlers := make([]Handler, 0, len(d.order))
+	for _, id := range d.order {
+		handlers = append(handlers, d.handlers[id])
+	}
+	d.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+// Begin publishes a Started event for the stage and returns an Operation
+// used to report its outcome.
+func (d *Dispatcher) Begin(stage Stage, hostName, driverName string) *Operation {
+	return d.BeginWithDetail(stage, hostName, driverName, "")
+}
+
+func (d *Dispatcher) BeginWithDetail(stage Stage, hostName, driverName, detail string) *Operation {
+	op := &Operation{
+		dispatcher: d,
+		start:      time.Now(),
+		template: Event{
+			Stage:      stage,
+			HostName:   hostName,
+			DriverName: driverName,
+			Detail:     detail,
+		},
+	}
+
+	e := op.template
+	e.Type = Started
+	e.Time = op.start
+	d.Publish(e)
+
+	return op
+}
+
+// Operation is a stage which has been started and not yet reported as
+// finished or failed.
+type Operation struct {
+	dispatcher *Dispatcher
+	start      time.Time
+	template   Event
+}
+
+// End publishes a Finished event if err is nil and a Failed event otherwise.
+// The error is returned unchanged so that callers can write
+// `return op.End(err)`.
+func (op *Operation) End(err error) error {
+	e := op.template
+	e.Time = time.Now()
+	e.Duration = e.Time.Sub(op.start)
+
+	if err != nil {
+		e.Type = Failed
+		e.Err = err
+	} else {
+		e.Type = Finished
+	}
+
+	op.dispatcher.Publish(e)
+
+	return err
+}
+
+var defaultDispatcher = NewDispatcher()
+
+// Default returns the dispatcher of the machines which belong to no
+// libmachine Client, which the package level functions use.
+func Default() *Dispatcher {
+	return defaultDispatcher
+}
+
+// Subscribe registers a handler on the default dispatcher.
+func Subscribe(handler Handler) func() {
+	return defaultDispatcher.Subscribe(handler)
+}
+
+func Publish(e Event) {
+	defaultDispatcher.Publish(e)
+}
+
+func Begin(stage Stage, hostName, driverName string) *Operation {
+	return defaultDispatcher.Begin(stage, hostName, driverName)
+}
+
+func BeginWithDetail(stage Stage, hostName, driverName, detail string) *Operation {
+	return defaultDispatcher.BeginWithDetail(stage, hostName, driverName, detail)
+}
@@ -0,0 +1,75 @@
+package event
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeAndUnsubscribe(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	received := []Stage{}
+	unsubscribe := dispatcher.Subscribe(func(e Event) {
+		received = append(received, e.Stage)
+	})
+
+	dispatcher.Publish(Event{Type: Started, Stage: Create})
+	unsubscribe()
+	dispatcher.Publish(Event{Type: Started, Stage: Provision})
+
+	assert.Equal(t, []Stage{Create}, received)
+}
+
+func TestPublishOrder(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	calls := []string{}
+	dispatcher.Subscribe(func(e Event) { calls = append(calls, "first") })
+	dispatcher.Subscribe(func(e Event) { calls = append(calls, "second") })
+
+	dispatcher.Publish(Event{})
+
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestOperationFinished(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	events := []Event{}
+	dispatcher.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+
+	err := dispatcher.BeginWithDetail(Provision, "foo", "virtualbox", "boot2docker").End(nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, Started, events[0].Type)
+	assert.Equal(t, Finished, events[1].Type)
+	for _, e := range events {
+		assert.Equal(t, Provision, e.Stage)
+		assert.Equal(t, "foo", e.HostName)
+		assert.Equal(t, "virtualbox", e.DriverName)
+		assert.Equal(t, "boot2docker", e.Detail)
+	}
+	assert.NoError(t, events[1].Err)
+}
+
+func TestOperationFailed(t *testing.T) {
+	dispatcher := NewDispatcher()
+
+	var last Event
+	dispatcher.Subscribe(func(e Event) {
+		last = e
+	})
+
+	expectedErr := errors.New("boom")
+	err := dispatcher.Begin(Start, "foo", "none").End(expectedErr)
+
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, Failed, last.Type)
+	assert.Equal(t, expectedErr, last.Err)
+	assert.False(t, last.Time.IsZero())
+}
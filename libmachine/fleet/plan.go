@@ -0,0 +1,395 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/swarm"
+)
+
+// ActionType is what has to be done to a machine to match the fleet.
+type ActionType string
+
+const (
+	Create    ActionType = "create"
+	Remove    ActionType = "remove"
+	Unchanged ActionType = "unchanged"
+	Drifted   ActionType = "drifted"
+)
+
+// Change is a difference between the configuration of a machine and the
+// fleet.
+type Change struct {
+	Field   string
+	Current string
+	Desired string
+
+	// Recreate is true if the machine has to be created again to apply
+	// the change, e.g. when its driver changed. The other changes are
+	// applied by provisioning the machine again.
+	Recreate bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Current, c.Desired)
+}
+
+// Action is what has to be done to a single machine.
+type Action struct {
+	Type ActionType
+	Name string
+
+	// Machine is the description of the machine in the fleet. It is nil
+	// for the machines to remove.
+	Machine *Machine
+
+	// Changes are the differences of a drifted machine.
+	Changes []Change
+}
+
+// Recreate returns true if the machine has to be created again to apply
+// its changes.
+func (a Action) Recreate() bool {
+	for _, c := range a.Changes {
+		if c.Recreate {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan holds the actions needed to make the store match a fleet.
+type Plan struct {
+	Actions []Action
+}
+
+// HasChanges returns true if applying the plan changes any machine.
+func (p *Plan) HasChanges() bool {
+	for _, a := range p.Actions {
+		if a.Type != Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPlan compares the machines of the fleet with the ones of the store.
+// The store may hold machines which are not managed with a fleet file, so
+// the machines missing from the fleet are only removed if prune is true.
+func NewPlan(api libmachine.API, spec *Spec, prune bool) (*Plan, error) {
+	plan := &Plan{}
+
+	for _, name := range spec.Names() {
+		action, err := compare(api, name, spec.Machines[name])
+		if err != nil {
+			return nil, err
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+
+	if !prune {
+		return plan, nil
+	}
+
+	names, err := api.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := spec.Machines[name]; !ok {
+			plan.Actions = append(plan.Actions, Action{Type: Remove, Name: name})
+		}
+	}
+
+	return plan, nil
+}
+
+// compare returns the action which makes the machine name of the store match
+// its description in the fleet. The driver of the machine is closed once
+// compared.
+func compare(api libmachine.API, name string, m *Machine) (Action, error) {
+	exists, err := api.Exists(name)
+	if err != nil {
+		return Action{}, err
+	}
+
+	if !exists {
+		return Action{Type: Create, Name: name, Machine: m}, nil
+	}
+
+	h, err := api.Load(name)
+	if err != nil {
+		return Action{}, err
+	}
+	defer drivers.Close(h.Driver)
+
+	changes, err := diff(api, h, m)
+	if err != nil {
+		return Action{}, fmt.Errorf("Error comparing %q with the fleet: %s", name, err)
+	}
+
+	action := Action{Type: Unchanged, Name: name, Machine: m}
+	if len(changes) > 0 {
+		action.Type = Drifted
+		action.Changes = changes
+	}
+
+	return action, nil
+}
+
+// diff returns the differences between a machine of the store and its
+// description in the fleet.
+func diff(api libmachine.API, h *host.Host, m *Machine) ([]Change, error) {
+	if h.DriverName != m.Driver {
+		return []Change{{Field: "driver", Current: h.DriverName, Desired: m.Driver, Recreate: true}}, nil
+	}
+
+	changes := []Change{}
+
+	if h.HostOptions != nil {
+		changes = append(changes, engineChanges(h.HostOptions.EngineOptions, m.EngineOptions())...)
+		changes = append(changes, swarmChanges(h.HostOptions.SwarmOptions, m.SwarmOptions())...)
+	}
+
+	driverChanges, err := driverChanges(api, h, m)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(changes, driverChanges...), nil
+}
+
+func engineChanges(current, desired *engine.Options) []Change {
+	if current == nil {
+		current = &engine.Options{}
+	}
+
+	changes := []Change{}
+	changes = appendListChange(changes, "engine.opts", current.ArbitraryFlags, desired.ArbitraryFlags)
+	changes = appendListChange(changes, "engine.env", current.Env, desired.Env)
+	changes = appendListChange(changes, "engine.insecure-registries", current.InsecureRegistry, desired.InsecureRegistry)
+	changes = appendListChange(changes, "engine.labels", current.Labels, desired.Labels)
+	changes = appendListChange(changes, "engine.registry-mirrors", current.RegistryMirror, desired.RegistryMirror)
+	changes = appendChange(changes, "engine.storage-driver", current.StorageDriver, desired.StorageDriver)
+	changes = appendChange(changes, "engine.install-url", current.InstallURL, desired.InstallURL)
+	return changes
+}
+
+func swarmChanges(current, desired *swarm.Options) []Change {
+	if current == nil {
+		current = &swarm.Options{}
+	}
+
+	changes := []Change{}
+	changes = appendBoolChange(changes, "swarm.enabled", current.IsSwarm, desired.IsSwarm)
+	if !current.IsSwarm && !desired.IsSwarm {
+		return changes
+	}
+
+	changes = appendBoolChange(changes, "swarm.master", current.Master, desired.Master)
+	changes = appendChange(changes, "swarm.discovery", current.Discovery, desired.Discovery)
+	changes = appendChange(changes, "swarm.image", current.Image, desired.Image)
+	changes = appendChange(changes, "swarm.strategy", current.Strategy, desired.Strategy)
+	changes = appendChange(changes, "swarm.host", current.Host, desired.Host)
+	changes = appendChange(changes, "swarm.addr", current.Address, desired.Address)
+	changes = appendListChange(changes, "swarm.opts", current.ArbitraryFlags, desired.ArbitraryFlags)
+	return changes
+}
+
+func appendChange(changes []Change, field, current, desired string) []Change {
+	if current == desired {
+		return changes
+	}
+	return append(changes, Change{Field: field, Current: quote(current), Desired: quote(desired)})
+}
+
+func appendBoolChange(changes []Change, field string, current, desired bool) []Change {
+	if current == desired {
+		return changes
+	}
+	return append(changes, Change{Field: field, Current: fmt.Sprint(current), Desired: fmt.Sprint(desired)})
+}
+
+func appendListChange(changes []Change, field string, current, desired []string) []Change {
+	if len(current) == 0 && len(desired) == 0 || reflect.DeepEqual(current, desired) {
+		return changes
+	}
+	return append(changes, Change{Field: field, Current: formatList(current), Desired: formatList(desired)})
+}
+
+// driverChanges compares the configuration of the driver of the machine
+// with the one the driver gets from the flags of the fleet. Only the fields
+// which the flags control are compared, the others are set by the driver
+// when the machine is created, e.g. its IP.
+func driverChanges(api libmachine.API, h *host.Host, m *Machine) ([]Change, error) {
+	rawDriver, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: h.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	desired, err := api.NewHost(m.Driver, rawDriver)
+	if err != nil {
+		return nil, err
+	}
+	defer drivers.Close(desired.Driver)
+
+	defaults, err := driverConfig(desired.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := desired.Driver.GetCreateFlags()
+	opts, err := m.driverFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := desired.Driver.SetConfigFromFlags(opts); err != nil {
+		return nil, err
+	}
+
+	wanted, err := driverConfig(desired.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := driverConfig(h.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	controlled := flagFields(desired.Driver, flags, opts, wanted)
+	for key := range wanted {
+		if !reflect.DeepEqual(wanted[key], defaults[key]) {
+			controlled[key] = true
+		}
+	}
+
+	keys := []string{}
+	for key := range controlled {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := []Change{}
+	for _, key := range keys {
+		if reflect.DeepEqual(wanted[key], current[key]) {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Field:    "driver." + key,
+			Current:  formatValue(current[key]),
+			Desired:  formatValue(wanted[key]),
+			Recreate: true,
+		})
+	}
+
+	return changes, nil
+}
+
+// flagFields returns the fields of the configuration of d which its create
+// flags control, including those a flag sets to its default. Each flag is
+// given another value in turn, and the fields which change with it are the
+// ones it controls. The flags whose other value the driver rejects are
+// skipped. wanted is the configuration of d with opts.
+func flagFields(d drivers.Driver, flags []mcnflag.Flag, opts rpcdriver.RPCFlags, wanted map[string]interface{}) map[string]bool {
+	fields := map[string]bool{}
+
+	for _, f := range flags {
+		name := f.String()
+
+		other, ok := otherValue(opts.Values[name])
+		if !ok {
+			continue
+		}
+
+		probe := rpcdriver.RPCFlags{Values: map[string]interface{}{}}
+		for key, value := range opts.Values {
+			probe.Values[key] = value
+		}
+		probe.Values[name] = other
+
+		if err := d.SetConfigFromFlags(probe); err != nil {
+			log.Debugf("Can not tell which fields flag %q controls: %s", name, err)
+			continue
+		}
+
+		config, err := driverConfig(d)
+		if err != nil {
+			continue
+		}
+
+		for key, value := range config {
+			if !reflect.DeepEqual(value, wanted[key]) {
+				fields[key] = true
+			}
+		}
+	}
+
+	return fields
+}
+
+// otherValue returns a value of the same type as value, but different.
+func otherValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return v + "-other", true
+	case int:
+		return v + 1, true
+	case bool:
+		return !v, true
+	case []string:
+		return append(append([]string{}, v...), "other"), true
+	}
+
+	return nil, false
+}
+
+func driverConfig(d drivers.Driver) (map[string]interface{}, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func formatList(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,194 @@
+package fleet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// sizedDriver is a fake driver with a create flag. It counts how many times
+// it is closed in closes, if set.
+type sizedDriver struct {
+	*fakedriver.Driver
+	Size   int
+	closes *int
+}
+
+func (d *sizedDriver) Close() error {
+	if d.closes != nil {
+		*d.closes++
+	}
+	return nil
+}
+
+func (d *sizedDriver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.IntFlag{Name: "fake-size", Value: 10},
+	}
+}
+
+func (d *sizedDriver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.Size = flags.Int("fake-size")
+	return nil
+}
+
+type fakeFleetAPI struct {
+	*libmachinetest.FakeAPI
+	closes int
+}
+
+func (api *fakeFleetAPI) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	driver := &sizedDriver{Driver: &fakedriver.Driver{}, Size: 10, closes: &api.closes}
+	if err := json.Unmarshal(rawDriver, driver); err != nil {
+		return nil, err
+	}
+
+	return &host.Host{
+		DriverName: driverName,
+		Driver:     driver,
+	}, nil
+}
+
+func (api *fakeFleetAPI) List() ([]string, error) {
+	names := []string{}
+	for _, h := range api.Hosts {
+		names = append(names, h.Name)
+	}
+	return names, nil
+}
+
+func newFleetHost(name, driverName string, size int, m *Machine) *host.Host {
+	return &host.Host{
+		Name:       name,
+		DriverName: driverName,
+		Driver:     &sizedDriver{Driver: &fakedriver.Driver{}, Size: size},
+		HostOptions: &host.Options{
+			EngineOptions: m.EngineOptions(),
+			SwarmOptions:  m.SwarmOptions(),
+		},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	unchanged := &Machine{Driver: "fake", Flags: map[string]interface{}{"fake-size": 20.0}}
+	labelled := &Machine{Driver: "fake", Engine: Engine{Labels: []string{"role=web"}}}
+	resized := &Machine{Driver: "fake", Flags: map[string]interface{}{"fake-size": 30.0}}
+	shrunk := &Machine{Driver: "fake"}
+	moved := &Machine{Driver: "other"}
+	created := &Machine{Driver: "fake"}
+
+	api := &fakeFleetAPI{
+		FakeAPI: &libmachinetest.FakeAPI{
+			Hosts: []*host.Host{
+				newFleetHost("unchanged", "fake", 20, unchanged),
+				newFleetHost("labelled", "fake", 10, &Machine{Driver: "fake"}),
+				newFleetHost("resized", "fake", 20, resized),
+				newFleetHost("shrunk", "fake", 20, shrunk),
+				newFleetHost("moved", "fake", 10, moved),
+				newFleetHost("extra", "fake", 10, created),
+			},
+		},
+	}
+
+	spec := &Spec{
+		Machines: map[string]*Machine{
+			"unchanged": unchanged,
+			"labelled":  labelled,
+			"resized":   resized,
+			"shrunk":    shrunk,
+			"moved":     moved,
+			"created":   created,
+		},
+	}
+
+	plan, err := NewPlan(api, spec, false)
+
+	assert.NoError(t, err)
+	assert.True(t, plan.HasChanges())
+	assert.Equal(t, []Action{
+		{Type: Create, Name: "created", Machine: created},
+		{Type: Drifted, Name: "labelled", Machine: labelled, Changes: []Change{
+			{Field: "engine.labels", Current: "[]", Desired: `["role=web"]`},
+		}},
+		{Type: Drifted, Name: "moved", Machine: moved, Changes: []Change{
+			{Field: "driver", Current: "fake", Desired: "other", Recreate: true},
+		}},
+		{Type: Drifted, Name: "resized", Machine: resized, Changes: []Change{
+			{Field: "driver.Size", Current: "20", Desired: "30", Recreate: true},
+		}},
+		{Type: Drifted, Name: "shrunk", Machine: shrunk, Changes: []Change{
+			{Field: "driver.Size", Current: "20", Desired: "10", Recreate: true},
+		}},
+		{Type: Unchanged, Name: "unchanged", Machine: unchanged},
+	}, plan.Actions)
+	assert.False(t, plan.Actions[1].Recreate())
+	assert.True(t, plan.Actions[2].Recreate())
+}
+
+func TestNewPlanPrune(t *testing.T) {
+	m := &Machine{Driver: "fake"}
+
+	api := &fakeFleetAPI{
+		FakeAPI: &libmachinetest.FakeAPI{
+			Hosts: []*host.Host{
+				newFleetHost("kept", "fake", 10, m),
+				newFleetHost("extra", "fake", 10, m),
+			},
+		},
+	}
+
+	spec := &Spec{
+		Machines: map[string]*Machine{
+			"kept": m,
+		},
+	}
+
+	plan, err := NewPlan(api, spec, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Action{
+		{Type: Unchanged, Name: "kept", Machine: m},
+		{Type: Remove, Name: "extra"},
+	}, plan.Actions)
+}
+
+func TestNewPlanUnchanged(t *testing.T) {
+	m := &Machine{Driver: "fake"}
+
+	api := &fakeFleetAPI{
+		FakeAPI: &libmachinetest.FakeAPI{
+			Hosts: []*host.Host{
+				newFleetHost("dev", "fake", 10, m),
+			},
+		},
+	}
+
+	plan, err := NewPlan(api, &Spec{Machines: map[string]*Machine{"dev": m}}, true)
+
+	assert.NoError(t, err)
+	assert.False(t, plan.HasChanges())
+}
+
+func TestNewPlanClosesDrivers(t *testing.T) {
+	m := &Machine{Driver: "fake"}
+
+	api := &fakeFleetAPI{
+		FakeAPI: &libmachinetest.FakeAPI{
+			Hosts: []*host.Host{
+				newFleetHost("dev", "fake", 10, m),
+			},
+		},
+	}
+	api.Hosts[0].Driver.(*sizedDriver).closes = &api.closes
+
+	_, err := NewPlan(api, &Spec{Machines: map[string]*Machine{"dev": m}}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, api.closes)
+}
@@ -0,0 +1,271 @@
+// Package fleet describes a set of machines in a file and computes the
+// changes needed to make the store match it.
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/swarm"
+)
+
+// The defaults of the options which are not given, the same as the ones of
+// the create command.
+const (
+	DefaultInstallURL    = "https://get.docker.com"
+	DefaultSwarmImage    = "swarm:latest"
+	DefaultSwarmStrategy = "spread"
+	DefaultSwarmHost     = "tcp://0.0.0.0:3376"
+)
+
+// Spec is the content of a fleet file.
+type Spec struct {
+	Machines map[string]*Machine `json:"machines"`
+}
+
+// Machine describes a machine of the fleet.
+type Machine struct {
+	Driver string `json:"driver"`
+
+	// Flags are the values of the create flags of the driver, e.g.
+	// "amazonec2-region".
+	Flags map[string]interface{} `json:"flags"`
+
+	Engine Engine `json:"engine"`
+	Swarm  Swarm  `json:"swarm"`
+}
+
+// Engine holds the options of the engine, like the --engine-* flags.
+type Engine struct {
+	Opts               []string `json:"opts"`
+	Env                []string `json:"env"`
+	InsecureRegistries []string `json:"insecure-registries"`
+	Labels             []string `json:"labels"`
+	RegistryMirrors    []string `json:"registry-mirrors"`
+	StorageDriver      string   `json:"storage-driver"`
+	InstallURL         string   `json:"install-url"`
+}
+
+// Swarm holds the options of Swarm, like the --swarm-* flags.
+type Swarm struct {
+	Enabled   bool     `json:"enabled"`
+	Master    bool     `json:"master"`
+	Discovery string   `json:"discovery"`
+	Image     string   `json:"image"`
+	Strategy  string   `json:"strategy"`
+	Host      string   `json:"host"`
+	Addr      string   `json:"addr"`
+	Opts      []string `json:"opts"`
+}
+
+// Load reads a fleet file, in JSON if its extension is .json and in YAML
+// otherwise.
+func Load(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := Parse(data, filepath.Ext(path) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", path, err)
+	}
+
+	return spec, nil
+}
+
+// Parse decodes a fleet file and validates it.
+func Parse(data []byte, isJSON bool) (*Spec, error) {
+	if !isJSON {
+		doc, err := decodeYAML(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	spec := &Spec{}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(spec); err != nil {
+		return nil, err
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+func (s *Spec) validate() error {
+	for _, name := range s.Names() {
+		if !host.ValidateHostName(name) {
+			return fmt.Errorf("%s: %q", mcnerror.ErrInvalidHostname, name)
+		}
+
+		m := s.Machines[name]
+		if m == nil {
+			return fmt.Errorf("Machine %q has no configuration", name)
+		}
+		if m.Driver == "" {
+			return fmt.Errorf("Machine %q has no driver", name)
+		}
+	}
+
+	return nil
+}
+
+// Names returns the names of the machines of the fleet in alphabetical
+// order.
+func (s *Spec) Names() []string {
+	names := []string{}
+	for name := range s.Machines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EngineOptions returns the options of the engine of the machine.
+func (m *Machine) EngineOptions() *engine.Options {
+	installURL := m.Engine.InstallURL
+	if installURL == "" {
+		installURL = DefaultInstallURL
+	}
+
+	return &engine.Options{
+		ArbitraryFlags:   nonNil(m.Engine.Opts),
+		Env:              nonNil(m.Engine.Env),
+		InsecureRegistry: nonNil(m.Engine.InsecureRegistries),
+		Labels:           nonNil(m.Engine.Labels),
+		RegistryMirror:   nonNil(m.Engine.RegistryMirrors),
+		StorageDriver:    m.Engine.StorageDriver,
+		TLSVerify:        true,
+		InstallURL:       installURL,
+	}
+}
+
+// SwarmOptions returns the Swarm options of the machine.
+func (m *Machine) SwarmOptions() *swarm.Options {
+	return &swarm.Options{
+		IsSwarm:        m.Swarm.Enabled,
+		Image:          withDefault(m.Swarm.Image, DefaultSwarmImage),
+		Master:         m.Swarm.Master,
+		Discovery:      m.Swarm.Discovery,
+		Address:        m.Swarm.Addr,
+		Host:           withDefault(m.Swarm.Host, DefaultSwarmHost),
+		Strategy:       withDefault(m.Swarm.Strategy, DefaultSwarmStrategy),
+		ArbitraryFlags: nonNil(m.Swarm.Opts),
+	}
+}
+
+// DriverOptions returns the values of the create flags of the driver, which
+// are the defaults of the flags unless the machine gives them.
+func (m *Machine) DriverOptions(flags []mcnflag.Flag) (drivers.DriverOptions, error) {
+	return m.driverFlags(flags)
+}
+
+func (m *Machine) driverFlags(flags []mcnflag.Flag) (rpcdriver.RPCFlags, error) {
+	opts := rpcdriver.RPCFlags{
+		Values: make(map[string]interface{}),
+	}
+
+	known := map[string]mcnflag.Flag{}
+	for _, f := range flags {
+		known[f.String()] = f
+
+		opts.Values[f.String()] = f.Default()
+		if f.Default() == nil {
+			opts.Values[f.String()] = false
+		}
+	}
+
+	for name, value := range m.Flags {
+		f, ok := known[name]
+		if !ok {
+			return opts, fmt.Errorf("Driver %q has no flag %q", m.Driver, name)
+		}
+
+		converted, err := convertFlag(f, value)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid value for %q: %s", name, err)
+		}
+		opts.Values[name] = converted
+	}
+
+	return opts, nil
+}
+
+// convertFlag converts a value decoded from JSON to the type of the flag.
+func convertFlag(f mcnflag.Flag, value interface{}) (interface{}, error) {
+	switch f.(type) {
+	case mcnflag.IntFlag, *mcnflag.IntFlag:
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return nil, fmt.Errorf("expected an integer, got %v", value)
+		}
+		return int(n), nil
+	case mcnflag.BoolFlag, *mcnflag.BoolFlag:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected true or false, got %v", value)
+		}
+		return b, nil
+	case mcnflag.StringSliceFlag, *mcnflag.StringSliceFlag:
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		values := []string{}
+		for _, item := range items {
+			s, err := scalarString(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+
+	return scalarString(value)
+}
+
+func scalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	return "", fmt.Errorf("expected a string, got %v", value)
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
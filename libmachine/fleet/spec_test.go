@@ -0,0 +1,147 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+const fleetYAML = `
+# Two machines
+machines:
+  web:
+    driver: virtualbox
+    flags:
+      virtualbox-memory: 2048
+      virtualbox-no-share: true
+    engine:
+      labels: [role=web, "env=prod"]
+      env:
+        - HTTP_PROXY=http://proxy:3128
+  manager:
+    driver: amazonec2
+    flags:
+      amazonec2-region: 'eu-west-1'
+    swarm:
+      enabled: true
+      master: true
+      discovery: token://1234
+`
+
+func TestParseYAML(t *testing.T) {
+	spec, err := Parse([]byte(fleetYAML), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"manager", "web"}, spec.Names())
+
+	web := spec.Machines["web"]
+	assert.Equal(t, "virtualbox", web.Driver)
+	assert.Equal(t, map[string]interface{}{"virtualbox-memory": 2048.0, "virtualbox-no-share": true}, web.Flags)
+	assert.Equal(t, []string{"role=web", "env=prod"}, web.Engine.Labels)
+	assert.Equal(t, []string{"HTTP_PROXY=http://proxy:3128"}, web.Engine.Env)
+
+	manager := spec.Machines["manager"]
+	assert.Equal(t, "amazonec2", manager.Driver)
+	assert.Equal(t, "eu-west-1", manager.Flags["amazonec2-region"])
+	assert.True(t, manager.Swarm.Enabled)
+	assert.True(t, manager.Swarm.Master)
+	assert.Equal(t, "token://1234", manager.Swarm.Discovery)
+}
+
+func TestParseYAMLAnchors(t *testing.T) {
+	spec, err := Parse([]byte("base: &base\n  driver: none\n  flags: {url: tcp://1.2.3.4:2376}\nmachines:\n  a: *base\n  b:\n    <<: *base\n    engine: {storage-driver: overlay}\n"), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, spec.Names())
+	assert.Equal(t, "none", spec.Machines["a"].Driver)
+	assert.Equal(t, "none", spec.Machines["b"].Driver)
+	assert.Equal(t, "tcp://1.2.3.4:2376", spec.Machines["b"].Flags["url"])
+	assert.Equal(t, "overlay", spec.Machines["b"].Engine.StorageDriver)
+}
+
+func TestParseJSON(t *testing.T) {
+	spec, err := Parse([]byte(`{"machines": {"dev": {"driver": "none", "flags": {"url": "tcp://1.2.3.4:2376"}}}}`), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev"}, spec.Names())
+	assert.Equal(t, "none", spec.Machines["dev"].Driver)
+}
+
+func TestParseInvalid(t *testing.T) {
+	var tests = []struct {
+		description string
+		data        string
+		expected    string
+	}{
+		{"missing driver", "machines:\n  dev:\n    flags:\n      url: x\n", `Machine "dev" has no driver`},
+		{"invalid name", "machines:\n  dev_1:\n    driver: none\n", `Invalid hostname specified. Allowed hostname chars are: 0-9a-zA-Z . -: "dev_1"`},
+		{"bad indentation", "machines:\n  dev:\n    driver: none\n   flags: {}\n", "yaml: line 3: did not find expected key"},
+		{"duplicate key", "machines:\n  dev:\n    driver: none\n    driver: virtualbox\n", "yaml: unmarshal errors:\n  line 4: key \"driver\" already set in map"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.data), false)
+
+		assert.EqualError(t, err, test.expected, test.description)
+	}
+}
+
+func TestMachineDefaults(t *testing.T) {
+	m := &Machine{Driver: "none"}
+
+	engineOptions := m.EngineOptions()
+	assert.Equal(t, DefaultInstallURL, engineOptions.InstallURL)
+	assert.True(t, engineOptions.TLSVerify)
+	assert.Equal(t, []string{}, engineOptions.Labels)
+
+	swarmOptions := m.SwarmOptions()
+	assert.False(t, swarmOptions.IsSwarm)
+	assert.Equal(t, DefaultSwarmImage, swarmOptions.Image)
+	assert.Equal(t, DefaultSwarmStrategy, swarmOptions.Strategy)
+	assert.Equal(t, DefaultSwarmHost, swarmOptions.Host)
+}
+
+var testFlags = []mcnflag.Flag{
+	mcnflag.IntFlag{Name: "fake-memory", Value: 1024},
+	mcnflag.BoolFlag{Name: "fake-no-share"},
+	mcnflag.StringFlag{Name: "fake-region", Value: "us-east-1"},
+	mcnflag.StringSliceFlag{Name: "fake-tags"},
+}
+
+func TestDriverOptions(t *testing.T) {
+	m := &Machine{
+		Driver: "fake",
+		Flags: map[string]interface{}{
+			"fake-memory": 2048.0,
+			"fake-tags":   []interface{}{"a", 1.5},
+		},
+	}
+
+	opts, err := m.DriverOptions(testFlags)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, opts.Int("fake-memory"))
+	assert.False(t, opts.Bool("fake-no-share"))
+	assert.Equal(t, "us-east-1", opts.String("fake-region"))
+	assert.Equal(t, []string{"a", "1.5"}, opts.StringSlice("fake-tags"))
+}
+
+func TestDriverOptionsInvalid(t *testing.T) {
+	var tests = []struct {
+		flags    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"fake-cpus": 2.0}, `Driver "fake" has no flag "fake-cpus"`},
+		{map[string]interface{}{"fake-memory": "lots"}, `Invalid value for "fake-memory": expected an integer, got lots`},
+		{map[string]interface{}{"fake-no-share": "yes"}, `Invalid value for "fake-no-share": expected true or false, got yes`},
+	}
+
+	for _, test := range tests {
+		m := &Machine{Driver: "fake", Flags: test.flags}
+
+		_, err := m.DriverOptions(testFlags)
+
+		assert.EqualError(t, err, test.expected)
+	}
+}
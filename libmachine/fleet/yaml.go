@@ -0,0 +1,38 @@
+package fleet
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// decodeYAML returns the document as the values encoding/json produces once
+// marshalled, i.e. with string keys. Duplicate keys are refused.
+func decodeYAML(data []byte) (interface{}, error) {
+	var doc interface{}
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return withStringKeys(doc), nil
+}
+
+// withStringKeys converts the mappings decoded by yaml, whose keys may be of
+// any type, to mappings with string keys which encoding/json can marshal.
+func withStringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = withStringKeys(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = withStringKeys(item)
+		}
+		return v
+	}
+
+	return value
+}
@@ -0,0 +1,263 @@
+// Package hook describes the commands run before and after the lifecycle
+// operations of a machine, e.g. to register it in a DNS once it is created.
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Event is the point of the lifecycle of a machine a hook runs at.
+type Event string
+
+const (
+	PreCreate  Event = "pre-create"
+	PostCreate Event = "post-create"
+	PreStart   Event = "pre-start"
+	PostStart  Event = "post-start"
+	PreStop    Event = "pre-stop"
+	PostStop   Event = "post-stop"
+	PreRemove  Event = "pre-rm"
+	PostRemove Event = "post-rm"
+
+	// Unhealthy and Recovered are run by the watcher when a machine goes
+	// down or its Docker daemon can not be reached, and once it is healthy
+	// again.
+	Unhealthy Event = "unhealthy"
+	Recovered Event = "recovered"
+)
+
+// Events are all the events, in the order of the lifecycle.
+var Events = []Event{PreCreate, PostCreate, PreStart, PostStart, PreStop, PostStop, PreRemove, PostRemove, Unhealthy, Recovered}
+
+// MachineRunning returns true if the machine is running at the event, so
+// that scripts can be run on it over SSH.
+func (e Event) MachineRunning() bool {
+	switch e {
+	case PostCreate, PostStart, PreStop, PreRemove, Recovered:
+		return true
+	}
+	return false
+}
+
+// Policy is what happens when a hook fails.
+type Policy string
+
+const (
+	// Abort fails the operation. A failing pre hook prevents the operation
+	// from being run and the following hooks from running; a failing post
+	// hook is reported as the error of the operation, which already ran.
+	Abort Policy = "abort"
+
+	// Warn logs the failure and carries on.
+	Warn Policy = "warn"
+
+	// Ignore only logs the failure in debug mode.
+	Ignore Policy = "ignore"
+)
+
+// Hook is a command run at an event. Either Command or Script is set.
+type Hook struct {
+	Event Event
+
+	// Command is run on the local host by the shell.
+	Command string `json:",omitempty"`
+
+	// Script is run on the machine over SSH.
+	Script string `json:",omitempty"`
+
+	// OnFailure is the policy applied if the hook fails, Abort if empty.
+	OnFailure Policy `json:",omitempty"`
+
+	// Timeout is the number of seconds the hook may run for, zero for no
+	// limit.
+	Timeout int `json:",omitempty"`
+}
+
+// Options are the hooks of a machine.
+type Options struct {
+	Hooks []Hook
+}
+
+// Remote returns true if the hook is run on the machine.
+func (h Hook) Remote() bool {
+	return h.Script != ""
+}
+
+// Policy returns the failure policy of the hook.
+func (h Hook) Policy() Policy {
+	if h.OnFailure == "" {
+		return Abort
+	}
+	return h.OnFailure
+}
+
+func (h Hook) String() string {
+	if h.Remote() {
+		return fmt.Sprintf("%s script %q", h.Event, h.Script)
+	}
+	return fmt.Sprintf("%s command %q", h.Event, h.Command)
+}
+
+// Validate checks that the hook can be run.
+func (h Hook) Validate() error {
+	if !isEvent(h.Event) {
+		return fmt.Errorf("Unknown hook event %q, expected one of %s", h.Event, eventNames())
+	}
+
+	if (h.Command == "") == (h.Script == "") {
+		return fmt.Errorf("A %s hook needs either a command or a script", h.Event)
+	}
+
+	if h.Remote() && !h.Event.MachineRunning() {
+		return fmt.Errorf("The machine is not running at %s, a script can not be run on it", h.Event)
+	}
+
+	switch h.Policy() {
+	case Abort, Warn, Ignore:
+	default:
+		return fmt.Errorf("Unknown hook failure policy %q, expected abort, warn or ignore", h.OnFailure)
+	}
+
+	if h.Timeout < 0 {
+		return fmt.Errorf("The timeout of a hook can not be negative")
+	}
+
+	return nil
+}
+
+// Validate checks every hook.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	for _, h := range o.Hooks {
+		if err := h.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// For returns the hooks of the event, in the order they were given.
+func (o *Options) For(e Event) []Hook {
+	if o == nil {
+		return nil
+	}
+
+	hooks := []Hook{}
+	for _, h := range o.Hooks {
+		if h.Event == e {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// Parse parses a hook given as "EVENT=COMMAND", or "EVENT=SCRIPT" if
+// remote is true.
+func Parse(value string, remote bool, policy Policy) (Hook, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return Hook{}, fmt.Errorf("Invalid hook %q, expected EVENT=COMMAND", value)
+	}
+
+	h := Hook{
+		Event:     Event(parts[0]),
+		OnFailure: policy,
+	}
+	if remote {
+		h.Script = parts[1]
+	} else {
+		h.Command = parts[1]
+	}
+
+	return h, h.Validate()
+}
+
+// Env returns the environment of a hook as NAME=value pairs sorted by name.
+func Env(vars map[string]string) []string {
+	env := []string{}
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// RunLocal runs command with the shell of the local host, adding env to its
+// environment, and returns its combined output. The shell is killed if ctx
+// is done first.
+func RunLocal(ctx context.Context, command string, env []string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = append(os.Environ(), env...)
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return output.String(), err
+	case <-ctx.Done():
+		// The children of the shell may keep its output open, so its
+		// output is not waited for.
+		cmd.Process.Kill()
+		return "", ctx.Err()
+	}
+}
+
+// RemoteScript returns script prefixed with the export of env, to be run
+// on the machine.
+func RemoteScript(script string, env []string) string {
+	var buf bytes.Buffer
+	for _, pair := range env {
+		parts := strings.SplitN(pair, "=", 2)
+		fmt.Fprintf(&buf, "export %s=%s\n", parts[0], shellQuote(parts[1]))
+	}
+	buf.WriteString(script)
+	return buf.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func isEvent(e Event) bool {
+	for _, known := range Events {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+func eventNames() string {
+	names := []string{}
+	for _, e := range Events {
+		names = append(names, string(e))
+	}
+	return strings.Join(names, ", ")
+}
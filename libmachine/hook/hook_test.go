@@ -0,0 +1,87 @@
+package hook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	h, err := Parse("post-create=./register-dns.sh --add", false, Warn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Hook{Event: PostCreate, Command: "./register-dns.sh --add", OnFailure: Warn}, h)
+	assert.False(t, h.Remote())
+}
+
+func TestParseRemote(t *testing.T) {
+	h, err := Parse("post-start=sudo systemctl start agent", true, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Hook{Event: PostStart, Script: "sudo systemctl start agent"}, h)
+	assert.True(t, h.Remote())
+	assert.Equal(t, Abort, h.Policy())
+}
+
+func TestParseInvalid(t *testing.T) {
+	var tests = []struct {
+		value    string
+		remote   bool
+		policy   Policy
+		expected string
+	}{
+		{"post-create", false, "", `Invalid hook "post-create", expected EVENT=COMMAND`},
+		{"post-create=", false, "", `Invalid hook "post-create=", expected EVENT=COMMAND`},
+		{"after-create=true", false, "", `Unknown hook event "after-create", expected one of pre-create, post-create, pre-start, post-start, pre-stop, post-stop, pre-rm, post-rm, unhealthy, recovered`},
+		{"pre-create=true", true, "", "The machine is not running at pre-create, a script can not be run on it"},
+		{"post-rm=true", true, "", "The machine is not running at post-rm, a script can not be run on it"},
+		{"post-rm=true", false, "retry", `Unknown hook failure policy "retry", expected abort, warn or ignore`},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.value, test.remote, test.policy)
+
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestValidateCommandAndScript(t *testing.T) {
+	h := Hook{Event: PostStart, Command: "true", Script: "true"}
+
+	assert.EqualError(t, h.Validate(), "A post-start hook needs either a command or a script")
+}
+
+func TestFor(t *testing.T) {
+	opts := &Options{
+		Hooks: []Hook{
+			{Event: PostCreate, Command: "first"},
+			{Event: PreStop, Command: "other"},
+			{Event: PostCreate, Script: "second"},
+		},
+	}
+
+	assert.Equal(t, []Hook{
+		{Event: PostCreate, Command: "first"},
+		{Event: PostCreate, Script: "second"},
+	}, opts.For(PostCreate))
+	assert.Empty(t, opts.For(PreStart))
+
+	var none *Options
+	assert.Empty(t, none.For(PostCreate))
+	assert.NoError(t, none.Validate())
+}
+
+func TestEnv(t *testing.T) {
+	env := Env(map[string]string{
+		"MACHINE_NAME": "dev",
+		"MACHINE_IP":   "1.2.3.4",
+	})
+
+	assert.Equal(t, []string{"MACHINE_IP=1.2.3.4", "MACHINE_NAME=dev"}, env)
+}
+
+func TestRemoteScript(t *testing.T) {
+	script := RemoteScript("echo $MACHINE_NAME", []string{"MACHINE_NAME=it's"})
+
+	assert.Equal(t, "export MACHINE_NAME='it'\\''s'\necho $MACHINE_NAME", script)
+}
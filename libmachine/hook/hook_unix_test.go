@@ -0,0 +1,34 @@
+// +build !windows
+
+package hook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestRunLocal(t *testing.T) {
+	output, err := RunLocal(context.Background(), "echo $MACHINE_NAME", []string{"MACHINE_NAME=dev"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "dev\n", output)
+}
+
+func TestRunLocalFailure(t *testing.T) {
+	output, err := RunLocal(context.Background(), "echo failed >&2; exit 3", nil)
+
+	assert.EqualError(t, err, "exit status 3")
+	assert.Equal(t, "failed\n", output)
+}
+
+func TestRunLocalTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := RunLocal(ctx, "sleep 10", nil)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
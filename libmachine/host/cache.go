@@ -0,0 +1,70 @@
+package host
+
+import (
+	"time"
+
+	"github.com/docker/machine/libmachine/state"
+)
+
+// CachedState is the state of a machine as last observed, which lets it be
+// listed without asking its driver.
+type CachedState struct {
+	State         state.State
+	IP            string `json:",omitempty"`
+	URL           string `json:",omitempty"`
+	DockerVersion string `json:",omitempty"`
+	Error         string `json:",omitempty"`
+	Time          time.Time
+
+	// StopRequested is true once a command stopped the machine on purpose,
+	// so that the watcher does not take it for down, until it is started
+	// again.
+	StopRequested bool `json:",omitempty"`
+}
+
+// Age returns how long ago the state was observed.
+func (c *CachedState) Age() time.Duration {
+	return time.Since(c.Time)
+}
+
+// SetCachedState records the state of the machine, e.g. once a lifecycle
+// operation completed. The IP and URL are asked to the driver if the machine
+// is running. The Docker version is kept from the previous observation, as
+// getting it requires to connect to the daemon.
+func (h *Host) SetCachedState(s state.State) {
+	cached := &CachedState{
+		State: s,
+		Time:  time.Now(),
+	}
+
+	if s == state.Running {
+		if ip, err := h.Driver.GetIP(); err == nil {
+			cached.IP = ip
+		}
+		if url, err := h.URL(); err == nil {
+			cached.URL = url
+		}
+		if h.CachedState != nil {
+			cached.DockerVersion = h.CachedState.DockerVersion
+		}
+	}
+
+	cached.StopRequested = h.StopRequested()
+	h.CachedState = cached
+}
+
+// RequestStop records that the machine is being stopped on purpose. Callers
+// save the machine before stopping it, so that a watcher loading it in the
+// meantime knows.
+func (h *Host) RequestStop() {
+	if h.CachedState == nil {
+		h.CachedState = &CachedState{State: state.None, Time: time.Now()}
+	}
+	h.CachedState.StopRequested = true
+}
+
+// StopRequested returns true if the machine was stopped on purpose and not
+// started since.
+func (h *Host) StopRequested() bool {
+	return h.CachedState != nil && h.CachedState.StopRequested
+}
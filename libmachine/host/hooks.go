@@ -0,0 +1,133 @@
+package host
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/net/context"
+)
+
+// Hooks returns the hooks of the machine.
+func (h *Host) Hooks() *hook.Options {
+	if h.HostOptions == nil {
+		return nil
+	}
+	return h.HostOptions.HookOptions
+}
+
+// RunHooks runs the hooks of the machine for the event in the order they
+// were given. It stops at the first failing hook whose policy is to abort
+// and returns its error; the failures of the other hooks are only logged.
+func (h *Host) RunHooks(ctx context.Context, e hook.Event) error {
+	return h.RunHooksWithVars(ctx, e, nil)
+}
+
+// RunHooksWithVars is like RunHooks but adds vars to the environment of the
+// hooks.
+func (h *Host) RunHooksWithVars(ctx context.Context, e hook.Event, vars map[string]string) error {
+	hooks := h.Hooks().For(e)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	allVars := h.hookVars(e)
+	for name, value := range vars {
+		allVars[name] = value
+	}
+	env := hook.Env(allVars)
+
+	for _, hk := range hooks {
+		err := h.runHook(ctx, hk, env)
+		if err == nil {
+			continue
+		}
+
+		switch hk.Policy() {
+		case hook.Warn:
+			log.Warnf("Error running %s hook of %q: %s", e, h.Name, err)
+		case hook.Ignore:
+			log.Debugf("Error running %s hook of %q: %s", e, h.Name, err)
+		default:
+			return fmt.Errorf("Error running %s hook of %q: %s", e, h.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// withHooks runs action between the pre and post hooks. The post hooks are
+// not run if action failed.
+func (h *Host) withHooks(ctx context.Context, pre, post hook.Event, action func() error) error {
+	if err := h.RunHooks(ctx, pre); err != nil {
+		return err
+	}
+
+	if err := action(); err != nil {
+		return err
+	}
+
+	return h.RunHooks(ctx, post)
+}
+
+func (h *Host) runHook(ctx context.Context, hk hook.Hook, env []string) error {
+	if hk.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(hk.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	op := h.events().BeginWithDetail(event.Hook, h.Name, h.DriverName, string(hk.Event))
+
+	var (
+		output string
+		err    error
+	)
+	if hk.Remote() {
+		err = mcnutils.RunWithContext(ctx, func() error {
+			var err error
+			output, err = h.RunSSHCommand(hook.RemoteScript(hk.Script, env))
+			return err
+		})
+	} else {
+		output, err = hook.RunLocal(ctx, hk.Command, env)
+	}
+
+	output = strings.TrimSpace(output)
+	if output != "" {
+		log.Debugf("(%s) %s hook output:\n%s", h.Name, hk.Event, output)
+	}
+
+	if err != nil && output != "" && ctx.Err() == nil {
+		err = fmt.Errorf("%s: %s", err, output)
+	}
+
+	return op.End(err)
+}
+
+// hookVars are the environment variables given to the hooks. The IP and
+// URL of the machine are only given when it is running.
+func (h *Host) hookVars(e hook.Event) map[string]string {
+	vars := map[string]string{
+		"MACHINE_NAME":   h.Name,
+		"MACHINE_DRIVER": h.DriverName,
+		"MACHINE_HOOK":   string(e),
+	}
+
+	if !e.MachineRunning() {
+		return vars
+	}
+
+	if ip, err := h.Driver.GetIP(); err == nil {
+		vars["MACHINE_IP"] = ip
+	}
+	if url, err := h.URL(); err == nil {
+		vars["MACHINE_URL"] = url
+	}
+
+	return vars
+}
@@ -0,0 +1,79 @@
+// +build !windows
+
+package host
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHookedHost(hooks ...hook.Hook) *Host {
+	return &Host{
+		Name:       "foo",
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: state.Stopped,
+			MockIP:    "1.2.3.4",
+		},
+		HostOptions: &Options{
+			HookOptions: &hook.Options{Hooks: hooks},
+		},
+	}
+}
+
+func TestStartRunsHooks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "machine-test-")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	h := newHookedHost(
+		hook.Hook{Event: hook.PreStart, Command: `echo "$MACHINE_HOOK $MACHINE_NAME $MACHINE_DRIVER [$MACHINE_IP]" >> ` + out},
+		hook.Hook{Event: hook.PostStart, Command: `echo "$MACHINE_HOOK $MACHINE_IP $MACHINE_URL" >> ` + out},
+		hook.Hook{Event: hook.PreStop, Command: `echo "$MACHINE_HOOK" >> ` + out},
+	)
+
+	assert.NoError(t, h.Start())
+
+	content, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "pre-start foo fakedriver []\npost-start 1.2.3.4 tcp://1.2.3.4:2376\n", string(content))
+}
+
+func TestFailingPreHookAbortsStop(t *testing.T) {
+	h := newHookedHost(hook.Hook{Event: hook.PreStop, Command: "echo unreachable; exit 1"})
+	h.Driver.(*fakedriver.Driver).MockState = state.Running
+
+	err := h.Stop()
+
+	assert.EqualError(t, err, `Error running pre-stop hook of "foo": exit status 1: unreachable`)
+	assert.Equal(t, state.Running, h.Driver.(*fakedriver.Driver).MockState)
+}
+
+func TestFailingHookWithWarnPolicy(t *testing.T) {
+	h := newHookedHost(
+		hook.Hook{Event: hook.PreStart, Command: "exit 1", OnFailure: hook.Warn},
+		hook.Hook{Event: hook.PostStart, Command: "exit 1", OnFailure: hook.Ignore},
+	)
+
+	assert.NoError(t, h.Start())
+	assert.Equal(t, state.Running, h.Driver.(*fakedriver.Driver).MockState)
+}
+
+func TestRemoveRunsHooks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "machine-test-")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	h := newHookedHost(hook.Hook{Event: hook.PostRemove, Command: `echo "$MACHINE_HOOK $MACHINE_NAME" >> ` + out})
+
+	assert.NoError(t, h.Remove())
+
+	content, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "post-rm foo\n", string(content))
+}
@@ -10,7 +10,10 @@ import (
 	"github.com/docker/machine/libmachine/crashreport"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
@@ -18,6 +21,7 @@ import (
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -45,6 +49,20 @@ type Host struct {
 	HostOptions   *Options
 	Name          string
 	RawDriver     []byte `json:"-"`
+
+	// CreateStage is the last stage of the creation process which
+	// completed successfully. It is empty once the machine is fully
+	// created, which lets a failed creation be resumed later on.
+	CreateStage event.Stage `json:",omitempty"`
+
+	// CachedState is the state of the machine observed by the last command
+	// which asked for it.
+	CachedState *CachedState `json:",omitempty"`
+
+	// Events receives the lifecycle events of the machine. It is set by the
+	// libmachine Client the machine was loaded with, and the events go to
+	// the default dispatcher otherwise.
+	Events *event.Dispatcher `json:"-"`
 }
 
 type Options struct {
@@ -54,6 +72,39 @@ type Options struct {
 	EngineOptions *engine.Options
 	SwarmOptions  *swarm.Options
 	AuthOptions   *auth.Options
+	HookOptions   *hook.Options `json:",omitempty"`
+	RestartPolicy RestartPolicy `json:",omitempty"`
+	ReapPolicy    *ReapPolicy   `json:",omitempty"`
+}
+
+// RestartPolicy is how a machine found unhealthy by a watcher is recovered.
+type RestartPolicy string
+
+const (
+	// RestartNo only notifies that the machine is unhealthy, through the
+	// events and the unhealthy hooks.
+	RestartNo RestartPolicy = "no"
+
+	// RestartMachine starts the machine again if it went down, and restarts
+	// it if its Docker daemon can not be reached.
+	RestartMachine RestartPolicy = "machine"
+
+	// RestartDocker restarts the Docker daemon of a running machine which
+	// can not be reached.
+	RestartDocker RestartPolicy = "docker"
+)
+
+// ParseRestartPolicy checks that the policy is known. The empty policy is
+// RestartNo.
+func ParseRestartPolicy(policy string) (RestartPolicy, error) {
+	switch RestartPolicy(policy) {
+	case "":
+		return RestartNo, nil
+	case RestartNo, RestartMachine, RestartDocker:
+		return RestartPolicy(policy), nil
+	}
+
+	return "", fmt.Errorf("Unknown restart policy %q, expected no, machine or docker", policy)
 }
 
 type Metadata struct {
@@ -66,6 +117,25 @@ func ValidateHostName(name string) bool {
 	return validHostNamePattern.MatchString(name)
 }
 
+// events returns the dispatcher the events of the machine are published to.
+func (h *Host) events() *event.Dispatcher {
+	if h.Events != nil {
+		return h.Events
+	}
+	return event.Default()
+}
+
+// Publish publishes an event about the machine, e.g. a change of its health.
+func (h *Host) Publish(e event.Event) {
+	h.events().Publish(e)
+}
+
+// IsPartiallyCreated returns whether the creation of the machine failed or
+// was interrupted before completing.
+func (h *Host) IsPartiallyCreated() bool {
+	return h.CreateStage != ""
+}
+
 func (h *Host) RunSSHCommand(command string) (string, error) {
 	return drivers.RunSSHCommandFromDriver(h.Driver, command)
 }
@@ -93,59 +163,280 @@ func (creator *StandardSSHClientCreator) CreateSSHClient(d drivers.Driver) (ssh.
 	return ssh.NewClient(d.GetSSHUsername(), addr, port, auth)
 }
 
-func (h *Host) runActionForState(action func() error, desiredState state.State) error {
+func (h *Host) runActionForState(ctx context.Context, action func(context.Context, drivers.Driver) error, desiredState state.State) error {
 	if drivers.MachineInState(h.Driver, desiredState)() {
 		return fmt.Errorf("Machine %q is already %s.", h.Name, strings.ToLower(desiredState.String()))
 	}
 
-	if err := action(); err != nil {
+	if err := action(ctx, h.Driver); err != nil {
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(h.Driver, desiredState))
+	if err := mcnutils.WaitForContext(ctx, drivers.MachineInState(h.Driver, desiredState)); err != nil {
+		return err
+	}
+
+	h.SetCachedState(desiredState)
+
+	return nil
 }
 
 func (h *Host) Start() error {
-	log.Infof("Starting %q...", h.Name)
-	if err := h.runActionForState(h.Driver.Start, state.Running); err != nil {
+	return h.StartContext(context.Background())
+}
+
+// StartContext starts the machine and waits for it to be running, giving up
+// when ctx is cancelled or its deadline expires. A paused machine is
+// unpaused, and the driver resumes a suspended one.
+func (h *Host) StartContext(ctx context.Context) error {
+	return h.withHooks(ctx, hook.PreStart, hook.PostStart, func() error {
+		op := h.events().Begin(event.Start, h.Name, h.DriverName)
+
+		if h.CachedState != nil {
+			h.CachedState.StopRequested = false
+		}
+
+		if drivers.MachineInState(h.Driver, state.Paused)() {
+			return op.End(h.runActionForState(ctx, drivers.UnpauseWithContext, state.Running))
+		}
+
+		err := h.runActionForState(ctx, drivers.StartWithContext, state.Running)
+		if err == nil {
+			h.recordStart()
+		}
+		return op.End(err)
+	})
+}
+
+func (h *Host) Stop() error {
+	return h.StopContext(context.Background())
+}
+
+func (h *Host) StopContext(ctx context.Context) error {
+	return h.withHooks(ctx, hook.PreStop, hook.PostStop, func() error {
+		op := h.events().Begin(event.Stop, h.Name, h.DriverName)
+		h.RequestStop()
+		return op.End(h.runActionForState(ctx, drivers.StopWithContext, state.Stopped))
+	})
+}
+
+func (h *Host) Remove() error {
+	return h.RemoveContext(context.Background())
+}
+
+// RemoveContext removes the machine from its provider, running its pre-rm
+// and post-rm hooks. The machine must be removed from the store afterwards.
+func (h *Host) RemoveContext(ctx context.Context) error {
+	return h.withHooks(ctx, hook.PreRemove, hook.PostRemove, func() error {
+		return drivers.RemoveWithContext(ctx, h.Driver)
+	})
+}
+
+func (h *Host) Kill() error {
+	return h.KillContext(context.Background())
+}
+
+func (h *Host) KillContext(ctx context.Context) error {
+	op := h.events().Begin(event.Kill, h.Name, h.DriverName)
+	h.RequestStop()
+	return op.End(h.runActionForState(ctx, drivers.KillWithContext, state.Stopped))
+}
+
+func (h *Host) Restart() error {
+	return h.RestartContext(context.Background())
+}
+
+func (h *Host) RestartContext(ctx context.Context) error {
+	if drivers.MachineInState(h.Driver, state.Stopped)() || drivers.MachineInState(h.Driver, state.Saved)() {
+		return h.StartContext(ctx)
+	}
+
+	op := h.events().Begin(event.Restart, h.Name, h.DriverName)
+
+	if drivers.MachineInState(h.Driver, state.Running)() {
+		if err := drivers.RestartWithContext(ctx, h.Driver); err != nil {
+			return op.End(err)
+		}
+		if err := mcnutils.WaitForContext(ctx, drivers.MachineInState(h.Driver, state.Running)); err != nil {
+			return op.End(err)
+		}
+		h.SetCachedState(state.Running)
+	}
+
+	return op.End(nil)
+}
+
+func (h *Host) Pause() error {
+	return h.PauseContext(context.Background())
+}
+
+// PauseContext freezes the machine if its driver is able to.
+func (h *Host) PauseContext(ctx context.Context) error {
+	op := h.events().Begin(event.Pause, h.Name, h.DriverName)
+	return op.End(h.runActionForState(ctx, drivers.PauseWithContext, state.Paused))
+}
+
+func (h *Host) Unpause() error {
+	return h.UnpauseContext(context.Background())
+}
+
+func (h *Host) UnpauseContext(ctx context.Context) error {
+	op := h.events().Begin(event.Unpause, h.Name, h.DriverName)
+
+	if !drivers.MachineInState(h.Driver, state.Paused)() {
+		return op.End(fmt.Errorf("Machine %q is not paused.", h.Name))
+	}
+
+	return op.End(h.runActionForState(ctx, drivers.UnpauseWithContext, state.Running))
+}
+
+func (h *Host) Suspend() error {
+	return h.SuspendContext(context.Background())
+}
+
+// SuspendContext saves the state of the machine to disk and stops it if its
+// driver is able to. Start resumes it.
+func (h *Host) SuspendContext(ctx context.Context) error {
+	op := h.events().Begin(event.Suspend, h.Name, h.DriverName)
+	return op.End(h.runActionForState(ctx, drivers.SuspendWithContext, state.Saved))
+}
+
+func (h *Host) Resize(opts drivers.ResizeOptions) error {
+	return h.ResizeContext(context.Background(), opts)
+}
+
+// ResizeContext changes the resources of the machine if its driver is able
+// to. A running machine is stopped first and started again afterwards.
+func (h *Host) ResizeContext(ctx context.Context, opts drivers.ResizeOptions) error {
+	if _, err := drivers.GetResizer(h.Driver); err != nil {
 		return err
 	}
 
-	log.Infof("Machine %q was started.", h.Name)
-	return nil
+	return h.runStopped(ctx, func() error {
+		op := h.events().Begin(event.Resize, h.Name, h.DriverName)
+		return op.End(drivers.ResizeWithContext(ctx, h.Driver, opts))
+	})
 }
 
-func (h *Host) Stop() error {
-	log.Infof("Stopping %q...", h.Name)
-	if err := h.runActionForState(h.Driver.Stop, state.Stopped); err != nil {
+// CloneContext creates the machine name as a copy of this one if its driver
+// is able to, and returns the configuration of the driver of the copy. A
+// running machine is stopped first and started again afterwards.
+func (h *Host) CloneContext(ctx context.Context, name string) ([]byte, error) {
+	if _, err := drivers.GetCloner(h.Driver); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err := h.runStopped(ctx, func() error {
+		op := h.events().Begin(event.Clone, h.Name, h.DriverName)
+
+		var err error
+		data, err = drivers.CloneWithContext(ctx, h.Driver, name)
+		return op.End(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// RenameContext renames the machine if its driver is able to. A running
+// machine is stopped first and started again afterwards. moveDir moves the
+// store directory of the machine, under the locks of the store, once the
+// driver released it. The machine must be saved under its new name
+// afterwards.
+func (h *Host) RenameContext(ctx context.Context, name string, moveDir func(oldName, newName string) error) error {
+	r, err := drivers.GetRenamer(h.Driver)
+	if err != nil {
 		return err
 	}
 
-	log.Infof("Machine %q was stopped.", h.Name)
-	return nil
+	return h.runStopped(ctx, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		op := h.events().Begin(event.Rename, h.Name, h.DriverName)
+		if err := op.End(h.rename(r, name, moveDir)); err != nil {
+			return err
+		}
+
+		h.Name = name
+		return nil
+	})
 }
 
-func (h *Host) Kill() error {
-	log.Infof("Killing %q...", h.Name)
-	if err := h.runActionForState(h.Driver.Kill, state.Stopped); err != nil {
+// rename moves the store directory of the machine and renames it at the
+// provider, moving the directory back if the driver fails to. The driver is
+// not interrupted once started, so that it can roll back its own changes.
+func (h *Host) rename(r drivers.Renamer, name string, moveDir func(oldName, newName string) error) error {
+	if err := drivers.ReleaseStoreDir(h.Driver); err != nil {
 		return err
 	}
 
-	log.Infof("Machine %q was killed.", h.Name)
-	return nil
+	if err := moveDir(h.Name, name); err != nil {
+		if useErr := drivers.UseStoreDir(h.Driver); useErr != nil {
+			log.Warnf("Error restoring machine %q: %s", h.Name, useErr)
+		}
+		return err
+	}
+
+	renameErr := r.Rename(name)
+	if renameErr == nil {
+		return nil
+	}
+
+	if err := moveDir(name, h.Name); err != nil {
+		log.Warnf("Error moving the directory of machine %q back: %s", h.Name, err)
+		return renameErr
+	}
+	if err := drivers.UseStoreDir(h.Driver); err != nil {
+		log.Warnf("Error restoring machine %q: %s", h.Name, err)
+	}
+
+	return renameErr
 }
 
-func (h *Host) Restart() error {
-	log.Infof("Restarting %q...", h.Name)
-	if drivers.MachineInState(h.Driver, state.Stopped)() {
-		return h.Start()
+// runStopped runs action while the machine is stopped. A running machine is
+// stopped first and started again afterwards, even if action failed.
+func (h *Host) runStopped(ctx context.Context, action func() error) error {
+	if err := h.CheckNotPaused(); err != nil {
+		return err
 	}
 
-	if drivers.MachineInState(h.Driver, state.Running)() {
-		if err := h.Driver.Restart(); err != nil {
+	wasRunning := drivers.MachineInState(h.Driver, state.Running)()
+	if wasRunning {
+		if err := h.StopContext(ctx); err != nil {
+			return err
+		}
+	}
+
+	actionErr := action()
+
+	if wasRunning && ctx.Err() == nil {
+		if err := h.StartContext(ctx); err != nil {
+			if actionErr != nil {
+				log.Warnf("Error starting %q again: %s", h.Name, err)
+				return actionErr
+			}
 			return err
 		}
-		return mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running))
+	}
+
+	return actionErr
+}
+
+// CheckNotPaused returns an ErrHostPaused error if the machine is paused or
+// suspended, since it can not be reached until it is resumed.
+func (h *Host) CheckNotPaused() error {
+	s, err := h.Driver.GetState()
+	if err != nil {
+		return err
+	}
+
+	if s == state.Paused || s == state.Saved {
+		return mcnerror.ErrHostPaused{Name: h.Name, State: s}
 	}
 
 	return nil
@@ -171,8 +462,8 @@ func (h *Host) Upgrade() error {
 		}
 	}
 
-	log.Info("Upgrading docker...")
-	if err := provisioner.Package("docker", pkgaction.Upgrade); err != nil {
+	op := h.events().Begin(event.Upgrade, h.Name, h.DriverName)
+	if err := op.End(provisioner.Package("docker", pkgaction.Upgrade)); err != nil {
 		return crashreport.CrashError{
 			Cause:      err,
 			Command:    "Upgrade",
@@ -181,10 +472,25 @@ func (h *Host) Upgrade() error {
 		}
 	}
 
+	// The Docker version changed, leave it to the next ls to find it.
+	h.SetCachedState(state.Running)
+	h.CachedState.DockerVersion = ""
+
 	log.Info("Restarting docker...")
 	return provisioner.Service("docker", serviceaction.Restart)
 }
 
+// RestartDocker restarts the Docker daemon of the machine.
+func (h *Host) RestartDocker() error {
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	op := h.events().Begin(event.RestartDocker, h.Name, h.DriverName)
+	return op.End(provisioner.Service("docker", serviceaction.Restart))
+}
+
 func (h *Host) URL() (string, error) {
 	return h.Driver.GetURL()
 }
@@ -196,6 +502,17 @@ func (h *Host) AuthOptions() *auth.Options {
 	return h.HostOptions.AuthOptions
 }
 
+// Provision provisions the machine again with its current options, e.g.
+// once its engine or Swarm options have been changed.
+func (h *Host) Provision() error {
+	provisioner, err := provision.DetectProvisioner(h.Driver)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions)
+}
+
 func (h *Host) ConfigureAuth() error {
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
@@ -1,9 +1,18 @@
 package host
 
 import (
+	"errors"
 	"testing"
+	"time"
 
-	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
 func TestValidateHostnameValid(t *testing.T) {
@@ -35,3 +44,246 @@ func TestValidateHostnameInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestStartPublishesEvents(t *testing.T) {
+	h := &Host{
+		Name:       "foo",
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: state.Stopped,
+		},
+	}
+
+	events := []event.Event{}
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		events = append(events, e)
+	})
+	defer unsubscribe()
+
+	assert.NoError(t, h.Start())
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, event.Started, events[0].Type)
+	assert.Equal(t, event.Finished, events[1].Type)
+	assert.Equal(t, event.Start, events[1].Stage)
+	assert.Equal(t, "foo", events[1].HostName)
+	assert.Equal(t, "fakedriver", events[1].DriverName)
+}
+
+func TestStopAlreadyStoppedPublishesFailure(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Stopped,
+		},
+	}
+
+	var last event.Event
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		last = e
+	})
+	defer unsubscribe()
+
+	err := h.Stop()
+
+	assert.Error(t, err)
+	assert.Equal(t, event.Failed, last.Type)
+	assert.Equal(t, err, last.Err)
+}
+
+func TestPauseAndStart(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+	}
+
+	assert.NoError(t, h.Pause())
+	assert.Equal(t, mcnerror.ErrHostPaused{Name: "foo", State: state.Paused}, h.CheckNotPaused())
+
+	assert.NoError(t, h.Start())
+	assert.NoError(t, h.CheckNotPaused())
+}
+
+func TestSuspendAndStart(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+	}
+
+	assert.NoError(t, h.Suspend())
+	assert.Equal(t, mcnerror.ErrHostPaused{Name: "foo", State: state.Saved}, h.CheckNotPaused())
+
+	assert.NoError(t, h.Start())
+	assert.NoError(t, h.CheckNotPaused())
+}
+
+func TestUnpauseNotPaused(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+	}
+
+	assert.EqualError(t, h.Unpause(), `Machine "foo" is not paused.`)
+}
+
+func TestPauseNotSupported(t *testing.T) {
+	h := &Host{
+		Name:   "foo",
+		Driver: none.NewDriver("foo", ""),
+	}
+
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "none", Feature: "pausing"}, h.Pause())
+}
+
+func TestResizeStoppedMachineStaysStopped(t *testing.T) {
+	driver := &fakedriver.Driver{
+		MockState: state.Stopped,
+	}
+	h := &Host{
+		Name:   "foo",
+		Driver: driver,
+	}
+
+	assert.NoError(t, h.Resize(drivers.ResizeOptions{Memory: 2048}))
+	assert.Equal(t, drivers.ResizeOptions{Memory: 2048}, driver.Resized)
+	assert.Equal(t, state.Stopped, driver.MockState)
+}
+
+func TestResizePausedMachine(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Paused,
+		},
+	}
+
+	assert.Equal(t, mcnerror.ErrHostPaused{Name: "foo", State: state.Paused}, h.Resize(drivers.ResizeOptions{CPU: 2}))
+}
+
+func TestCloneRunningMachine(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+	}
+
+	data, err := h.CloneContext(context.Background(), "bar")
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"MachineName":"bar"`)
+	assert.Equal(t, state.Running, h.Driver.(*fakedriver.Driver).MockState)
+}
+
+func TestRenameMovesTheDirectory(t *testing.T) {
+	driver := &fakedriver.Driver{
+		MockName:  "foo",
+		MockState: state.Running,
+	}
+	h := &Host{
+		Name:   "foo",
+		Driver: driver,
+	}
+	moves := []string{}
+
+	err := h.RenameContext(context.Background(), "bar", func(oldName, newName string) error {
+		moves = append(moves, oldName+" "+newName)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo bar"}, moves)
+	assert.Equal(t, "bar", h.Name)
+	assert.Equal(t, "bar", driver.MockName)
+	assert.Equal(t, state.Running, driver.MockState)
+}
+
+// failingRenamer fails to rename the machine.
+type failingRenamer struct {
+	*fakedriver.Driver
+}
+
+func (d *failingRenamer) Rename(name string) error {
+	return errors.New("rename failed")
+}
+
+func TestRenameMovesTheDirectoryBackOnFailure(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &failingRenamer{&fakedriver.Driver{
+			MockState: state.Stopped,
+		}},
+	}
+	moves := []string{}
+
+	err := h.RenameContext(context.Background(), "bar", func(oldName, newName string) error {
+		moves = append(moves, oldName+" "+newName)
+		return nil
+	})
+
+	assert.EqualError(t, err, "rename failed")
+	assert.Equal(t, []string{"foo bar", "bar foo"}, moves)
+	assert.Equal(t, "foo", h.Name)
+}
+
+func TestStopCachesState(t *testing.T) {
+	h := &Host{
+		Name: "foo",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+			MockIP:    "1.2.3.4",
+		},
+		CachedState: &CachedState{
+			State:         state.Running,
+			DockerVersion: "v1.10.0",
+		},
+	}
+
+	assert.NoError(t, h.Stop())
+	assert.Equal(t, state.Stopped, h.CachedState.State)
+	assert.Equal(t, "", h.CachedState.URL)
+	assert.Equal(t, "", h.CachedState.DockerVersion)
+	assert.True(t, h.StopRequested())
+
+	assert.NoError(t, h.Start())
+	assert.False(t, h.StopRequested())
+	assert.Equal(t, state.Running, h.CachedState.State)
+	assert.Equal(t, "1.2.3.4", h.CachedState.IP)
+	assert.Equal(t, "tcp://1.2.3.4:2376", h.CachedState.URL)
+}
+
+func TestStartResetsIdleTime(t *testing.T) {
+	h := &Host{
+		Name:   "foo",
+		Driver: &fakedriver.Driver{MockState: state.Stopped},
+		HostOptions: &Options{
+			ReapPolicy: &ReapPolicy{
+				IdleMinutes: 10,
+				IdleSince:   time.Now().Add(-time.Hour),
+			},
+		},
+	}
+
+	assert.NoError(t, h.Start())
+	assert.True(t, h.ReapPolicy().IdleSince.IsZero())
+	assert.False(t, h.ReapPolicy().StartedAt.IsZero())
+}
+
+func TestParseExpiryAction(t *testing.T) {
+	action, err := ParseExpiryAction("")
+	assert.NoError(t, err)
+	assert.Equal(t, ExpiryStop, action)
+
+	action, err = ParseExpiryAction("rm")
+	assert.NoError(t, err)
+	assert.Equal(t, ExpiryRemove, action)
+
+	_, err = ParseExpiryAction("kill")
+	assert.Error(t, err)
+}
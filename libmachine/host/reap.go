@@ -0,0 +1,75 @@
+package host
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpiryAction is what is done to a machine once it expired.
+type ExpiryAction string
+
+const (
+	ExpiryStop   ExpiryAction = "stop"
+	ExpiryRemove ExpiryAction = "rm"
+)
+
+// ReapPolicy tells when a forgotten machine is stopped or removed by
+// `docker-machine reap`.
+type ReapPolicy struct {
+	// IdleMinutes is the number of minutes a running machine may have no
+	// running containers before it is stopped, zero to never stop it.
+	IdleMinutes int `json:",omitempty"`
+
+	// ExpiresAt is when ExpiryAction is applied to the machine, the zero
+	// time for never.
+	ExpiresAt    time.Time
+	ExpiryAction ExpiryAction `json:",omitempty"`
+
+	// IdleSince is when the machine became idle, the zero time if it was
+	// not seen idle since it last ran containers or was started.
+	IdleSince time.Time
+
+	// StartedAt is when the machine was last started by Docker Machine.
+	// The containers which finished before do not tell how long it has
+	// been idle.
+	StartedAt time.Time
+}
+
+// ParseExpiryAction checks that the action is known. The empty action is
+// ExpiryStop.
+func ParseExpiryAction(action string) (ExpiryAction, error) {
+	switch ExpiryAction(action) {
+	case "":
+		return ExpiryStop, nil
+	case ExpiryStop, ExpiryRemove:
+		return ExpiryAction(action), nil
+	}
+
+	return "", fmt.Errorf("Unknown expiry action %q, expected stop or rm", action)
+}
+
+// Expires returns true if the machine has an expiry date.
+func (p *ReapPolicy) Expires() bool {
+	return p != nil && !p.ExpiresAt.IsZero()
+}
+
+// StopsWhenIdle returns true if the machine is stopped when idle.
+func (p *ReapPolicy) StopsWhenIdle() bool {
+	return p != nil && p.IdleMinutes > 0
+}
+
+// recordStart resets the idle time of the machine, once started.
+func (h *Host) recordStart() {
+	if p := h.ReapPolicy(); p != nil {
+		p.StartedAt = time.Now()
+		p.IdleSince = time.Time{}
+	}
+}
+
+// ReapPolicy returns the reap policy of the machine, nil if it has none.
+func (h *Host) ReapPolicy() *ReapPolicy {
+	if h.HostOptions == nil {
+		return nil
+	}
+	return h.HostOptions.ReapPolicy
+}
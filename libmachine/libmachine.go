@@ -17,6 +17,8 @@ import (
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
@@ -27,12 +29,15 @@ import (
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
 	"github.com/docker/machine/libmachine/version"
+	"golang.org/x/net/context"
 )
 
 type API interface {
 	io.Closer
 	NewHost(driverName string, rawDriver []byte) (*host.Host, error)
 	Create(h *host.Host) error
+	CreateContext(ctx context.Context, h *host.Host) error
+	ResumeCreateContext(ctx context.Context, h *host.Host) error
 	persist.Store
 }
 
@@ -42,16 +47,24 @@ type Client struct {
 	SSHClientType  ssh.ClientType
 	GithubAPIToken string
 	*persist.Filestore
+	// Store holds the configuration of the machines. It defaults to the
+	// Filestore, which is also used to locate the machine directories.
+	Store               persist.Store
 	clientDriverFactory rpcdriver.RPCClientDriverFactory
+	events              *event.Dispatcher
 }
 
 func NewClient(storePath, certsDir string) *Client {
+	filestore := persist.NewFilestore(storePath, certsDir, certsDir)
+
 	return &Client{
 		certsDir:            certsDir,
 		IsDebug:             false,
 		SSHClientType:       ssh.External,
-		Filestore:           persist.NewFilestore(storePath, certsDir, certsDir),
+		Filestore:           filestore,
+		Store:               filestore,
 		clientDriverFactory: rpcdriver.NewRPCClientDriverFactory(),
+		events:              event.NewDispatcher(),
 	}
 }
 
@@ -66,6 +79,7 @@ func (api *Client) NewHost(driverName string, rawDriver []byte) (*host.Host, err
 		Name:          driver.GetMachineName(),
 		Driver:        driver,
 		DriverName:    driver.DriverName(),
+		Events:        api.events,
 		HostOptions: &host.Options{
 			AuthOptions: &auth.Options{
 				CertDir:          api.certsDir,
@@ -90,11 +104,47 @@ func (api *Client) NewHost(driverName string, rawDriver []byte) (*host.Host, err
 	}, nil
 }
 
+func (api *Client) Exists(name string) (bool, error) {
+	return api.Store.Exists(name)
+}
+
+func (api *Client) List() ([]string, error) {
+	return api.Store.List()
+}
+
+func (api *Client) Remove(name string) error {
+	return api.Store.Remove(name)
+}
+
+func (api *Client) Save(h *host.Host) error {
+	return api.Store.Save(h)
+}
+
+// SaveCachedState records the cached state of a machine in the store,
+// without loading its driver.
+func (api *Client) SaveCachedState(name string, cached *host.CachedState) error {
+	return persist.SaveCachedState(api.Store, name, cached)
+}
+
+// GetDirMover returns what moves the directories of the machines of api when
+// they are renamed: the store of a Client, or api itself otherwise, e.g. the
+// client of a remote server. ok is false if it can not move them.
+func GetDirMover(api API) (mover persist.DirMover, ok bool) {
+	if client, isClient := api.(*Client); isClient {
+		mover, ok = client.Store.(persist.DirMover)
+		return mover, ok
+	}
+
+	mover, ok = api.(persist.DirMover)
+	return mover, ok
+}
+
 func (api *Client) Load(name string) (*host.Host, error) {
-	h, err := api.Filestore.Load(name)
+	h, err := api.Store.Load(name)
 	if err != nil {
 		return nil, err
 	}
+	h.Events = api.events
 
 	d, err := api.clientDriverFactory.NewRPCClientDriver(h.DriverName, h.RawDriver)
 	if err != nil {
@@ -115,93 +165,248 @@ func (api *Client) Load(name string) (*host.Host, error) {
 	return h, nil
 }
 
+// Subscribe registers a handler which receives the lifecycle events
+// published while the machines of the client are created, started, stopped,
+// etc. The returned function removes the handler.
+func (api *Client) Subscribe(handler event.Handler) func() {
+	return api.events.Subscribe(handler)
+}
+
 // Create is the wrapper method which covers all of the boilerplate around
 // actually creating, provisioning, and persisting an instance in the store.
 func (api *Client) Create(h *host.Host) error {
+	return api.CreateContext(context.Background(), h)
+}
+
+// CreateContext is like Create but can be cancelled or given a deadline
+// through ctx. If the context is done before the machine is ready, the
+// partially created machine is removed from the provider and the store.
+// The pre-create hooks of the machine run once the pre-create checks
+// passed, and its post-create hooks once it is ready.
+func (api *Client) CreateContext(ctx context.Context, h *host.Host) error {
+	h.Events = api.events
+
+	if err := h.Hooks().Validate(); err != nil {
+		return err
+	}
+
 	if err := cert.BootstrapCertificates(h.AuthOptions()); err != nil {
 		return fmt.Errorf("Error generating certificates: %s", err)
 	}
 
-	log.Info("Running pre-create checks...")
-
-	if err := h.Driver.PreCreateCheck(); err != nil {
+	op := api.events.Begin(event.PreCreateCheck, h.Name, h.DriverName)
+	if err := op.End(mcnutils.RunWithContext(ctx, h.Driver.PreCreateCheck)); err != nil {
+		if err == ctx.Err() {
+			return err
+		}
 		return mcnerror.ErrDuringPreCreate{err}
 	}
 
-	if err := api.Save(h); err != nil {
-		return fmt.Errorf("Error saving host to store before attempting creation: %s", err)
+	if err := h.RunHooks(ctx, hook.PreCreate); err != nil {
+		return err
 	}
 
-	log.Info("Creating machine...")
+	h.CreateStage = event.PreCreateCheck
 
-	if err := api.performCreate(h); err != nil {
-		// Wait for all the logs to reach the client
-		time.Sleep(2 * time.Second)
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error saving host to store before attempting creation: %s", err)
+	}
 
-		vBoxLog := ""
-		if h.DriverName == "virtualbox" {
-			vBoxLog = filepath.Join(api.GetMachinesDir(), h.Name, h.Name, "Logs", "VBox.log")
+	if err := api.performCreate(ctx, h); err != nil {
+		if ctx.Err() != nil {
+			api.abortCreate(h)
+			return fmt.Errorf("Machine creation was aborted: %s", ctx.Err())
 		}
 
-		return crashreport.CrashError{
-			Cause:       err,
-			Command:     "Create",
-			Context:     "api.performCreate",
-			DriverName:  h.DriverName,
-			LogFilePath: vBoxLog,
-		}
+		return api.createCrashError(h, err)
 	}
 
 	log.Debug("Reticulating splines...")
 
-	return nil
+	return h.RunHooks(ctx, hook.PostCreate)
+}
+
+// ResumeCreate continues the creation of a machine from the stage following
+// the last one which completed successfully.
+func (api *Client) ResumeCreate(h *host.Host) error {
+	return api.ResumeCreateContext(context.Background(), h)
 }
 
-func (api *Client) performCreate(h *host.Host) error {
-	if err := h.Driver.Create(); err != nil {
-		return fmt.Errorf("Error in driver during machine creation: %s", err)
+// ResumeCreateContext is like ResumeCreate but can be cancelled or given a
+// deadline through ctx. Unlike CreateContext, the machine is kept if the
+// context is done so that its creation can be resumed once more.
+func (api *Client) ResumeCreateContext(ctx context.Context, h *host.Host) error {
+	if !h.IsPartiallyCreated() {
+		return fmt.Errorf("Machine %q is already created, there is nothing to resume", h.Name)
 	}
 
-	if err := api.Save(h); err != nil {
-		return fmt.Errorf("Error saving host to store after attempting creation: %s", err)
+	h.Events = api.events
+
+	log.Infof("Resuming creation of %q after stage %q...", h.Name, h.CreateStage)
+
+	if err := api.performCreate(ctx, h); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Machine creation was aborted: %s", ctx.Err())
+		}
+
+		return api.createCrashError(h, err)
 	}
 
-	// TODO: Not really a fan of just checking "none" or "ci-test" here.
-	if h.Driver.DriverName() == "none" || h.Driver.DriverName() == "ci-test" {
-		return nil
+	return h.RunHooks(ctx, hook.PostCreate)
+}
+
+func (api *Client) createCrashError(h *host.Host, err error) error {
+	// Wait for all the logs to reach the client
+	time.Sleep(2 * time.Second)
+
+	vBoxLog := ""
+	if h.DriverName == "virtualbox" {
+		vBoxLog = filepath.Join(api.GetMachinesDir(), h.Name, h.Name, "Logs", "VBox.log")
 	}
 
-	log.Info("Waiting for machine to be running, this may take a few minutes...")
-	if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running)); err != nil {
-		return fmt.Errorf("Error waiting for machine to be running: %s", err)
+	return crashreport.CrashError{
+		Cause:       err,
+		Command:     "Create",
+		Context:     "api.performCreate",
+		DriverName:  h.DriverName,
+		LogFilePath: vBoxLog,
 	}
+}
+
+// createStep is one stage of the creation process.
+type createStep struct {
+	stage  event.Stage
+	errFmt string
+	run    func() error
 
-	log.Info("Machine is running, waiting for SSH to be available...")
-	if err := drivers.WaitForSSH(h.Driver); err != nil {
-		return fmt.Errorf("Error waiting for SSH: %s", err)
+	// Stages which are not checkpoints are run again when resuming,
+	// as their outcome is not persisted.
+	checkpoint bool
+}
+
+// performCreate runs every creation stage following h.CreateStage, saving
+// the host after each checkpoint so that a failed creation can be resumed.
+func (api *Client) performCreate(ctx context.Context, h *host.Host) error {
+	var provisioner provision.Provisioner
+
+	steps := []createStep{
+		{
+			stage:      event.Create,
+			errFmt:     "Error in driver during machine creation: %s",
+			run:        func() error { return createInDriver(ctx, h) },
+			checkpoint: true,
+		},
+		{
+			stage:      event.WaitForRunning,
+			errFmt:     "Error waiting for machine to be running: %s",
+			run:        func() error { return mcnutils.WaitForContext(ctx, drivers.MachineInState(h.Driver, state.Running)) },
+			checkpoint: true,
+		},
+		{
+			stage:      event.WaitForSSH,
+			errFmt:     "Error waiting for SSH: %s",
+			run:        func() error { return drivers.WaitForSSHContext(ctx, h.Driver) },
+			checkpoint: true,
+		},
+		{
+			stage:  event.DetectProvisioner,
+			errFmt: "Error detecting OS: %s",
+			run: func() error {
+				var err error
+				provisioner, err = provision.DetectProvisionerContext(ctx, h.Driver)
+				return err
+			},
+		},
+		{
+			stage:  event.Provision,
+			errFmt: "Error running provisioning: %s",
+			run: func() error {
+				return provision.ProvisionContext(ctx, provisioner, *h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions)
+			},
+			checkpoint: true,
+		},
+		{
+			// We should check the connection to docker here
+			stage:  event.CheckConnection,
+			errFmt: "Error checking the host: %s",
+			run: func() error {
+				_, _, err := check.DefaultConnChecker.Check(h, false)
+				return err
+			},
+			checkpoint: true,
+		},
 	}
 
-	log.Info("Detecting operating system of created instance...")
-	provisioner, err := provision.DetectProvisioner(h.Driver)
-	if err != nil {
-		return fmt.Errorf("Error detecting OS: %s", err)
+	skipping := h.CreateStage != event.PreCreateCheck
+
+	for _, step := range steps {
+		if skipping {
+			if step.stage == h.CreateStage {
+				skipping = false
+			}
+			continue
+		}
+
+		var op *event.Operation
+		if step.stage == event.Provision {
+			op = api.events.BeginWithDetail(step.stage, h.Name, h.DriverName, provisioner.String())
+		} else {
+			op = api.events.Begin(step.stage, h.Name, h.DriverName)
+		}
+
+		if err := op.End(step.run()); err != nil {
+			return fmt.Errorf(step.errFmt, err)
+		}
+
+		if !step.checkpoint {
+			continue
+		}
+
+		h.CreateStage = step.stage
+		if err := api.Save(h); err != nil {
+			return fmt.Errorf("Error saving host to store after stage %q: %s", step.stage, err)
+		}
+
+		// TODO: Not really a fan of just checking "none" or "ci-test" here.
+		if step.stage == event.Create && (h.Driver.DriverName() == "none" || h.Driver.DriverName() == "ci-test") {
+			break
+		}
 	}
 
-	log.Infof("Provisioning with %s...", provisioner.String())
-	if err := provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions); err != nil {
-		return fmt.Errorf("Error running provisioning: %s", err)
+	if skipping {
+		return fmt.Errorf("Unknown creation stage %q", h.CreateStage)
 	}
 
-	// We should check the connection to docker here
-	log.Info("Checking connection to Docker...")
-	if _, _, err = check.DefaultConnChecker.Check(h, false); err != nil {
-		return fmt.Errorf("Error checking the host: %s", err)
+	h.CreateStage = ""
+	h.SetCachedState(state.Running)
+	if err := api.Save(h); err != nil {
+		return fmt.Errorf("Error saving host to store after creation: %s", err)
 	}
 
-	log.Info("Docker is up and running!")
 	return nil
 }
 
+// createInDriver runs the driver's Create. Should ctx be done first, the
+// driver aborts and we wait for it to return so that whatever it managed to
+// create can be cleaned up afterwards.
+func createInDriver(ctx context.Context, h *host.Host) error {
+	return drivers.CreateWithContext(ctx, h.Driver)
+}
+
+// abortCreate removes whatever was built for a machine whose creation was
+// cancelled, so that no half-built instances are left behind.
+func (api *Client) abortCreate(h *host.Host) {
+	log.Infof("Removing partially created machine %q...", h.Name)
+
+	if err := h.Driver.Remove(); err != nil {
+		log.Warnf("Error removing machine %q, it might need to be removed manually: %s", h.Name, err)
+	}
+
+	if err := api.Remove(h.Name); err != nil {
+		log.Warnf("Error removing machine %q from the store: %s", h.Name, err)
+	}
+}
+
 func (api *Client) Close() error {
 	return api.clientDriverFactory.Close()
 }
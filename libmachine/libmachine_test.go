@@ -0,0 +1,158 @@
+package libmachine
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/check"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type FakeConnChecker struct {
+	calls int
+}
+
+func (fcc *FakeConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Options, error) {
+	fcc.calls++
+	return "tcp://1.2.3.4:2376", &auth.Options{}, nil
+}
+
+func getTestClient(t *testing.T) (*Client, func()) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewClient(storePath, storePath), func() {
+		os.RemoveAll(storePath)
+	}
+}
+
+func TestResumeCreateSkipsCompletedStages(t *testing.T) {
+	api, cleanup := getTestClient(t)
+	defer cleanup()
+
+	connChecker := &FakeConnChecker{}
+	defaultConnChecker := check.DefaultConnChecker
+	check.DefaultConnChecker = connChecker
+	defer func() { check.DefaultConnChecker = defaultConnChecker }()
+
+	h := &host.Host{
+		Name:        "foo",
+		DriverName:  "fakedriver",
+		Driver:      &fakedriver.Driver{MockState: state.Running},
+		HostOptions: &host.Options{},
+		CreateStage: event.Provision,
+	}
+
+	stages := []event.Stage{}
+	unsubscribe := api.Subscribe(func(e event.Event) {
+		if e.Type == event.Started {
+			stages = append(stages, e.Stage)
+		}
+	})
+	defer unsubscribe()
+
+	err := api.ResumeCreate(h)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []event.Stage{event.CheckConnection}, stages)
+	assert.Equal(t, 1, connChecker.calls)
+	assert.False(t, h.IsPartiallyCreated())
+
+	exists, err := api.Exists("foo")
+	assert.True(t, exists)
+	assert.NoError(t, err)
+}
+
+func TestResumeCreateFullyCreatedMachine(t *testing.T) {
+	api, cleanup := getTestClient(t)
+	defer cleanup()
+
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "fakedriver",
+		Driver:     &fakedriver.Driver{MockState: state.Running},
+	}
+
+	err := api.ResumeCreate(h)
+
+	assert.EqualError(t, err, `Machine "foo" is already created, there is nothing to resume`)
+}
+
+func TestResumeCreateUnknownStage(t *testing.T) {
+	api, cleanup := getTestClient(t)
+	defer cleanup()
+
+	h := &host.Host{
+		Name:        "foo",
+		DriverName:  "fakedriver",
+		Driver:      &fakedriver.Driver{MockState: state.Running},
+		HostOptions: &host.Options{},
+		CreateStage: event.Stage("bogus"),
+	}
+
+	err := api.performCreate(context.Background(), h)
+
+	assert.EqualError(t, err, `Unknown creation stage "bogus"`)
+}
+
+func TestSubscribeOnlyReceivesEventsOfTheClient(t *testing.T) {
+	api, cleanup := getTestClient(t)
+	defer cleanup()
+
+	other, otherCleanup := getTestClient(t)
+	defer otherCleanup()
+
+	connChecker := &FakeConnChecker{}
+	defaultConnChecker := check.DefaultConnChecker
+	check.DefaultConnChecker = connChecker
+	defer func() { check.DefaultConnChecker = defaultConnChecker }()
+
+	received := 0
+	unsubscribe := api.Subscribe(func(e event.Event) {
+		received++
+	})
+	defer unsubscribe()
+
+	h := &host.Host{
+		Name:        "foo",
+		DriverName:  "fakedriver",
+		Driver:      &fakedriver.Driver{MockState: state.Running},
+		HostOptions: &host.Options{},
+		CreateStage: event.Provision,
+	}
+
+	assert.NoError(t, other.ResumeCreate(h))
+	assert.Equal(t, 0, received)
+
+	h.Publish(event.Event{Type: event.Changed, Stage: event.Health, HostName: "foo"})
+	assert.Equal(t, 0, received)
+}
+
+// storeOnly hides the methods of a store which are not those of Store.
+type storeOnly struct {
+	persist.Store
+}
+
+func TestGetDirMoverAsksTheStore(t *testing.T) {
+	api, cleanup := getTestClient(t)
+	defer cleanup()
+
+	mover, ok := GetDirMover(api)
+	assert.True(t, ok)
+	assert.Equal(t, api.Store, mover)
+
+	api.Store = storeOnly{api.Store}
+
+	_, ok = GetDirMover(api)
+	assert.False(t, ok)
+}
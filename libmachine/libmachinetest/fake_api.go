@@ -6,6 +6,7 @@ import (
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
 )
 
 type FakeAPI struct {
@@ -28,6 +29,15 @@ func (api *FakeAPI) Create(h *host.Host) error {
 	return nil
 }
 
+func (api *FakeAPI) CreateContext(ctx context.Context, h *host.Host) error {
+	return ctx.Err()
+}
+
+func (api *FakeAPI) ResumeCreateContext(ctx context.Context, h *host.Host) error {
+	h.CreateStage = ""
+	return ctx.Err()
+}
+
 func (api *FakeAPI) Exists(name string) (bool, error) {
 	for _, host := range api.Hosts {
 		if name == host.Name {
@@ -72,6 +82,10 @@ func (api *FakeAPI) Save(host *host.Host) error {
 	return nil
 }
 
+func (api *FakeAPI) MoveDir(oldName, newName string) error {
+	return nil
+}
+
 func State(api libmachine.API, name string) state.State {
 	host, _ := api.Load(name)
 	machineState, _ := host.Driver.GetState()
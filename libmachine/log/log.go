@@ -57,6 +57,11 @@ func Warnf(fmtString string, args ...interface{}) {
 	logger.Warnf(fmtString, args...)
 }
 
+// SetMachineLogger replaces the logger the messages are handed to.
+func SetMachineLogger(l MachineLogger) {
+	logger = l
+}
+
 func SetDebug(debug bool) {
 	logger.SetDebug(debug)
 }
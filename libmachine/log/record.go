@@ -0,0 +1,232 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a log record.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Fields are key/value pairs describing the context of a log record.
+type Fields map[string]interface{}
+
+// Record is a log message with its level, e.g. to be sent by a driver plugin
+// to docker-machine.
+type Record struct {
+	Level   Level
+	Message string
+	Fields  map[string]string `json:",omitempty"`
+
+	// Machine is the name of the machine the record is about, if known.
+	Machine string `json:",omitempty"`
+}
+
+// String returns the message followed by the fields sorted by key.
+func (r Record) String() string {
+	if len(r.Fields) == 0 {
+		return r.Message
+	}
+
+	keys := []string{}
+	for key := range r.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{r.Message}
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", key, r.Fields[key]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Log logs a record at its level.
+func Log(r Record) {
+	message := r.String()
+
+	switch r.Level {
+	case DebugLevel:
+		Debug(message)
+	case InfoLevel:
+		Info(message)
+	case WarnLevel:
+		Warn(message)
+	default:
+		Error(message)
+	}
+}
+
+// recordLogger is implemented by the loggers which keep the fields of the
+// records separate from their message.
+type recordLogger interface {
+	LogRecord(r Record)
+}
+
+// WithFields returns an entry to log messages with fields.
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+// Entry logs messages with fields.
+type Entry struct {
+	fields Fields
+}
+
+func (e *Entry) log(level Level, message string) {
+	r := Record{
+		Level:   level,
+		Message: message,
+		Fields:  map[string]string{},
+	}
+	for key, value := range e.fields {
+		r.Fields[key] = fmt.Sprint(value)
+	}
+
+	if l, ok := logger.(recordLogger); ok {
+		l.LogRecord(r)
+		return
+	}
+
+	Log(r)
+}
+
+func (e *Entry) Debug(args ...interface{}) {
+	e.log(DebugLevel, fmt.Sprint(args...))
+}
+
+func (e *Entry) Debugf(fmtString string, args ...interface{}) {
+	e.log(DebugLevel, fmt.Sprintf(fmtString, args...))
+}
+
+func (e *Entry) Info(args ...interface{}) {
+	e.log(InfoLevel, fmt.Sprint(args...))
+}
+
+func (e *Entry) Infof(fmtString string, args ...interface{}) {
+	e.log(InfoLevel, fmt.Sprintf(fmtString, args...))
+}
+
+func (e *Entry) Warn(args ...interface{}) {
+	e.log(WarnLevel, fmt.Sprint(args...))
+}
+
+func (e *Entry) Warnf(fmtString string, args ...interface{}) {
+	e.log(WarnLevel, fmt.Sprintf(fmtString, args...))
+}
+
+func (e *Entry) Error(args ...interface{}) {
+	e.log(ErrorLevel, fmt.Sprint(args...))
+}
+
+func (e *Entry) Errorf(fmtString string, args ...interface{}) {
+	e.log(ErrorLevel, fmt.Sprintf(fmtString, args...))
+}
+
+// RecordMachineLogger is the MachineLogger of driver plugins. It prints the
+// messages with another logger until Forward is called, and then hands them
+// as records to the given function, so that they reach docker-machine with
+// their level.
+type RecordMachineLogger struct {
+	MachineLogger
+
+	lock    sync.Mutex
+	forward func(Record)
+}
+
+func NewRecordMachineLogger(fallback MachineLogger) *RecordMachineLogger {
+	return &RecordMachineLogger{
+		MachineLogger: fallback,
+	}
+}
+
+// Forward hands the next messages to fn instead of printing them.
+func (ml *RecordMachineLogger) Forward(fn func(Record)) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.forward = fn
+}
+
+func (ml *RecordMachineLogger) LogRecord(r Record) {
+	ml.lock.Lock()
+	forward := ml.forward
+	ml.lock.Unlock()
+
+	if forward == nil {
+		ml.print(r)
+		return
+	}
+
+	forward(r)
+}
+
+func (ml *RecordMachineLogger) print(r Record) {
+	message := r.String()
+
+	switch r.Level {
+	case DebugLevel:
+		ml.MachineLogger.Debug(message)
+	case InfoLevel:
+		ml.MachineLogger.Info(message)
+	case WarnLevel:
+		ml.MachineLogger.Warn(message)
+	default:
+		ml.MachineLogger.Error(message)
+	}
+}
+
+func (ml *RecordMachineLogger) Debug(args ...interface{}) {
+	ml.LogRecord(Record{Level: DebugLevel, Message: fmt.Sprint(args...)})
+}
+
+func (ml *RecordMachineLogger) Debugf(fmtString string, args ...interface{}) {
+	ml.LogRecord(Record{Level: DebugLevel, Message: fmt.Sprintf(fmtString, args...)})
+}
+
+func (ml *RecordMachineLogger) Info(args ...interface{}) {
+	ml.LogRecord(Record{Level: InfoLevel, Message: fmt.Sprint(args...)})
+}
+
+func (ml *RecordMachineLogger) Infof(fmtString string, args ...interface{}) {
+	ml.LogRecord(Record{Level: InfoLevel, Message: fmt.Sprintf(fmtString, args...)})
+}
+
+func (ml *RecordMachineLogger) Warn(args ...interface{}) {
+	ml.LogRecord(Record{Level: WarnLevel, Message: fmt.Sprint(args...)})
+}
+
+func (ml *RecordMachineLogger) Warnf(fmtString string, args ...interface{}) {
+	ml.LogRecord(Record{Level: WarnLevel, Message: fmt.Sprintf(fmtString, args...)})
+}
+
+func (ml *RecordMachineLogger) Error(args ...interface{}) {
+	ml.LogRecord(Record{Level: ErrorLevel, Message: fmt.Sprint(args...)})
+}
+
+func (ml *RecordMachineLogger) Errorf(fmtString string, args ...interface{}) {
+	ml.LogRecord(Record{Level: ErrorLevel, Message: fmt.Sprintf(fmtString, args...)})
+}
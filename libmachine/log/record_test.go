@@ -0,0 +1,47 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordString(t *testing.T) {
+	assert.Equal(t, "Creating VM...", Record{Message: "Creating VM..."}.String())
+
+	r := Record{
+		Message: "Creating VM...",
+		Fields:  map[string]string{"memory": "1024", "cpus": "2"},
+	}
+	assert.Equal(t, "Creating VM... cpus=2 memory=1024", r.String())
+}
+
+func TestRecordMachineLoggerPrints(t *testing.T) {
+	testLogger := NewRecordMachineLogger(NewFmtMachineLogger())
+
+	result := captureOutput(testLogger, func() { testLogger.Info("info") })
+	assert.Equal(t, "info", result)
+
+	result = captureError(testLogger, func() { testLogger.Error("error") })
+	assert.Equal(t, "error", result)
+}
+
+func TestRecordMachineLoggerForwards(t *testing.T) {
+	testLogger := NewRecordMachineLogger(NewFmtMachineLogger())
+
+	records := []Record{}
+	testLogger.Forward(func(r Record) {
+		records = append(records, r)
+	})
+
+	SetMachineLogger(testLogger)
+	defer SetMachineLogger(NewFmtMachineLogger())
+
+	Warnf("Low disk space: %d%%", 5)
+	WithFields(Fields{"disk": "sda", "free": 5}).Error("Low disk space")
+
+	assert.Equal(t, []Record{
+		{Level: WarnLevel, Message: "Low disk space: 5%"},
+		{Level: ErrorLevel, Message: "Low disk space", Fields: map[string]string{"disk": "sda", "free": "5"}},
+	}, records)
+}
@@ -0,0 +1,90 @@
+package mcndockerclient
+
+import (
+	"fmt"
+	"time"
+)
+
+var CurrentContainerCounter ContainerCounter = &defaultContainerCounter{}
+
+// machineContainers are the containers Docker Machine runs itself, which do
+// not make a machine busy.
+var machineContainers = map[string]bool{
+	"/swarm-agent":        true,
+	"/swarm-agent-master": true,
+}
+
+type ContainerCounter interface {
+	RunningContainers(host DockerHost) (int, error)
+	LastFinished(host DockerHost) (time.Time, error)
+}
+
+// RunningContainers returns the number of containers running on the host,
+// not counting the Swarm containers started by Docker Machine.
+func RunningContainers(host DockerHost) (int, error) {
+	return CurrentContainerCounter.RunningContainers(host)
+}
+
+// LastFinished returns when the last container which is not running anymore
+// finished, the zero time if none did. The Swarm containers started by
+// Docker Machine are not counted either.
+func LastFinished(host DockerHost) (time.Time, error) {
+	return CurrentContainerCounter.LastFinished(host)
+}
+
+type defaultContainerCounter struct{}
+
+func (cc *defaultContainerCounter) RunningContainers(host DockerHost) (int, error) {
+	client, err := DockerClient(host)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to list the containers: %s", err)
+	}
+
+	containers, err := client.ListContainers(false, false, "")
+	if err != nil {
+		return 0, fmt.Errorf("Unable to list the containers: %s", err)
+	}
+
+	count := 0
+	for _, container := range containers {
+		if len(container.Names) == 0 || !machineContainers[container.Names[0]] {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (cc *defaultContainerCounter) LastFinished(host DockerHost) (time.Time, error) {
+	var last time.Time
+
+	client, err := DockerClient(host)
+	if err != nil {
+		return last, fmt.Errorf("Unable to list the containers: %s", err)
+	}
+
+	containers, err := client.ListContainers(true, false, "")
+	if err != nil {
+		return last, fmt.Errorf("Unable to list the containers: %s", err)
+	}
+
+	for _, container := range containers {
+		if len(container.Names) > 0 && machineContainers[container.Names[0]] {
+			continue
+		}
+
+		info, err := client.InspectContainer(container.Id)
+		if err != nil {
+			return last, fmt.Errorf("Unable to inspect container %s: %s", container.Id, err)
+		}
+
+		if info.State == nil || info.State.Running {
+			continue
+		}
+		if info.State.FinishedAt.After(last) {
+			last = info.State.FinishedAt
+		}
+	}
+
+	return last, nil
+}
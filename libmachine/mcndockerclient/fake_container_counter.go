@@ -0,0 +1,25 @@
+package mcndockerclient
+
+import "time"
+
+type FakeContainerCounter struct {
+	Count    int
+	Finished time.Time
+	Err      error
+}
+
+func (cc *FakeContainerCounter) RunningContainers(host DockerHost) (int, error) {
+	if cc.Err != nil {
+		return 0, cc.Err
+	}
+
+	return cc.Count, nil
+}
+
+func (cc *FakeContainerCounter) LastFinished(host DockerHost) (time.Time, error) {
+	if cc.Err != nil {
+		return time.Time{}, cc.Err
+	}
+
+	return cc.Finished, nil
+}
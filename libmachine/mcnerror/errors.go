@@ -3,6 +3,8 @@ package mcnerror
 import (
 	"errors"
 	"fmt"
+
+	"github.com/docker/machine/libmachine/state"
 )
 
 var (
@@ -32,3 +34,42 @@ type ErrDuringPreCreate struct {
 func (e ErrDuringPreCreate) Error() string {
 	return fmt.Sprintf("Error with pre-create check: %q", e.Cause)
 }
+
+type ErrHostBusy struct {
+	Name string
+	Pid  int
+}
+
+func (e ErrHostBusy) Error() string {
+	if e.Pid == 0 {
+		return fmt.Sprintf("Machine %q is busy", e.Name)
+	}
+	return fmt.Sprintf("Machine %q is busy (locked by pid %d)", e.Name, e.Pid)
+}
+
+// ErrStoreBusy is returned when the store stays locked by another process.
+// Pid is zero when it is locked by readers, which are not recorded.
+type ErrStoreBusy struct {
+	Pid int
+}
+
+func (e ErrStoreBusy) Error() string {
+	if e.Pid == 0 {
+		return "Machine store is busy"
+	}
+	return fmt.Sprintf("Machine store is busy (locked by pid %d)", e.Pid)
+}
+
+// ErrHostPaused is returned when a machine has to be reached but has been
+// paused or suspended.
+type ErrHostPaused struct {
+	Name  string
+	State state.State
+}
+
+func (e ErrHostPaused) Error() string {
+	if e.State == state.Saved {
+		return fmt.Sprintf("Machine %q is suspended, resume it with \"docker-machine start %s\"", e.Name, e.Name)
+	}
+	return fmt.Sprintf("Machine %q is paused, resume it with \"docker-machine unpause %s\"", e.Name, e.Name)
+}
@@ -9,6 +9,8 @@ import (
 	"runtime"
 	"strconv"
 	"time"
+
+	"golang.org/x/net/context"
 )
 
 // GetHomeDir returns the home directory
@@ -84,6 +86,29 @@ func WaitForSpecificOrError(f func() (bool, error), maxAttempts int, waitInterva
 	return fmt.Errorf("Maximum number of retries (%d) exceeded", maxAttempts)
 }
 
+// WaitForSpecificOrErrorContext is like WaitForSpecificOrError but gives up
+// with ctx.Err() as soon as the context is cancelled or its deadline expires.
+func WaitForSpecificOrErrorContext(ctx context.Context, f func() (bool, error), maxAttempts int, waitInterval time.Duration) error {
+	for i := 0; i < maxAttempts; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stop, err := f()
+		if err != nil {
+			return err
+		}
+		if stop {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitInterval):
+		}
+	}
+	return fmt.Errorf("Maximum number of retries (%d) exceeded", maxAttempts)
+}
+
 func WaitForSpecific(f func() bool, maxAttempts int, waitInterval time.Duration) error {
 	return WaitForSpecificOrError(func() (bool, error) {
 		return f(), nil
@@ -94,6 +119,33 @@ func WaitFor(f func() bool) error {
 	return WaitForSpecific(f, 60, 3*time.Second)
 }
 
+func WaitForContext(ctx context.Context, f func() bool) error {
+	return WaitForSpecificOrErrorContext(ctx, func() (bool, error) {
+		return f(), nil
+	}, 60, 3*time.Second)
+}
+
+// RunWithContext runs f in the background and returns its error, or
+// ctx.Err() if the context is done first. In the latter case f is left
+// running and its result is discarded.
+func RunWithContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // TruncateID returns a shorten id
 // Following two functions are from github.com/docker/docker/utils module. It
 // was way overkill to include the whole module, so we just have these bits
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
 func TestCopyFile(t *testing.T) {
@@ -99,3 +102,54 @@ func TestShortenIdInvalid(t *testing.T) {
 		t.Fatalf("Id returned is incorrect: truncate on %s returned %s", id, truncID)
 	}
 }
+
+func TestWaitForContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	err := WaitForSpecificOrErrorContext(ctx, func() (bool, error) {
+		calls++
+		cancel()
+		return false, nil
+	}, 10, time.Hour)
+
+	if err != context.Canceled {
+		t.Fatalf("Expected %s, got %v", context.Canceled, err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestWaitForContextDone(t *testing.T) {
+	calls := 0
+
+	err := WaitForSpecificOrErrorContext(context.Background(), func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	}, 10, time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRunWithContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	blockCh := make(chan struct{})
+	defer close(blockCh)
+
+	err := RunWithContext(ctx, func() error {
+		<-blockCh
+		return nil
+	})
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected %s, got %v", context.DeadlineExceeded, err)
+	}
+}
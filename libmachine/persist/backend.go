@@ -0,0 +1,63 @@
+package persist
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// FileBackend stores each machine in its own config.json file.
+	FileBackend = "file"
+
+	// DbBackend stores every machine in a single database file.
+	DbBackend = "db"
+)
+
+// NewStore returns the store for the given backend. It shares the path, the
+// lock timeout and the key provider of the filestore, which is returned as
+// is for the file backend.
+func NewStore(backend string, filestore *Filestore) (Store, error) {
+	switch backend {
+	case "", FileBackend:
+		return filestore, nil
+	case DbBackend:
+		return &Dbstore{
+			Path:        filestore.Path,
+			LockTimeout: filestore.LockTimeout,
+			KeyProvider: filestore.KeyProvider,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown storage backend %q, expected %s or %s", backend, FileBackend, DbBackend)
+}
+
+// CopyHosts saves every machine of src into dst and returns their names.
+// Machines which already exist in dst are overwritten. Machines are copied
+// as they are stored, their drivers are not loaded. Every machine is loaded
+// before the first one is saved, so that nothing is written if one of them
+// can not be read.
+func CopyHosts(src, dst Store) ([]string, error) {
+	hostNames, err := src.List()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*host.Host{}
+	for _, hostName := range hostNames {
+		h, err := src.Load(hostName)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading machine %q: %s", hostName, err)
+		}
+		hosts = append(hosts, h)
+	}
+
+	for _, h := range hosts {
+		hostName := h.Name
+		if err := dst.Save(h); err != nil {
+			return nil, fmt.Errorf("Error saving machine %q: %s", hostName, err)
+		}
+	}
+
+	return hostNames, nil
+}
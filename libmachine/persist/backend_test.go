@@ -0,0 +1,58 @@
+package persist
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStore(t *testing.T) {
+	filestore := NewFilestore("/tmp/machine", "", "")
+	filestore.LockTimeout = time.Minute
+
+	store, err := NewStore("", filestore)
+	assert.NoError(t, err)
+	assert.Equal(t, filestore, store)
+
+	store, err = NewStore(DbBackend, filestore)
+	assert.NoError(t, err)
+	assert.Equal(t, &Dbstore{Path: "/tmp/machine", LockTimeout: time.Minute}, store)
+
+	_, err = NewStore("bolt", filestore)
+	assert.EqualError(t, err, `Unknown storage backend "bolt", expected file or db`)
+}
+
+func TestCopyHosts(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filestore := NewFilestore(tmpDir, "", "")
+	dbstore := NewDbstore(tmpDir)
+
+	for _, name := range []string{"foo", "bar"} {
+		assert.NoError(t, filestore.Save(getTestHost(t, name)))
+	}
+
+	hostNames, err := CopyHosts(filestore, dbstore)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, hostNames)
+
+	copied, err := dbstore.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, copied)
+
+	h, err := dbstore.Load("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", h.DriverName)
+
+	// Going back overwrites the configuration left in the file store.
+	hostNames, err = CopyHosts(dbstore, filestore)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, hostNames)
+}
@@ -0,0 +1,107 @@
+package persist
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/secret"
+)
+
+// CachedStateSaver is implemented by the stores which record the cached
+// state of a machine without rewriting the rest of its configuration, which
+// another process may have changed since the machine was loaded.
+type CachedStateSaver interface {
+	SaveCachedState(name string, cached *host.CachedState) error
+}
+
+// SaveCachedState records the cached state of the machine name. The stores
+// which can not do it on their own load the machine again and save it with
+// the cached state replaced.
+func SaveCachedState(s Store, name string, cached *host.CachedState) error {
+	if saver, ok := s.(CachedStateSaver); ok {
+		return saver.SaveCachedState(name, cached)
+	}
+
+	h, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+	setCachedState(h, cached)
+
+	return s.Save(h)
+}
+
+// setCachedState replaces the cached state of h, except whether a stop was
+// requested, which only the lifecycle operations change.
+func setCachedState(h *host.Host, cached *host.CachedState) {
+	c := *cached
+	c.StopRequested = h.StopRequested()
+	h.CachedState = &c
+}
+
+// decodeWithCachedState returns the machine serialized in data with its
+// cached state replaced.
+func decodeWithCachedState(name string, data []byte, cached *host.CachedState, storePath string, keys secret.KeyProvider) (*host.Host, error) {
+	decryptedData, err := unmarshalHostData(name, data, storePath, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	h, _, err := host.MigrateHost(&host.Host{Name: name}, decryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting migrated host: %s", err)
+	}
+	h.Name = name
+
+	setCachedState(h, cached)
+
+	return h, nil
+}
+
+// SaveCachedState reads the configuration of the machine again while
+// holding its lock, and saves it with the cached state replaced.
+func (s Filestore) SaveCachedState(name string, cached *host.CachedState) error {
+	return s.withLocks(name, false, true, func() error {
+		data, err := ioutil.ReadFile(filepath.Join(s.GetMachinesDir(), name, "config.json"))
+		if os.IsNotExist(err) {
+			return mcnerror.ErrHostDoesNotExist{Name: name}
+		}
+		if err != nil {
+			return err
+		}
+
+		h, err := decodeWithCachedState(name, data, cached, s.Path, s.KeyProvider)
+		if err != nil {
+			return err
+		}
+
+		return s.save(h)
+	})
+}
+
+// SaveCachedState reads the configuration of the machine again and saves it
+// with the cached state replaced in the same transaction.
+func (s Dbstore) SaveCachedState(name string, cached *host.CachedState) error {
+	return s.Update(func(tx *Tx) error {
+		data := tx.Get(machinesBucket, name)
+		if data == nil {
+			return mcnerror.ErrHostDoesNotExist{Name: name}
+		}
+
+		h, err := decodeWithCachedState(name, data, cached, s.Path, s.KeyProvider)
+		if err != nil {
+			return err
+		}
+
+		data, err = marshalHost(h, s.Path, s.KeyProvider, false)
+		if err != nil {
+			return err
+		}
+
+		return tx.Put(machinesBucket, name, data)
+	})
+}
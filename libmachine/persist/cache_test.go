@@ -0,0 +1,55 @@
+package persist
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSaveCachedState checks that saving the cached state of a machine loaded
+// earlier keeps the changes made to its configuration since.
+func testSaveCachedState(t *testing.T, store Store) {
+	h := getTestHost(t, "dev")
+	h.CachedState = &host.CachedState{State: state.Stopped, StopRequested: true}
+	assert.NoError(t, store.Save(h))
+
+	stale, err := store.Load("dev")
+	assert.NoError(t, err)
+
+	changed, err := store.Load("dev")
+	assert.NoError(t, err)
+	changed.HostOptions.Memory = 4096
+	assert.NoError(t, store.Save(changed))
+
+	assert.NoError(t, SaveCachedState(store, stale.Name, &host.CachedState{State: state.Running, IP: "1.2.3.4"}))
+
+	loaded, err := store.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, 4096, loaded.HostOptions.Memory)
+	assert.Equal(t, state.Running, loaded.CachedState.State)
+	assert.Equal(t, "1.2.3.4", loaded.CachedState.IP)
+	assert.True(t, loaded.StopRequested())
+
+	err = SaveCachedState(store, "missing", &host.CachedState{State: state.Running})
+	assert.Equal(t, mcnerror.ErrHostDoesNotExist{Name: "missing"}, err)
+}
+
+func TestStoreSaveCachedState(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+	defer os.RemoveAll(store.Path)
+
+	testSaveCachedState(t, store)
+}
+
+func TestDbstoreSaveCachedState(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	testSaveCachedState(t, store)
+}
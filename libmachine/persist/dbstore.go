@@ -0,0 +1,386 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/secret"
+)
+
+const (
+	// DbFileName is the name of the database file in the storage path.
+	DbFileName = "machines.db"
+
+	dbVersion = 1
+
+	machinesBucket = "machines"
+	backupsBucket  = "backups"
+	metaBucket     = "meta"
+	versionKey     = "version"
+)
+
+var (
+	errDbFromFuture = errors.New("Database version is from the future, please upgrade your Docker Machine client.")
+	errTxReadOnly   = errors.New("Cannot write in a read-only transaction")
+)
+
+// Dbstore keeps the configuration of every machine in a single bolt
+// database file, indexed by machine name, which makes listing many machines
+// cheap and lets concurrent invocations update the store without stepping on
+// each other. The machine directories are still used for the files created
+// by the drivers, such as SSH keys and disks.
+type Dbstore struct {
+	Path string
+
+	// LockTimeout is how long to wait for other processes to release the
+	// database. Zero means DefaultLockTimeout.
+	LockTimeout time.Duration
+
+	// KeyProvider, if set, is used to encrypt the credentials of the
+	// drivers when machines are saved. It is also needed to load machines
+	// which have been saved encrypted.
+	KeyProvider secret.KeyProvider
+}
+
+func NewDbstore(path string) *Dbstore {
+	return &Dbstore{
+		Path: path,
+	}
+}
+
+func (s Dbstore) GetMachinesDir() string {
+	return filepath.Join(s.Path, "machines")
+}
+
+func (s Dbstore) GetDbPath() string {
+	return filepath.Join(s.Path, DbFileName)
+}
+
+// Tx is a transaction on the database. Writes are only visible to other
+// transactions once the function given to Update returns successfully.
+type Tx struct {
+	// tx is nil in a read-only transaction on a database which does not
+	// exist yet.
+	tx *bolt.Tx
+}
+
+// Get returns the value of key in bucket, nil if there is none.
+func (tx *Tx) Get(bucket, key string) []byte {
+	if tx.tx == nil {
+		return nil
+	}
+
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	value := b.Get([]byte(key))
+	if value == nil {
+		return nil
+	}
+
+	// The value is only valid during the transaction.
+	return append([]byte{}, value...)
+}
+
+func (tx *Tx) Put(bucket, key string, value []byte) error {
+	if tx.tx == nil || !tx.tx.Writable() {
+		return errTxReadOnly
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return fmt.Errorf("Invalid value for key %q in bucket %q", key, bucket)
+	}
+
+	b, err := tx.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte(key), value)
+}
+
+func (tx *Tx) Delete(bucket, key string) error {
+	if tx.tx == nil || !tx.tx.Writable() {
+		return errTxReadOnly
+	}
+
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	return b.Delete([]byte(key))
+}
+
+// Keys returns the sorted keys of the bucket.
+func (tx *Tx) Keys(bucket string) []string {
+	keys := []string{}
+	if tx.tx == nil {
+		return keys
+	}
+
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return keys
+	}
+
+	b.ForEach(func(key, _ []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+
+	return keys
+}
+
+// View runs fn in a read-only transaction. Other readers are allowed to run
+// at the same time, writers are not.
+func (s Dbstore) View(fn func(tx *Tx) error) error {
+	return s.withDb(false, func(db *bolt.DB) error {
+		if db == nil {
+			return fn(&Tx{})
+		}
+
+		return db.View(func(tx *bolt.Tx) error {
+			return fn(&Tx{tx: tx})
+		})
+	})
+}
+
+// Update runs fn in a read-write transaction. The changes are written to
+// the database only if fn returns nil, and they are written atomically so
+// that a crash never leaves a half written database behind.
+func (s Dbstore) Update(fn func(tx *Tx) error) error {
+	return s.withDb(true, func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			return fn(&Tx{tx: tx})
+		})
+	})
+}
+
+// withDb opens the database, read-only unless writable, and runs fn with it.
+// A read-only database which does not exist yet is nil.
+func (s Dbstore) withDb(writable bool, fn func(db *bolt.DB) error) error {
+	return s.withLock(writable, func() error {
+		if _, err := os.Stat(s.GetDbPath()); os.IsNotExist(err) && !writable {
+			return fn(nil)
+		}
+
+		timeout := s.LockTimeout
+		if timeout <= 0 {
+			timeout = DefaultLockTimeout
+		}
+
+		db, err := bolt.Open(s.GetDbPath(), 0600, &bolt.Options{
+			Timeout:  timeout,
+			ReadOnly: !writable,
+		})
+		if err != nil {
+			return fmt.Errorf("Error opening database %s: %s", s.GetDbPath(), err)
+		}
+		defer db.Close()
+
+		if writable {
+			err = db.Update(initDb)
+		} else {
+			err = db.View(checkVersion)
+		}
+		if err != nil {
+			return err
+		}
+
+		return fn(db)
+	})
+}
+
+// initDb creates the buckets of a new database, and checks the version of
+// an existing one.
+func initDb(tx *bolt.Tx) error {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta != nil {
+		return checkVersion(tx)
+	}
+
+	meta, err := tx.CreateBucket([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range []string{machinesBucket, backupsBucket} {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+			return err
+		}
+	}
+
+	return meta.Put([]byte(versionKey), []byte(strconv.Itoa(dbVersion)))
+}
+
+func checkVersion(tx *bolt.Tx) error {
+	meta := tx.Bucket([]byte(metaBucket))
+	if meta == nil {
+		return nil
+	}
+
+	version, err := strconv.Atoi(string(meta.Get([]byte(versionKey))))
+	if err != nil {
+		return fmt.Errorf("Invalid database version: %s", err)
+	}
+
+	if version > dbVersion {
+		return errDbFromFuture
+	}
+
+	return nil
+}
+
+func (s Dbstore) withLock(exclusive bool, fn func() error) error {
+	if err := os.MkdirAll(s.Path, 0700); err != nil {
+		return err
+	}
+
+	lock, err := acquireLock(s.GetDbPath()+".lock", exclusive, s.LockTimeout)
+	if err != nil {
+		if locked, ok := err.(errLocked); ok {
+			return mcnerror.ErrStoreBusy{Pid: locked.Pid}
+		}
+		return fmt.Errorf("Error locking the database: %s", err)
+	}
+	defer lock.Release()
+
+	return fn()
+}
+
+func (s Dbstore) Save(host *host.Host) error {
+	data, err := marshalHost(host, s.Path, s.KeyProvider, false)
+	if err != nil {
+		return err
+	}
+
+	// The drivers expect the machine directory to exist.
+	if err := os.MkdirAll(filepath.Join(s.GetMachinesDir(), host.Name), 0700); err != nil {
+		return err
+	}
+
+	return s.Update(func(tx *Tx) error {
+		return tx.Put(machinesBucket, host.Name, data)
+	})
+}
+
+func (s Dbstore) Remove(name string) error {
+	if err := s.Update(func(tx *Tx) error {
+		if err := tx.Delete(machinesBucket, name); err != nil {
+			return err
+		}
+		return tx.Delete(backupsBucket, name)
+	}); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(s.GetMachinesDir(), name))
+}
+
+// MoveDir moves the directory of the machine oldName to the one of newName,
+// which must not exist. The configuration of the machine is saved under its
+// new name separately.
+func (s Dbstore) MoveDir(oldName, newName string) error {
+	return s.withLock(true, func() error {
+		oldDir := filepath.Join(s.GetMachinesDir(), oldName)
+		newDir := filepath.Join(s.GetMachinesDir(), newName)
+
+		if _, err := os.Stat(newDir); err == nil {
+			return fmt.Errorf("Directory %s already exists", newDir)
+		}
+
+		return os.Rename(oldDir, newDir)
+	})
+}
+
+func (s Dbstore) List() ([]string, error) {
+	hostNames := []string{}
+
+	err := s.View(func(tx *Tx) error {
+		hostNames = tx.Keys(machinesBucket)
+		return nil
+	})
+
+	return hostNames, err
+}
+
+func (s Dbstore) Exists(name string) (bool, error) {
+	exists := false
+
+	err := s.View(func(tx *Tx) error {
+		exists = tx.Get(machinesBucket, name) != nil
+		return nil
+	})
+
+	return exists, err
+}
+
+func (s Dbstore) Load(name string) (*host.Host, error) {
+	var data []byte
+
+	if err := s.View(func(tx *Tx) error {
+		data = tx.Get(machinesBucket, name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if data == nil {
+		return nil, mcnerror.ErrHostDoesNotExist{
+			Name: name,
+		}
+	}
+
+	h := &host.Host{
+		Name: name,
+	}
+
+	decryptedData, err := unmarshalHostData(name, data, s.Path, s.KeyProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	migratedHost, migrationPerformed, err := host.MigrateHost(h, decryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting migrated host: %s", err)
+	}
+
+	*h = *migratedHost
+
+	h.Name = name
+
+	// If we end up performing a migration, we should save afterwards so we
+	// don't have to do it again on subsequent invocations.
+	if migrationPerformed {
+		migratedData, err := marshalHost(h, s.Path, s.KeyProvider, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.Update(func(tx *Tx) error {
+			if err := tx.Put(backupsBucket, name, data); err != nil {
+				return fmt.Errorf("Error attempting to save backup after migration: %s", err)
+			}
+			if err := tx.Put(machinesBucket, name, migratedData); err != nil {
+				return fmt.Errorf("Error saving config after migration was performed: %s", err)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
@@ -0,0 +1,225 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestDbstore(t *testing.T) *Dbstore {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewDbstore(tmpDir)
+}
+
+func getTestHost(t *testing.T, name string) *host.Host {
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Name = name
+	return h
+}
+
+func TestDbstoreSaveLoad(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, store.Save(h))
+
+	_, err = os.Stat(filepath.Join(store.GetMachinesDir(), h.Name))
+	assert.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(store.GetMachinesDir(), h.Name, "config.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	loaded, err := store.Load(h.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, h.Name, loaded.Name)
+	assert.Equal(t, h.DriverName, loaded.DriverName)
+	assert.IsType(t, &host.RawDataDriver{}, loaded.Driver)
+}
+
+func TestDbstoreLoadMissing(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	_, err := store.Load("missing")
+
+	assert.Equal(t, mcnerror.ErrHostDoesNotExist{Name: "missing"}, err)
+}
+
+func TestDbstoreListExistsRemove(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	for _, name := range []string{"foo", "bar"} {
+		assert.NoError(t, store.Save(getTestHost(t, name)))
+	}
+
+	hostNames, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, hostNames)
+
+	exists, err := store.Exists("foo")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, store.Remove("foo"))
+
+	exists, err = store.Exists("foo")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = os.Stat(filepath.Join(store.GetMachinesDir(), "foo"))
+	assert.True(t, os.IsNotExist(err))
+
+	hostNames, err = store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, hostNames)
+}
+
+func TestDbstoreMoveDir(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	assert.NoError(t, store.Save(getTestHost(t, "foo")))
+	assert.NoError(t, store.Save(getTestHost(t, "bar")))
+
+	assert.Error(t, store.MoveDir("foo", "bar"))
+	assert.NoError(t, store.MoveDir("foo", "baz"))
+
+	_, err := os.Stat(filepath.Join(store.GetMachinesDir(), "foo"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(store.GetMachinesDir(), "baz"))
+	assert.NoError(t, err)
+}
+
+func TestDbstoreUpdateRollback(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	expectedErr := errors.New("rollback")
+
+	err := store.Update(func(tx *Tx) error {
+		if err := tx.Put(machinesBucket, "foo", []byte(`{}`)); err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	assert.Equal(t, expectedErr, err)
+
+	exists, err := store.Exists("foo")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDbstoreViewIsReadOnly(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	err := store.View(func(tx *Tx) error {
+		return tx.Put(machinesBucket, "foo", []byte(`{}`))
+	})
+
+	assert.Equal(t, errTxReadOnly, err)
+}
+
+func TestDbstoreConcurrentSaves(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			h := getTestHost(t, fmt.Sprintf("host-%d", i))
+
+			// Every save goes through its own store, like separate
+			// invocations of the CLI would.
+			if err := NewDbstore(store.Path).Save(h); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	hostNames, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, hostNames, 10)
+}
+
+func TestDbstoreLoadMigratesHost(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	v2Data := []byte(`{
+    "ConfigVersion": 2,
+    "Driver": {"MachineName": "default"},
+    "DriverName": "virtualbox",
+    "HostOptions": {
+        "AuthOptions": {
+            "StorePath": "/tmp/machine/machines/default"
+        }
+    },
+    "StorePath": "/tmp/machine/machines/default",
+    "Name": "default"
+}`)
+
+	assert.NoError(t, store.Update(func(tx *Tx) error {
+		return tx.Put(machinesBucket, "default", v2Data)
+	}))
+
+	h, err := store.Load("default")
+	assert.NoError(t, err)
+	assert.Equal(t, version.ConfigVersion, h.ConfigVersion)
+
+	assert.NoError(t, store.View(func(tx *Tx) error {
+		var backup, saved host.Metadata
+		if err := json.Unmarshal(tx.Get(backupsBucket, "default"), &backup); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(tx.Get(machinesBucket, "default"), &saved); err != nil {
+			return err
+		}
+
+		assert.Equal(t, 2, backup.ConfigVersion)
+		assert.Equal(t, version.ConfigVersion, saved.ConfigVersion)
+
+		return nil
+	}))
+}
+
+func TestDbstoreFromFuture(t *testing.T) {
+	store := getTestDbstore(t)
+	defer os.RemoveAll(store.Path)
+
+	assert.NoError(t, store.Update(func(tx *Tx) error {
+		return tx.tx.Bucket([]byte(metaBucket)).Put([]byte(versionKey), []byte("42"))
+	}))
+
+	_, err := store.List()
+
+	assert.Equal(t, errDbFromFuture, err)
+}
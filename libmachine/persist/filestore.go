@@ -1,21 +1,33 @@
 package persist
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/secret"
 )
 
 type Filestore struct {
 	Path             string
 	CaCertPath       string
 	CaPrivateKeyPath string
+
+	// LockTimeout is how long to wait for other processes to release a
+	// machine or the store. Zero means DefaultLockTimeout.
+	LockTimeout time.Duration
+
+	// KeyProvider, if set, is used to encrypt the credentials of the
+	// drivers when machines are saved. It is also needed to load machines
+	// which have been saved encrypted.
+	KeyProvider secret.KeyProvider
 }
 
 func NewFilestore(path, caCertPath, caPrivateKeyPath string) *Filestore {
@@ -30,6 +42,51 @@ func (s Filestore) GetMachinesDir() string {
 	return filepath.Join(s.Path, "machines")
 }
 
+// The lock files live next to the machine directories rather than inside
+// them so that removing a machine does not remove a lock in use. They are
+// hidden so that List ignores them.
+func (s Filestore) storeLockPath() string {
+	return filepath.Join(s.GetMachinesDir(), ".lock")
+}
+
+func (s Filestore) hostLockPath(name string) string {
+	return filepath.Join(s.GetMachinesDir(), "."+name+".lock")
+}
+
+// withLocks runs fn while holding the store-wide lock and, if name is not
+// empty, the lock of the machine. Locks are always taken in this order so
+// that processes can not deadlock. The store-wide lock is taken exclusively
+// by the operations which add or remove machines, and shared otherwise.
+func (s Filestore) withLocks(name string, exclusiveStore, exclusiveHost bool, fn func() error) error {
+	if err := os.MkdirAll(s.GetMachinesDir(), 0700); err != nil {
+		return err
+	}
+
+	storeLock, err := acquireLock(s.storeLockPath(), exclusiveStore, s.LockTimeout)
+	if err != nil {
+		if locked, ok := err.(errLocked); ok {
+			return mcnerror.ErrStoreBusy{Pid: locked.Pid}
+		}
+		return fmt.Errorf("Error locking the store: %s", err)
+	}
+	defer storeLock.Release()
+
+	if name == "" {
+		return fn()
+	}
+
+	hostLock, err := acquireLock(s.hostLockPath(name), exclusiveHost, s.LockTimeout)
+	if err != nil {
+		if locked, ok := err.(errLocked); ok {
+			return mcnerror.ErrHostBusy{Name: name, Pid: locked.Pid}
+		}
+		return fmt.Errorf("Error locking machine %q: %s", name, err)
+	}
+	defer hostLock.Release()
+
+	return fn()
+}
+
 func (s Filestore) saveToFile(data []byte, file string) error {
 	if _, err := os.Stat(file); os.IsNotExist(err) {
 		return ioutil.WriteFile(file, data, 0600)
@@ -49,18 +106,48 @@ func (s Filestore) saveToFile(data []byte, file string) error {
 		return err
 	}
 
-	if err = os.Remove(file); err != nil {
-		return err
-	}
-
+	// Renaming over the existing file is atomic, readers either see the
+	// old or the new content. Windows may refuse to replace the file, in
+	// which case it has to be removed first.
 	if err = os.Rename(tmpfi.Name(), file); err != nil {
-		return err
+		if runtime.GOOS != "windows" {
+			return err
+		}
+		if err = os.Remove(file); err != nil {
+			return err
+		}
+		return os.Rename(tmpfi.Name(), file)
 	}
 	return nil
 }
 
+// errNewHost is returned by the first attempt to save a machine which does
+// not exist yet, which is then saved with the store-wide lock held
+// exclusively.
+var errNewHost = errors.New("new machine")
+
 func (s Filestore) Save(host *host.Host) error {
-	data, err := json.MarshalIndent(host, "", "    ")
+	err := s.withLocks(host.Name, false, true, func() error {
+		exists, err := s.Exists(host.Name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errNewHost
+		}
+		return s.save(host)
+	})
+	if err != errNewHost {
+		return err
+	}
+
+	return s.withLocks(host.Name, true, true, func() error {
+		return s.save(host)
+	})
+}
+
+func (s Filestore) save(host *host.Host) error {
+	data, err := marshalHost(host, s.Path, s.KeyProvider, true)
 	if err != nil {
 		return err
 	}
@@ -75,14 +162,60 @@ func (s Filestore) Save(host *host.Host) error {
 	return s.saveToFile(data, filepath.Join(hostPath, "config.json"))
 }
 
+// Remove removes the machine directory and its lock file. Holding the
+// store-wide lock exclusively, no other process holds or waits for the lock
+// of the machine, since it is always taken after the store-wide one.
 func (s Filestore) Remove(name string) error {
-	hostPath := filepath.Join(s.GetMachinesDir(), name)
-	return os.RemoveAll(hostPath)
+	return s.withLocks("", true, false, func() error {
+		hostPath := filepath.Join(s.GetMachinesDir(), name)
+		if err := os.RemoveAll(hostPath); err != nil {
+			return err
+		}
+
+		if err := os.Remove(s.hostLockPath(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// MoveDir moves the directory of the machine oldName to the one of newName,
+// which must not exist, and removes the lock file of oldName. It holds the
+// store-wide lock exclusively, like Remove.
+func (s Filestore) MoveDir(oldName, newName string) error {
+	return s.withLocks("", true, false, func() error {
+		oldDir := filepath.Join(s.GetMachinesDir(), oldName)
+		newDir := filepath.Join(s.GetMachinesDir(), newName)
+
+		if _, err := os.Stat(newDir); err == nil {
+			return fmt.Errorf("Directory %s already exists", newDir)
+		}
+
+		if err := os.Rename(oldDir, newDir); err != nil {
+			return err
+		}
+
+		if err := os.Remove(s.hostLockPath(oldName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (s Filestore) List() ([]string, error) {
-	dir, err := ioutil.ReadDir(s.GetMachinesDir())
-	if err != nil && !os.IsNotExist(err) {
+	if _, err := os.Stat(s.GetMachinesDir()); os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	var dir []os.FileInfo
+
+	if err := s.withLocks("", false, false, func() error {
+		var err error
+		dir, err = ioutil.ReadDir(s.GetMachinesDir())
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -110,8 +243,13 @@ func (s Filestore) Exists(name string) (bool, error) {
 }
 
 func (s Filestore) loadConfig(h *host.Host) error {
-	data, err := ioutil.ReadFile(filepath.Join(s.GetMachinesDir(), h.Name, "config.json"))
-	if err != nil {
+	var data []byte
+
+	if err := s.withLocks(h.Name, false, false, func() error {
+		var err error
+		data, err = ioutil.ReadFile(filepath.Join(s.GetMachinesDir(), h.Name, "config.json"))
+		return err
+	}); err != nil {
 		return err
 	}
 
@@ -119,7 +257,12 @@ func (s Filestore) loadConfig(h *host.Host) error {
 	// struct in the migration.
 	name := h.Name
 
-	migratedHost, migrationPerformed, err := host.MigrateHost(h, data)
+	decryptedData, err := unmarshalHostData(h.Name, data, s.Path, s.KeyProvider)
+	if err != nil {
+		return err
+	}
+
+	migratedHost, migrationPerformed, err := host.MigrateHost(h, decryptedData)
 	if err != nil {
 		return fmt.Errorf("Error getting migrated host: %s", err)
 	}
@@ -130,13 +273,17 @@ func (s Filestore) loadConfig(h *host.Host) error {
 
 	// If we end up performing a migration, we should save afterwards so we don't have to do it again on subsequent invocations.
 	if migrationPerformed {
-		if err := s.saveToFile(data, filepath.Join(s.GetMachinesDir(), h.Name, "config.json.bak")); err != nil {
-			return fmt.Errorf("Error attempting to save backup after migration: %s", err)
-		}
+		return s.withLocks(h.Name, false, true, func() error {
+			if err := s.saveToFile(data, filepath.Join(s.GetMachinesDir(), h.Name, "config.json.bak")); err != nil {
+				return fmt.Errorf("Error attempting to save backup after migration: %s", err)
+			}
 
-		if err := s.Save(h); err != nil {
-			return fmt.Errorf("Error saving config after migration was performed: %s", err)
-		}
+			if err := s.save(h); err != nil {
+				return fmt.Errorf("Error saving config after migration was performed: %s", err)
+			}
+
+			return nil
+		})
 	}
 
 	return nil
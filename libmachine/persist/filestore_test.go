@@ -7,12 +7,15 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/drivers/none"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
 )
 
 func cleanup() {
@@ -131,6 +134,49 @@ func TestStoreRemove(t *testing.T) {
 	if _, err := os.Stat(path); err == nil {
 		t.Fatalf("Host path still exists after remove: %s", path)
 	}
+
+	if _, err := os.Stat(store.hostLockPath(h.Name)); !os.IsNotExist(err) {
+		t.Fatalf("Host lock file still exists after remove: %s", store.hostLockPath(h.Name))
+	}
+}
+
+func TestStoreMoveDir(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MoveDir(h.Name, "moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.GetMachinesDir(), "moved", "config.json")); err != nil {
+		t.Fatalf("Host config was not moved: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.GetMachinesDir(), h.Name)); !os.IsNotExist(err) {
+		t.Fatalf("Host path still exists after move: %s", err)
+	}
+
+	if _, err := os.Stat(store.hostLockPath(h.Name)); !os.IsNotExist(err) {
+		t.Fatalf("Host lock file still exists after move: %s", store.hostLockPath(h.Name))
+	}
+
+	if err := os.MkdirAll(filepath.Join(store.GetMachinesDir(), h.Name), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MoveDir("moved", h.Name); err == nil {
+		t.Fatal("Expected an error moving the directory over an existing one")
+	}
 }
 
 func TestStoreList(t *testing.T) {
@@ -247,3 +293,142 @@ func TestStoreLoad(t *testing.T) {
 		t.Fatalf("GetURL is not %q, got %q", expectedURL, actualURL)
 	}
 }
+
+func TestStoreLoadBusy(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+	store.LockTimeout = 10 * time.Millisecond
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireLock(store.hostLockPath(h.Name), true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	_, err = store.Load(h.Name)
+
+	expectedErr := mcnerror.ErrHostBusy{Name: h.Name, Pid: os.Getpid()}
+	if err != expectedErr {
+		t.Fatalf("Expected error %q, got %v", expectedErr, err)
+	}
+
+	if expectedErr.Error() != fmt.Sprintf("Machine %q is busy (locked by pid %d)", h.Name, os.Getpid()) {
+		t.Fatalf("Unexpected error message: %s", expectedErr)
+	}
+}
+
+func TestStoreListBusy(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+	store.LockTimeout = 10 * time.Millisecond
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := acquireLock(store.storeLockPath(), true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	if _, err := store.List(); err != (mcnerror.ErrStoreBusy{Pid: os.Getpid()}) {
+		t.Fatalf("Expected the store to be busy, got %v", err)
+	}
+}
+
+func TestStoreListIgnoresLocks(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hosts) != 1 || hosts[0] != h.Name {
+		t.Fatalf("Expected only %q, got %v", h.Name, hosts)
+	}
+}
+
+type fakeKeyProvider []byte
+
+func (k fakeKeyProvider) Key() ([]byte, error) {
+	return k, nil
+}
+
+func TestStoreEncryptedCredentials(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+	store.KeyProvider = fakeKeyProvider("0123456789abcdef0123456789abcdef")
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.DriverName = "openstack"
+	h.Driver = &host.RawDataDriver{Data: []byte(`{"MachineName":"test-host","Username":"user","Password":"hunter2"}`)}
+
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	configData, err := ioutil.ReadFile(filepath.Join(store.GetMachinesDir(), h.Name, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(configData), "hunter2") {
+		t.Fatal("Expected the password to be encrypted in config.json")
+	}
+
+	if !strings.Contains(string(configData), `"Username": "user"`) {
+		t.Fatal("Expected the other fields to be kept in plaintext in config.json")
+	}
+
+	loaded, err := store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(loaded.RawDriver), `"Password":"hunter2"`) {
+		t.Fatalf("Expected the password to be decrypted, got %s", loaded.RawDriver)
+	}
+
+	store.KeyProvider = nil
+	if _, err := store.Load(h.Name); err == nil {
+		t.Fatal("Expected an error loading encrypted credentials without a key")
+	}
+
+	store.KeyProvider = fakeKeyProvider("abcdef0123456789abcdef0123456789")
+	if _, err := store.Load(h.Name); err == nil {
+		t.Fatal("Expected an error loading encrypted credentials with the wrong key")
+	}
+}
@@ -0,0 +1,28 @@
+// +build !windows
+
+package persist
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile attempts to lock f without blocking. It returns false if the
+// lock is held by someone else.
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,53 @@
+package persist
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+
+	errorLockViolation syscall.Errno = 33
+
+	// Windows locks are mandatory, so lock a byte far past the content of
+	// the lock file to keep the pid it records readable by everyone.
+	lockOffsetHigh = 1
+)
+
+var (
+	modkernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modkernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+)
+
+// tryLockFile attempts to lock f without blocking. It returns false if the
+// lock is held by someone else.
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	var flags uintptr = lockfileFailImmediately
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+
+	ol := &syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	r, _, err := procLockFileEx.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		if err == errorLockViolation {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func unlockFile(f *os.File) error {
+	ol := &syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package persist
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long the stores wait for a lock held by another
+// process before giving up.
+const DefaultLockTimeout = 10 * time.Second
+
+var lockRetryInterval = 50 * time.Millisecond
+
+// errLocked is returned when a lock could not be acquired in time. Pid is
+// the process which holds it, as recorded in the lock file.
+type errLocked struct {
+	Pid int
+}
+
+func (e errLocked) Error() string {
+	return "locked by pid " + strconv.Itoa(e.Pid)
+}
+
+// fileLock is an advisory lock on a file shared by every process using the
+// same store. Removing a file which another process is waiting on would let
+// two processes hold the lock, so the lock file of a machine is only removed
+// along with the machine, by the holder of the store-wide lock.
+type fileLock struct {
+	file      *os.File
+	exclusive bool
+}
+
+// acquireLock locks the file at path, creating it if needed. Shared locks
+// can be held by several processes at the same time, exclusive locks can
+// not. It waits at most timeout for the lock to be released, or
+// DefaultLockTimeout if timeout is zero.
+func acquireLock(path string, exclusive bool, timeout time.Duration) (*fileLock, error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, errLocked{Pid: lockHolder(path)}
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	// Record who holds the lock so that the processes waiting for it can
+	// tell the user. Shared locks have several holders, none of which is
+	// recorded.
+	if exclusive {
+		if err := f.Truncate(0); err == nil {
+			f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+		}
+	}
+
+	return &fileLock{file: f, exclusive: exclusive}, nil
+}
+
+func (l *fileLock) Release() error {
+	defer l.file.Close()
+
+	// Not to be taken for the holder of the shared locks taken next.
+	if l.exclusive {
+		l.file.Truncate(0)
+	}
+
+	return unlockFile(l.file)
+}
+
+func lockHolder(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
@@ -0,0 +1,86 @@
+package persist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestLockPath(t *testing.T) (string, func()) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(tmpDir, ".lock"), func() { os.RemoveAll(tmpDir) }
+}
+
+func TestAcquireLockExclusive(t *testing.T) {
+	path, cleanup := getTestLockPath(t)
+	defer cleanup()
+
+	lock, err := acquireLock(path, true, time.Second)
+	assert.NoError(t, err)
+
+	_, err = acquireLock(path, false, 10*time.Millisecond)
+	assert.Equal(t, errLocked{Pid: os.Getpid()}, err)
+
+	assert.NoError(t, lock.Release())
+
+	lock, err = acquireLock(path, true, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestAcquireLockShared(t *testing.T) {
+	path, cleanup := getTestLockPath(t)
+	defer cleanup()
+
+	first, err := acquireLock(path, false, time.Second)
+	assert.NoError(t, err)
+	defer first.Release()
+
+	second, err := acquireLock(path, false, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer second.Release()
+
+	_, err = acquireLock(path, true, 10*time.Millisecond)
+	assert.Equal(t, errLocked{}, err)
+}
+
+func TestAcquireLockSharedAfterExclusive(t *testing.T) {
+	path, cleanup := getTestLockPath(t)
+	defer cleanup()
+
+	lock, err := acquireLock(path, true, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), lockHolder(path))
+	assert.NoError(t, lock.Release())
+
+	shared, err := acquireLock(path, false, time.Second)
+	assert.NoError(t, err)
+	defer shared.Release()
+
+	assert.Equal(t, 0, lockHolder(path))
+}
+
+func TestAcquireLockWaits(t *testing.T) {
+	path, cleanup := getTestLockPath(t)
+	defer cleanup()
+
+	lock, err := acquireLock(path, true, time.Second)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Release()
+	}()
+
+	next, err := acquireLock(path, true, 5*time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, next.Release())
+}
@@ -0,0 +1,308 @@
+package persist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// StorePathMarker replaces the storage path at the start of the paths saved
+// in the configuration of the machines, so that the store keeps working
+// once it is moved. Paths are resolved against the actual storage path when
+// the machines are loaded.
+const StorePathMarker = "$MACHINE_STORAGE_PATH"
+
+// pathFields are the fields of the serialized configuration of a machine
+// which hold paths, possibly under the storage path. The fields of a driver
+// also hold paths when its configuration is serialized alone, as it is sent
+// to a remote server.
+var pathFields = []string{
+	"SSHKeyPath",
+	"StorePath",
+	"Driver.SSHKeyPath",
+	"Driver.StorePath",
+	"HostOptions.AuthOptions.CertDir",
+	"HostOptions.AuthOptions.CaCertPath",
+	"HostOptions.AuthOptions.CaPrivateKeyPath",
+	"HostOptions.AuthOptions.ServerCertPath",
+	"HostOptions.AuthOptions.ServerKeyPath",
+	"HostOptions.AuthOptions.ClientKeyPath",
+	"HostOptions.AuthOptions.ClientCertPath",
+	"HostOptions.AuthOptions.StorePath",
+}
+
+// RewritePaths replaces the oldRoot prefix by newRoot in the path fields of
+// the serialized configuration of a machine, e.g. the paths to its
+// certificates and SSH key, or the store path of its driver. The old root may
+// come from another operating system, so both kinds of separators are
+// understood.
+func RewritePaths(data []byte, oldRoot, newRoot string) ([]byte, error) {
+	return rewritePathFields(data, func(path string) string {
+		return RewritePath(path, oldRoot, newRoot)
+	})
+}
+
+// RelativizePaths replaces the storage path by StorePathMarker in the path
+// fields of the serialized configuration of a machine. The rest of the paths
+// is written with forward slashes whatever the operating system.
+func RelativizePaths(data []byte, storePath string) ([]byte, error) {
+	return rewritePathFields(data, func(path string) string {
+		return relativizePath(path, storePath)
+	})
+}
+
+// ResolvePaths replaces StorePathMarker by the storage path in the path
+// fields of the serialized configuration of a machine.
+func ResolvePaths(data []byte, storePath string) ([]byte, error) {
+	if !bytes.Contains(data, []byte(StorePathMarker)) {
+		return data, nil
+	}
+
+	return rewritePathFields(data, func(path string) string {
+		return resolvePath(path, storePath)
+	})
+}
+
+// OutsidePaths returns the paths of the serialized configuration of a machine
+// or of a driver which are not under the storage path. Empty paths are not
+// returned, the default ones being under the storage path.
+func OutsidePaths(data []byte, storePath string) ([]string, error) {
+	root := filepath.Clean(storePath)
+	outside := []string{}
+
+	_, err := rewritePathFields(data, func(path string) string {
+		if path == "" {
+			return path
+		}
+
+		cleaned := filepath.Clean(path)
+		if !filepath.IsAbs(cleaned) || (cleaned != root && !strings.HasPrefix(cleaned, root+string(filepath.Separator))) {
+			outside = append(outside, path)
+		}
+
+		return path
+	})
+
+	return outside, err
+}
+
+func relativizePath(path, storePath string) string {
+	root := strings.TrimRight(toSlash(storePath), "/")
+	if root == "" {
+		return path
+	}
+
+	normalized := toSlash(path)
+
+	if normalized == root {
+		return StorePathMarker
+	}
+
+	if strings.HasPrefix(normalized, root+"/") {
+		return StorePathMarker + normalized[len(root):]
+	}
+
+	return path
+}
+
+func resolvePath(path, storePath string) string {
+	if path == StorePathMarker {
+		return storePath
+	}
+
+	if strings.HasPrefix(path, StorePathMarker+"/") {
+		return filepath.Join(storePath, filepath.FromSlash(path[len(StorePathMarker)+1:]))
+	}
+
+	return path
+}
+
+// rewritePathFields rewrites the path fields of data. The JSON is copied
+// token by token so that the order of the keys and the numbers are kept as
+// they are written.
+func rewritePathFields(data []byte, rewrite func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var buf bytes.Buffer
+	if err := copyValue(decoder, &buf, "", rewrite); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// copyValue copies the next value of decoder to buf. field is the dotted path
+// of the value in the document.
+func copyValue(decoder *json.Decoder, buf *bytes.Buffer, field string, rewrite func(string) string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		return copyContainer(decoder, buf, field, t, rewrite)
+	case string:
+		if isPathField(field) {
+			t = rewrite(t)
+		}
+		return writeJSON(buf, t)
+	default:
+		return writeJSON(buf, t)
+	}
+}
+
+func copyContainer(decoder *json.Decoder, buf *bytes.Buffer, field string, delim json.Delim, rewrite func(string) string) error {
+	closing := byte(']')
+	if delim == '{' {
+		closing = '}'
+	}
+
+	buf.WriteByte(byte(delim))
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		// The elements of arrays are not path fields.
+		child := field + "[]"
+		if delim == '{' {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+
+			child = key.(string)
+			if field != "" {
+				child = field + "." + child
+			}
+		}
+
+		if err := copyValue(decoder, buf, child, rewrite); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing delimiter.
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte(closing)
+
+	return nil
+}
+
+func isPathField(field string) bool {
+	for _, pathField := range pathFields {
+		if field == pathField {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(buf *bytes.Buffer, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+// RewritePath replaces the oldRoot prefix of path by newRoot. The path is
+// returned as is if it is not under oldRoot.
+func RewritePath(path, oldRoot, newRoot string) string {
+	root := strings.TrimRight(toSlash(oldRoot), "/")
+	if root == "" {
+		return path
+	}
+
+	normalized := toSlash(path)
+
+	if normalized == root {
+		return newRoot
+	}
+
+	if strings.HasPrefix(normalized, root+"/") {
+		return filepath.Join(newRoot, filepath.FromSlash(normalized[len(root)+1:]))
+	}
+
+	return path
+}
+
+func toSlash(path string) string {
+	return strings.Replace(path, "\\", "/", -1)
+}
+
+// Relocate saves every machine of the store again with its paths relative to
+// storePath, and returns their names. The paths of a machine which are under
+// oldRoot are first moved under storePath. If oldRoot is empty, the storage
+// path the machine was last saved with is used, as recorded by its driver.
+func Relocate(store Store, storePath, oldRoot string) ([]string, error) {
+	hostNames, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hostName := range hostNames {
+		h, err := store.Load(hostName)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading machine %q: %s", hostName, err)
+		}
+
+		from := oldRoot
+		if from == "" {
+			from = savedStorePath(h)
+		}
+
+		if from != "" {
+			data, err := json.Marshal(h)
+			if err != nil {
+				return nil, err
+			}
+
+			if data, err = RewritePaths(data, from, storePath); err != nil {
+				return nil, fmt.Errorf("Error rewriting the paths of machine %q: %s", hostName, err)
+			}
+
+			if h, _, err = host.MigrateHost(&host.Host{Name: hostName}, data); err != nil {
+				return nil, fmt.Errorf("Error reading machine %q: %s", hostName, err)
+			}
+			h.Name = hostName
+		}
+
+		if err := store.Save(h); err != nil {
+			return nil, fmt.Errorf("Error saving machine %q: %s", hostName, err)
+		}
+	}
+
+	return hostNames, nil
+}
+
+// savedStorePath returns the storage path recorded in the configuration of
+// the driver, or guesses it from the directory of the machine.
+func savedStorePath(h *host.Host) string {
+	var driver struct {
+		StorePath string
+	}
+
+	if raw, ok := h.Driver.(*host.RawDataDriver); ok && json.Unmarshal(raw.Data, &driver) == nil && driver.StorePath != "" {
+		return driver.StorePath
+	}
+
+	if authOptions := h.AuthOptions(); authOptions != nil && authOptions.StorePath != "" {
+		return filepath.Dir(filepath.Dir(authOptions.StorePath))
+	}
+
+	return ""
+}
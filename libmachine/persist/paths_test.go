@@ -0,0 +1,137 @@
+package persist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePath(t *testing.T) {
+	newRoot := filepath.Join("/home", "bob", ".docker", "machine")
+
+	assert.Equal(t, newRoot, RewritePath("/Users/alice/.docker/machine", "/Users/alice/.docker/machine", newRoot))
+	assert.Equal(t, filepath.Join(newRoot, "machines", "dev", "id_rsa"), RewritePath("/Users/alice/.docker/machine/machines/dev/id_rsa", "/Users/alice/.docker/machine/", newRoot))
+	assert.Equal(t, filepath.Join(newRoot, "certs", "ca.pem"), RewritePath(`C:\Users\alice\.docker\machine\certs\ca.pem`, `C:\Users\alice\.docker\machine`, newRoot))
+	assert.Equal(t, "/Users/alice/.docker/machine-other/ca.pem", RewritePath("/Users/alice/.docker/machine-other/ca.pem", "/Users/alice/.docker/machine", newRoot))
+	assert.Equal(t, "virtualbox", RewritePath("virtualbox", "", newRoot))
+}
+
+func TestRewritePaths(t *testing.T) {
+	data := []byte(`{"Driver":{"StorePath":"/old","SSHKeyPath":"/old/machines/dev/id_rsa","DiskSize":9007199254740993},"HostOptions":{"AuthOptions":{"ServerCertRemotePath":"","StorePath":"/old/machines/dev"},"EngineOptions":{"Labels":["/old"]}}}`)
+
+	rewritten, err := RewritePaths(data, "/old", "/new")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Driver":{"StorePath":"/new","SSHKeyPath":"`+filepath.Join("/new", "machines", "dev", "id_rsa")+`","DiskSize":9007199254740993},"HostOptions":{"AuthOptions":{"ServerCertRemotePath":"","StorePath":"`+filepath.Join("/new", "machines", "dev")+`"},"EngineOptions":{"Labels":["/old"]}}}`, string(rewritten))
+}
+
+func TestRelativizeResolvePath(t *testing.T) {
+	storePath := filepath.Join("/home", "bob", ".docker", "machine")
+	keyPath := filepath.Join(storePath, "machines", "dev", "id_rsa")
+
+	assert.Equal(t, StorePathMarker, relativizePath(storePath, storePath))
+	assert.Equal(t, StorePathMarker+"/machines/dev/id_rsa", relativizePath(keyPath, storePath))
+	assert.Equal(t, "/usr/local/bin/docker", relativizePath("/usr/local/bin/docker", storePath))
+
+	assert.Equal(t, storePath, resolvePath(StorePathMarker, storePath))
+	assert.Equal(t, keyPath, resolvePath(StorePathMarker+"/machines/dev/id_rsa", storePath))
+	assert.Equal(t, "/usr/local/bin/docker", resolvePath("/usr/local/bin/docker", storePath))
+}
+
+func TestRelativizeDriverPaths(t *testing.T) {
+	storePath := filepath.Join("/home", "bob", ".docker", "machine")
+	data := []byte(`{"MachineName":"dev","StorePath":"` + storePath + `","SSHKeyPath":"` + filepath.Join(storePath, "machines", "dev", "id_rsa") + `"}`)
+
+	relative, err := RelativizePaths(data, storePath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"MachineName":"dev","StorePath":"`+StorePathMarker+`","SSHKeyPath":"`+StorePathMarker+`/machines/dev/id_rsa"}`, string(relative))
+}
+
+func TestOutsidePaths(t *testing.T) {
+	storePath := filepath.Join("/home", "bob", ".docker", "machine")
+	data := []byte(`{"Driver":{"StorePath":"` + storePath + `","SSHKeyPath":"` + filepath.Join(storePath, "machines", "..", "..", "id_rsa") + `"},"HostOptions":{"AuthOptions":{"CertDir":"` + filepath.Join(storePath, "certs") + `","CaCertPath":"id_rsa","ServerCertPath":"","ClientCertPath":"` + storePath + `-other/cert.pem"}}}`)
+
+	outside, err := OutsidePaths(data, storePath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(storePath, "machines", "..", "..", "id_rsa"), "id_rsa", storePath + "-other/cert.pem"}, outside)
+}
+
+func TestFilestoreSavesRelativePaths(t *testing.T) {
+	store := getTestStore()
+	defer os.RemoveAll(store.Path)
+
+	h := getTestHost(t, "dev")
+	h.HostOptions.AuthOptions.StorePath = filepath.Join(store.GetMachinesDir(), "dev")
+	h.Driver = &host.RawDataDriver{Data: []byte(`{"MachineName":"dev","StorePath":"` + store.Path + `"}`)}
+
+	assert.NoError(t, store.Save(h))
+
+	data, err := ioutil.ReadFile(filepath.Join(store.GetMachinesDir(), "dev", "config.json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), store.Path)
+	assert.Contains(t, string(data), StorePathMarker+"/machines/dev")
+
+	// Move the store somewhere else.
+	movedPath := store.Path + "-moved"
+	assert.NoError(t, os.Rename(store.Path, movedPath))
+	defer os.RemoveAll(movedPath)
+
+	loaded, err := NewFilestore(movedPath, "", "").Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(movedPath, "machines", "dev"), loaded.HostOptions.AuthOptions.StorePath)
+	assert.Equal(t, `{"MachineName":"dev","StorePath":"`+movedPath+`"}`, string(loaded.RawDriver))
+}
+
+func TestRelocate(t *testing.T) {
+	store := getTestStore()
+	defer os.RemoveAll(store.Path)
+
+	// A machine saved with absolute paths before the store was moved.
+	writeConfig := func() {
+		config := `{"ConfigVersion":3,"Name":"dev","DriverName":"none","Driver":{"MachineName":"dev","StorePath":"/old/store","SSHKeyPath":"/old/store/machines/dev/id_rsa"},"HostOptions":{"AuthOptions":{"StorePath":"/old/store/machines/dev","CaCertPath":"/old/store/certs/ca.pem"}}}`
+		machineDir := filepath.Join(store.GetMachinesDir(), "dev")
+		if err := os.MkdirAll(machineDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(machineDir, "config.json"), []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig()
+
+	hostNames, err := Relocate(store, store.Path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev"}, hostNames)
+
+	loaded, err := store.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(store.Path, "certs", "ca.pem"), loaded.HostOptions.AuthOptions.CaCertPath)
+
+	var driver map[string]string
+	assert.NoError(t, json.Unmarshal(loaded.RawDriver, &driver))
+	assert.Equal(t, store.Path, driver["StorePath"])
+	assert.Equal(t, filepath.Join(store.Path, "machines", "dev", "id_rsa"), driver["SSHKeyPath"])
+
+	data, err := ioutil.ReadFile(filepath.Join(store.GetMachinesDir(), "dev", "config.json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "/old/store")
+
+	// An explicit previous path takes precedence.
+	writeConfig()
+
+	_, err = Relocate(store, store.Path, "/old")
+	assert.NoError(t, err)
+
+	loaded, err = store.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(store.Path, "store", "certs", "ca.pem"), loaded.HostOptions.AuthOptions.CaCertPath)
+}
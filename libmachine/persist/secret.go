@@ -0,0 +1,105 @@
+package persist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/secret"
+)
+
+// marshalHost serializes the host with the secret fields of its driver
+// encrypted if a key provider is given, and its paths relative to the
+// storage path.
+func marshalHost(h *host.Host, storePath string, keys secret.KeyProvider, indent bool) ([]byte, error) {
+	if keys != nil && h.Driver != nil {
+		key, err := keys.Key()
+		if err != nil {
+			return nil, fmt.Errorf("Error getting the key to encrypt the store: %s", err)
+		}
+
+		rawDriver, err := json.Marshal(h.Driver)
+		if err != nil {
+			return nil, err
+		}
+
+		encrypted, err := secret.EncryptFields(h.DriverName, rawDriver, key)
+		if err != nil {
+			return nil, err
+		}
+
+		withEncryptedDriver := *h
+		withEncryptedDriver.Driver = &host.RawDataDriver{Data: encrypted}
+		h = &withEncryptedDriver
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = RelativizePaths(data, storePath); err != nil {
+		return nil, err
+	}
+
+	if !indent {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "    "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalHostData returns the serialized host with the secret fields of
+// its driver decrypted and its paths resolved against the storage path.
+func unmarshalHostData(name string, data []byte, storePath string, keys secret.KeyProvider) ([]byte, error) {
+	decrypted, err := decryptHostData(name, data, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return ResolvePaths(decrypted, storePath)
+}
+
+// decryptHostData decrypts the secret fields of the driver in the serialized
+// host. The data is returned as is if none of them is encrypted.
+func decryptHostData(name string, data []byte, keys secret.KeyProvider) ([]byte, error) {
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	var driverName string
+	if raw, ok := config["DriverName"]; ok {
+		if err := json.Unmarshal(raw, &driverName); err != nil {
+			return nil, err
+		}
+	}
+
+	rawDriver := config["Driver"]
+	if !secret.HasEncryptedFields(driverName, rawDriver) {
+		return data, nil
+	}
+
+	if keys == nil {
+		return nil, fmt.Errorf("The credentials of machine %q are encrypted and no key was provided to decrypt them", name)
+	}
+
+	key, err := keys.Key()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting the key to decrypt the store: %s", err)
+	}
+
+	decrypted, err := secret.DecryptFields(driverName, rawDriver, key)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting the credentials of machine %q: %s", name, err)
+	}
+
+	config["Driver"] = decrypted
+
+	return json.Marshal(config)
+}
@@ -21,6 +21,12 @@ type Store interface {
 	Save(host *host.Host) error
 }
 
+// DirMover is implemented by the stores which keep the directories of the
+// machines, to move the directory of a machine when it is renamed.
+type DirMover interface {
+	MoveDir(oldName, newName string) error
+}
+
 func LoadHosts(s Store, hostNames []string) ([]*host.Host, map[string]error) {
 	loadedHosts := []*host.Host{}
 	errors := map[string]error{}
@@ -7,7 +7,6 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
 	"github.com/docker/machine/libmachine/log"
-	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
 	"github.com/docker/machine/libmachine/swarm"
@@ -127,7 +126,7 @@ func (provisioner *ArchProvisioner) Provision(swarmOptions swarm.Options, authOp
 	}
 
 	log.Debug("Waiting for docker daemon")
-	if err := mcnutils.WaitFor(provisioner.dockerDaemonResponding); err != nil {
+	if err := waitFor(provisioner.Driver, provisioner.dockerDaemonResponding); err != nil {
 		return err
 	}
 
@@ -72,7 +72,7 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 		return err
 	}
 
-	if err := mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
+	if err := waitFor(provisioner.Driver, drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
 		return err
 	}
 
@@ -92,7 +92,7 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Running))
+	return waitFor(provisioner.Driver, drivers.MachineInState(provisioner.Driver, state.Running))
 }
 
 func (provisioner *Boot2DockerProvisioner) Package(name string, action pkgaction.PackageAction) error {
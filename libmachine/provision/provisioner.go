@@ -10,6 +10,7 @@ import (
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
 	"github.com/docker/machine/libmachine/swarm"
+	"golang.org/x/net/context"
 )
 
 var provisioners = make(map[string]*RegisteredProvisioner)
@@ -101,3 +102,33 @@ func DetectProvisioner(d drivers.Driver) (Provisioner, error) {
 
 	return nil, ErrDetectionFailed
 }
+
+// DetectProvisionerContext is like DetectProvisioner but gives up once ctx is
+// cancelled or its deadline expires. It returns after the SSH command in
+// flight, so that nothing reaches the machine afterwards. The provisioner is
+// bound to ctx, for ProvisionContext.
+func DetectProvisionerContext(ctx context.Context, d drivers.Driver) (Provisioner, error) {
+	provisioner, err := DetectProvisioner(drivers.WithContext(ctx, d))
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	return provisioner, err
+}
+
+// ProvisionContext runs p.Provision, which gives up once the context p was
+// detected with by DetectProvisionerContext is cancelled or its deadline
+// expires. It returns ctx.Err() in that case, after the SSH command in flight.
+// Commands which already ran on the machine are not rolled back.
+func ProvisionContext(ctx context.Context, p Provisioner, swarmOptions swarm.Options, authOptions auth.Options, engineOptions engine.Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := p.Provision(swarmOptions, authOptions, engineOptions)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
@@ -175,7 +175,7 @@ func (provisioner *RancherProvisioner) upgradeIso() error {
 		return err
 	}
 
-	if err := mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
+	if err := waitFor(provisioner.Driver, drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
 		return err
 	}
 
@@ -208,7 +208,7 @@ func (provisioner *RancherProvisioner) upgradeIso() error {
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Running))
+	return waitFor(provisioner.Driver, drivers.MachineInState(provisioner.Driver, state.Running))
 }
 
 func (provisioner *RancherProvisioner) getLatestISOURL() (string, error) {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
@@ -66,6 +67,10 @@ func ConfigureAuth(p Provisioner) error {
 	org := mcnutils.GetUsername() + "." + machineName
 	bits := 2048
 
+	if authOptions.CaPrivateKeyPath == "" {
+		return fmt.Errorf("Machine %q has no CA private key to sign its certificates with, it was imported without it", machineName)
+	}
+
 	ip, err := driver.GetIP()
 	if err != nil {
 		return err
@@ -222,8 +227,20 @@ func checkDaemonUp(p Provisioner, dockerPort int) func() bool {
 	}
 }
 
+// waitFor is mcnutils.WaitFor, which gives up once the context d is bound to
+// is done.
+func waitFor(d drivers.Driver, f func() bool) error {
+	return mcnutils.WaitForContext(drivers.ContextOf(d), f)
+}
+
 func waitForDocker(p Provisioner, dockerPort int) error {
-	if err := mcnutils.WaitForSpecific(checkDaemonUp(p, dockerPort), 10, 3*time.Second); err != nil {
+	ctx := drivers.ContextOf(p.GetDriver())
+	if err := mcnutils.WaitForSpecificOrErrorContext(ctx, func() (bool, error) {
+		return checkDaemonUp(p, dockerPort)(), nil
+	}, 10, 3*time.Second); err != nil {
+		if err == ctx.Err() {
+			return err
+		}
 		return NewErrDaemonAvailable(err)
 	}
 
@@ -0,0 +1,212 @@
+// Package reap stops idle machines and stops or removes expired ones,
+// according to their reap policy.
+package reap
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcndockerclient"
+	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
+)
+
+// Action is what is done to a machine.
+type Action string
+
+const (
+	Keep   Action = "keep"
+	Stop   Action = "stop"
+	Remove Action = "rm"
+)
+
+// Decision is what was, or would be in dry-run mode, done to a machine.
+type Decision struct {
+	Name   string
+	Action Action
+	Reason string
+	Err    error
+}
+
+// Reaper applies the reap policies of the machines.
+type Reaper struct {
+	api libmachine.API
+
+	// DryRun only reports the decisions, without changing any machine.
+	DryRun bool
+
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+func NewReaper(api libmachine.API) *Reaper {
+	return &Reaper{
+		api: api,
+		Now: time.Now,
+	}
+}
+
+// Reap applies the policy of the given machines, or of all the machines of
+// the store if none is given, and returns a decision for every machine
+// which has a policy.
+func (r *Reaper) Reap(ctx context.Context, names []string) ([]Decision, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = r.api.List(); err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+	}
+
+	decisions := []Decision{}
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return decisions, ctx.Err()
+		}
+
+		h, err := r.api.Load(name)
+		if err != nil {
+			decisions = append(decisions, Decision{Name: name, Action: Keep, Err: err})
+			continue
+		}
+
+		if h.ReapPolicy() == nil {
+			continue
+		}
+
+		d := r.decide(h)
+		if d.Err == nil && !r.DryRun {
+			d.Err = r.apply(ctx, h, d.Action)
+		}
+		decisions = append(decisions, d)
+	}
+
+	return decisions, nil
+}
+
+func (r *Reaper) decide(h *host.Host) Decision {
+	d := Decision{Name: h.Name, Action: Keep}
+	policy := h.ReapPolicy()
+	now := r.Now()
+
+	s, err := h.Driver.GetState()
+	if err != nil {
+		d.Err = err
+		return d
+	}
+
+	if policy.Expires() && !now.Before(policy.ExpiresAt) {
+		d.Reason = fmt.Sprintf("expired at %s", policy.ExpiresAt.Format(time.RFC3339))
+		switch {
+		case policy.ExpiryAction == host.ExpiryRemove:
+			d.Action = Remove
+		case s == state.Running:
+			d.Action = Stop
+		default:
+			d.Reason += ", already stopped"
+		}
+		return d
+	}
+
+	if !policy.StopsWhenIdle() {
+		return d
+	}
+
+	if s != state.Running {
+		r.setIdleSince(h, time.Time{})
+		d.Reason = "not running"
+		return d
+	}
+
+	count, err := mcndockerclient.RunningContainers(h)
+	if err != nil {
+		d.Err = err
+		return d
+	}
+
+	if count > 0 {
+		r.setIdleSince(h, time.Time{})
+		d.Reason = fmt.Sprintf("%d running containers", count)
+		return d
+	}
+
+	since := policy.IdleSince
+	if since.IsZero() {
+		since = idleSince(h, now)
+		r.setIdleSince(h, since)
+	}
+
+	idle := now.Sub(since) / time.Second * time.Second
+
+	limit := time.Duration(policy.IdleMinutes) * time.Minute
+	if idle >= limit {
+		d.Action = Stop
+		d.Reason = fmt.Sprintf("no running container for %s", idle)
+	} else {
+		d.Reason = fmt.Sprintf("no running container for %s of %s", idle, limit)
+	}
+
+	return d
+}
+
+// idleSince returns when the last container of an idle machine finished, so
+// that the idle time does not depend on how often reap runs. The machine is
+// idle since it was started if no container finished since, or since now if
+// it is unknown.
+func idleSince(h *host.Host, now time.Time) time.Time {
+	since := h.ReapPolicy().StartedAt
+
+	finished, err := mcndockerclient.LastFinished(h)
+	if err != nil {
+		log.Debugf("Error getting when the last container of %q finished: %s", h.Name, err)
+		return now
+	}
+	if finished.After(since) {
+		since = finished
+	}
+
+	if since.IsZero() || since.After(now) {
+		return now
+	}
+	return since
+}
+
+// setIdleSince records when the machine was first seen idle, unless in
+// dry-run mode.
+func (r *Reaper) setIdleSince(h *host.Host, since time.Time) {
+	policy := h.ReapPolicy()
+	if r.DryRun || policy.IdleSince.Equal(since) {
+		return
+	}
+
+	policy.IdleSince = since
+	if err := r.api.Save(h); err != nil {
+		log.Warnf("Error saving %q: %s", h.Name, err)
+	}
+}
+
+func (r *Reaper) apply(ctx context.Context, h *host.Host, action Action) error {
+	switch action {
+	case Stop:
+		h.RequestStop()
+		if err := r.api.Save(h); err != nil {
+			return err
+		}
+		if err := h.StopContext(ctx); err != nil {
+			return err
+		}
+		h.ReapPolicy().IdleSince = time.Time{}
+		return r.api.Save(h)
+	case Remove:
+		if err := h.RemoveContext(ctx); err != nil {
+			return err
+		}
+		return r.api.Remove(h.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package reap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/mcndockerclient"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+var now = time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newReapedHost(name string, s state.State, policy *host.ReapPolicy) *host.Host {
+	return &host.Host{
+		Name:       name,
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: s,
+		},
+		HostOptions: &host.Options{
+			ReapPolicy: policy,
+		},
+	}
+}
+
+func newTestReaper(hosts ...*host.Host) (*Reaper, *libmachinetest.FakeAPI) {
+	api := &libmachinetest.FakeAPI{Hosts: hosts}
+
+	r := NewReaper(api)
+	r.Now = func() time.Time { return now }
+
+	return r, api
+}
+
+func withContainers(count int, err error) func() {
+	return withContainerCounter(&mcndockerclient.FakeContainerCounter{Count: count, Err: err})
+}
+
+func withContainerCounter(cc *mcndockerclient.FakeContainerCounter) func() {
+	previous := mcndockerclient.CurrentContainerCounter
+	mcndockerclient.CurrentContainerCounter = cc
+	return func() { mcndockerclient.CurrentContainerCounter = previous }
+}
+
+func mockState(h *host.Host) state.State {
+	return h.Driver.(*fakedriver.Driver).MockState
+}
+
+func TestReapSkipsMachinesWithoutPolicy(t *testing.T) {
+	h := newReapedHost("foo", state.Running, nil)
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, decisions)
+	assert.Equal(t, state.Running, mockState(h))
+}
+
+func TestReapStopsExpiredMachine(t *testing.T) {
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		ExpiresAt:    now.Add(-time.Minute),
+		ExpiryAction: host.ExpiryStop,
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Decision{{Name: "foo", Action: Stop, Reason: "expired at 2016-03-01T11:59:00Z"}}, decisions)
+	assert.Equal(t, state.Stopped, mockState(h))
+}
+
+func TestReapKeepsExpiredStoppedMachine(t *testing.T) {
+	h := newReapedHost("foo", state.Stopped, &host.ReapPolicy{
+		ExpiresAt:    now,
+		ExpiryAction: host.ExpiryStop,
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Keep, decisions[0].Action)
+	assert.Equal(t, "expired at 2016-03-01T12:00:00Z, already stopped", decisions[0].Reason)
+}
+
+func TestReapRemovesExpiredMachine(t *testing.T) {
+	h := newReapedHost("foo", state.Stopped, &host.ReapPolicy{
+		ExpiresAt:    now.Add(-time.Hour),
+		ExpiryAction: host.ExpiryRemove,
+	})
+	r, api := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Remove, decisions[0].Action)
+	assert.NoError(t, decisions[0].Err)
+	assert.Empty(t, api.Hosts)
+}
+
+func TestReapDryRunChangesNothing(t *testing.T) {
+	defer withContainers(0, nil)()
+
+	expired := newReapedHost("expired", state.Running, &host.ReapPolicy{
+		ExpiresAt:    now.Add(-time.Hour),
+		ExpiryAction: host.ExpiryRemove,
+	})
+	idle := newReapedHost("idle", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+	})
+	r, api := newTestReaper(expired, idle)
+	r.DryRun = true
+
+	decisions, err := r.Reap(context.Background(), []string{"expired", "idle"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Remove, decisions[0].Action)
+	assert.Equal(t, Keep, decisions[1].Action)
+	assert.Len(t, api.Hosts, 2)
+	assert.Equal(t, state.Running, mockState(expired))
+	assert.True(t, idle.ReapPolicy().IdleSince.IsZero())
+}
+
+func TestReapRecordsIdleSince(t *testing.T) {
+	defer withContainers(0, nil)()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{IdleMinutes: 10})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Keep, decisions[0].Action)
+	assert.Equal(t, "no running container for 0s of 10m0s", decisions[0].Reason)
+	assert.Equal(t, now, h.ReapPolicy().IdleSince)
+}
+
+func TestReapIdleSinceLastContainerFinished(t *testing.T) {
+	defer withContainerCounter(&mcndockerclient.FakeContainerCounter{Finished: now.Add(-15 * time.Minute)})()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{IdleMinutes: 10})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Stop, decisions[0].Action)
+	assert.Equal(t, "no running container for 15m0s", decisions[0].Reason)
+	assert.Equal(t, state.Stopped, mockState(h))
+}
+
+func TestReapIdleSinceStarted(t *testing.T) {
+	defer withContainerCounter(&mcndockerclient.FakeContainerCounter{Finished: now.Add(-time.Hour)})()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+		StartedAt:   now.Add(-5 * time.Minute),
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Keep, decisions[0].Action)
+	assert.Equal(t, "no running container for 5m0s of 10m0s", decisions[0].Reason)
+	assert.Equal(t, now.Add(-5*time.Minute), h.ReapPolicy().IdleSince)
+}
+
+func TestReapRecordsStopRequest(t *testing.T) {
+	defer withContainers(0, nil)()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+		IdleSince:   now.Add(-15 * time.Minute),
+	})
+	r, _ := newTestReaper(h)
+
+	_, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.True(t, h.StopRequested())
+}
+
+func TestReapStopsIdleMachine(t *testing.T) {
+	defer withContainers(0, nil)()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+		IdleSince:   now.Add(-15 * time.Minute),
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Stop, decisions[0].Action)
+	assert.Equal(t, "no running container for 15m0s", decisions[0].Reason)
+	assert.Equal(t, state.Stopped, mockState(h))
+	assert.True(t, h.ReapPolicy().IdleSince.IsZero())
+}
+
+func TestReapResetsIdleSinceWhenBusy(t *testing.T) {
+	defer withContainers(2, nil)()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+		IdleSince:   now.Add(-15 * time.Minute),
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Keep, decisions[0].Action)
+	assert.Equal(t, "2 running containers", decisions[0].Reason)
+	assert.Equal(t, state.Running, mockState(h))
+	assert.True(t, h.ReapPolicy().IdleSince.IsZero())
+}
+
+func TestReapKeepsMachineWhenContainersCanNotBeCounted(t *testing.T) {
+	defer withContainers(0, errors.New("connection refused"))()
+
+	h := newReapedHost("foo", state.Running, &host.ReapPolicy{
+		IdleMinutes: 10,
+		IdleSince:   now.Add(-15 * time.Minute),
+	})
+	r, _ := newTestReaper(h)
+
+	decisions, err := r.Reap(context.Background(), []string{"foo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Keep, decisions[0].Action)
+	assert.EqualError(t, decisions[0].Err, "connection refused")
+	assert.Equal(t, state.Running, mockState(h))
+}
@@ -0,0 +1,258 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Client implements the libmachine API on top of a Server. The machines it
+// returns have a Driver which calls the server for every operation.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+
+	// StorePath is the local storage path. The paths of the machines
+	// which are under the storage path of the server are resolved against
+	// it, e.g. those of the client certificates.
+	StorePath string
+
+	lock       sync.Mutex
+	events     *event.Dispatcher
+	names      map[string]bool
+	stopEvents context.CancelFunc
+}
+
+// NewClient returns a client for the server at addr, which is either a URL
+// or a host:port pair to connect to over TLS.
+func NewClient(addr, storePath string, tlsConfig *tls.Config) *Client {
+	url := addr
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+
+	return &Client{
+		URL:       strings.TrimRight(url, "/"),
+		StorePath: storePath,
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+}
+
+// do sends in as JSON and decodes the response into out. The name of the
+// machine concerned, if any, is used to report the errors of the server.
+func (c *Client) do(ctx context.Context, method, path, name string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.URL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("Error contacting the server at %s: %s", c.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("Unexpected response from the server: %s", resp.Status)
+		}
+		return errResp.err(name)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// encodeConfig serializes the configuration of a machine or of a driver
+// with its paths relative to the local storage path.
+func (c *Client) encodeConfig(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return persist.RelativizePaths(data, c.StorePath)
+}
+
+// decodeHost reads the host returned by the server, with a Driver which
+// calls the server.
+func (c *Client) decodeHost(data []byte, h *host.Host) error {
+	driver, ok := h.Driver.(*Driver)
+	if !ok {
+		driver = &Driver{client: c}
+		h.Driver = driver
+	}
+
+	config, err := persist.ResolvePaths(data, c.StorePath)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(config, h); err != nil {
+		return err
+	}
+
+	driver.driverName = h.DriverName
+	driver.machineName = h.Name
+	h.RawDriver = driver.data
+
+	// The events of the machine, including those of its operations run by
+	// the client, e.g. Start, go to the subscribers of the client.
+	h.Events = c.dispatcher()
+	c.known(h.Name)
+
+	return nil
+}
+
+func (c *Client) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	driverData, err := persist.RelativizePaths(rawDriver, c.StorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var data json.RawMessage
+	if err := c.do(context.Background(), "POST", hostsPath, "", &newHostRequest{
+		DriverName: driverName,
+		RawDriver:  driverData,
+	}, &data); err != nil {
+		return nil, err
+	}
+
+	h := &host.Host{}
+	if err := c.decodeHost(data, h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (c *Client) Create(h *host.Host) error {
+	return c.CreateContext(context.Background(), h)
+}
+
+// CreateContext creates the machine on the server. Cancelling ctx drops the
+// connection, which makes the server abort the creation.
+func (c *Client) CreateContext(ctx context.Context, h *host.Host) error {
+	return c.create(ctx, h, false)
+}
+
+func (c *Client) ResumeCreateContext(ctx context.Context, h *host.Host) error {
+	return c.create(ctx, h, true)
+}
+
+func (c *Client) create(ctx context.Context, h *host.Host, resume bool) error {
+	hostData, err := c.encodeConfig(h)
+	if err != nil {
+		return err
+	}
+
+	var data json.RawMessage
+	if err := c.do(ctx, "POST", createPath, h.Name, &createRequest{
+		Host:   hostData,
+		Resume: resume,
+	}, &data); err != nil {
+		return err
+	}
+
+	return c.decodeHost(data, h)
+}
+
+func (c *Client) Exists(name string) (bool, error) {
+	_, err := c.Load(name)
+	if _, ok := err.(mcnerror.ErrHostDoesNotExist); ok {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (c *Client) List() ([]string, error) {
+	hostNames := []string{}
+	if err := c.do(context.Background(), "GET", machinesPath, "", nil, &hostNames); err != nil {
+		return nil, err
+	}
+
+	return hostNames, nil
+}
+
+func (c *Client) Load(name string) (*host.Host, error) {
+	var data json.RawMessage
+	if err := c.do(context.Background(), "GET", machinesPath+"/"+name, name, nil, &data); err != nil {
+		return nil, err
+	}
+
+	h := &host.Host{Name: name}
+	if err := c.decodeHost(data, h); err != nil {
+		return nil, err
+	}
+	h.Name = name
+
+	return h, nil
+}
+
+func (c *Client) Remove(name string) error {
+	return c.do(context.Background(), "DELETE", machinesPath+"/"+name, name, nil, nil)
+}
+
+// MoveDir moves the directory of a machine which is renamed on the server.
+func (c *Client) MoveDir(oldName, newName string) error {
+	return c.do(context.Background(), "POST", movePath, oldName, &moveRequest{
+		Name:    oldName,
+		NewName: newName,
+	}, nil)
+}
+
+func (c *Client) Save(h *host.Host) error {
+	data, err := c.encodeConfig(h)
+	if err != nil {
+		return err
+	}
+
+	return c.do(context.Background(), "PUT", machinesPath+"/"+h.Name, h.Name, &data, nil)
+}
+
+// Close stops receiving the events of the server.
+func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.stopEvents != nil {
+		c.stopEvents()
+		c.stopEvents = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package remote
+
+import (
+	"encoding/json"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
+)
+
+// Driver runs the methods of the driver of a machine on the server. It keeps
+// the configuration of the driver and sends it along with every call, which
+// the server applies to the driver it keeps running for the machine.
+type Driver struct {
+	client      *Client
+	driverName  string
+	machineName string
+	data        json.RawMessage
+}
+
+func (d *Driver) MarshalJSON() ([]byte, error) {
+	return d.data, nil
+}
+
+func (d *Driver) UnmarshalJSON(data []byte) error {
+	d.data = append(json.RawMessage{}, data...)
+	return nil
+}
+
+// call runs method on the server and decodes its result into result. The
+// configuration of the driver is updated with the one returned by the server.
+func (d *Driver) call(method string, args, result interface{}) error {
+	driverData, err := persist.RelativizePaths(d.data, d.client.StorePath)
+	if err != nil {
+		return err
+	}
+
+	req := &driverRequest{
+		Name:       d.machineName,
+		DriverName: d.driverName,
+		Driver:     driverData,
+		Method:     method,
+	}
+
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		req.Args = data
+	}
+
+	var resp driverResponse
+	if err := d.client.do(context.Background(), "POST", driverPath, "", req, &resp); err != nil {
+		return err
+	}
+
+	if len(resp.Driver) > 0 {
+		if d.data, err = persist.ResolvePaths(resp.Driver, d.client.StorePath); err != nil {
+			return err
+		}
+	}
+
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+
+	return nil
+}
+
+func (d *Driver) stringCall(method string) (string, error) {
+	var s string
+	err := d.call(method, nil, &s)
+	return s, err
+}
+
+func (d *Driver) DriverName() string {
+	return d.driverName
+}
+
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	var flags []flag
+	if err := d.call("GetCreateFlags", nil, &flags); err != nil {
+		log.Warnf("Error attempting call to get create flags: %s", err)
+		return nil
+	}
+
+	mcnFlags, err := decodeFlags(flags)
+	if err != nil {
+		log.Warnf("Error reading the create flags: %s", err)
+		return nil
+	}
+
+	return mcnFlags
+}
+
+func (d *Driver) SetConfigFromFlags(opts drivers.DriverOptions) error {
+	encoded, err := encodeOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	return d.call("SetConfigFromFlags", encoded, nil)
+}
+
+func (d *Driver) GetMachineName() string {
+	name, err := d.stringCall("GetMachineName")
+	if err != nil {
+		log.Warnf("Error attempting call to get machine name: %s", err)
+	}
+
+	return name
+}
+
+// GetSSHKeyPath returns the path of the key on the server.
+func (d *Driver) GetSSHKeyPath() string {
+	path, err := d.stringCall("GetSSHKeyPath")
+	if err != nil {
+		log.Warnf("Error attempting call to get SSH key path: %s", err)
+	}
+
+	return path
+}
+
+func (d *Driver) GetSSHUsername() string {
+	username, err := d.stringCall("GetSSHUsername")
+	if err != nil {
+		log.Warnf("Error attempting call to get SSH username: %s", err)
+	}
+
+	return username
+}
+
+func (d *Driver) GetIP() (string, error) {
+	return d.stringCall("GetIP")
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	return d.stringCall("GetSSHHostname")
+}
+
+func (d *Driver) GetSSHPort() (int, error) {
+	var port int
+	err := d.call("GetSSHPort", nil, &port)
+	return port, err
+}
+
+func (d *Driver) GetURL() (string, error) {
+	return d.stringCall("GetURL")
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	var s state.State
+	if err := d.call("GetState", nil, &s); err != nil {
+		return state.Error, err
+	}
+
+	return s, nil
+}
+
+func (d *Driver) PreCreateCheck() error {
+	return d.call("PreCreateCheck", nil, nil)
+}
+
+func (d *Driver) Create() error {
+	return d.call("Create", nil, nil)
+}
+
+func (d *Driver) Remove() error {
+	return d.call("Remove", nil, nil)
+}
+
+func (d *Driver) Start() error {
+	return d.call("Start", nil, nil)
+}
+
+func (d *Driver) Stop() error {
+	return d.call("Stop", nil, nil)
+}
+
+func (d *Driver) Restart() error {
+	return d.call("Restart", nil, nil)
+}
+
+func (d *Driver) Kill() error {
+	return d.call("Kill", nil, nil)
+}
+
+func (d *Driver) CreateSnapshot(name string) error {
+	return d.call("CreateSnapshot", name, nil)
+}
+
+func (d *Driver) ListSnapshots() ([]drivers.Snapshot, error) {
+	var snapshots []drivers.Snapshot
+	if err := d.call("ListSnapshots", nil, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (d *Driver) RestoreSnapshot(name string) error {
+	return d.call("RestoreSnapshot", name, nil)
+}
+
+func (d *Driver) RemoveSnapshot(name string) error {
+	return d.call("RemoveSnapshot", name, nil)
+}
+
+func (d *Driver) Pause() error {
+	return d.call("Pause", nil, nil)
+}
+
+func (d *Driver) Unpause() error {
+	return d.call("Unpause", nil, nil)
+}
+
+func (d *Driver) Suspend() error {
+	return d.call("Suspend", nil, nil)
+}
+
+func (d *Driver) Resize(opts drivers.ResizeOptions) error {
+	return d.call("Resize", opts, nil)
+}
+
+// Clone returns the configuration of the copy with the paths of the server,
+// where the copy is created by NewHost.
+func (d *Driver) Clone(name string) ([]byte, error) {
+	var data json.RawMessage
+	if err := d.call("Clone", name, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (d *Driver) Rename(name string) error {
+	if err := d.call("Rename", name, nil); err != nil {
+		return err
+	}
+
+	d.machineName = name
+	return nil
+}
+
+func (d *Driver) ReleaseStoreDir() error {
+	return d.call("ReleaseStoreDir", nil, nil)
+}
+
+func (d *Driver) UseStoreDir() error {
+	return d.call("UseStoreDir", nil, nil)
+}
@@ -0,0 +1,167 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/log"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// eventBuffer is the number of events kept for a client which reads them
+// slower than they are published. The events which do not fit are dropped
+// rather than slowing down the machines publishing them.
+const eventBuffer = 64
+
+// subscriber is implemented by the APIs which publish the events of their
+// own machines.
+type subscriber interface {
+	Subscribe(handler event.Handler) func()
+}
+
+// handleEvents streams the events published by the APIs of the server, as
+// JSON documents, until the client goes away or the server is closed.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("The server can not stream the events"))
+		return
+	}
+
+	events := make(chan event.Event, eventBuffer)
+	unsubscribe := s.events.Subscribe(func(e event.Event) {
+		select {
+		case events <- e:
+		default:
+			log.Debugf("(%s) Dropping event %s %s for a slow client", e.HostName, e.Stage, e.Type)
+		}
+	})
+	defer unsubscribe()
+
+	ctx, cancel := requestContext(w)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case e := <-events:
+			if err := encoder.Encode(encodeEvent(e)); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// dispatcher returns the dispatcher of the events of the machines of the
+// client, both those published locally and those received from the server.
+func (c *Client) dispatcher() *event.Dispatcher {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.events == nil {
+		c.events = event.NewDispatcher()
+	}
+	return c.events
+}
+
+// Subscribe registers a handler which receives the events of the machines
+// loaded or created with the client, including those published on the
+// server, e.g. while it creates a machine. The returned function removes
+// the handler.
+func (c *Client) Subscribe(handler event.Handler) func() {
+	unsubscribe := c.dispatcher().Subscribe(handler)
+	c.receiveEvents()
+	return unsubscribe
+}
+
+// known records that the events of the machine name concern the client.
+func (c *Client) known(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.names == nil {
+		c.names = map[string]bool{}
+	}
+	c.names[name] = true
+}
+
+func (c *Client) isKnown(name string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.names[name]
+}
+
+// receiveEvents starts receiving the events of the server, unless it already
+// did. It returns once the server accepted the stream, so that no event of
+// the requests which follow is missed.
+func (c *Client) receiveEvents() {
+	c.lock.Lock()
+	if c.stopEvents != nil {
+		c.lock.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stopEvents = cancel
+	c.lock.Unlock()
+
+	ready := make(chan struct{})
+	go c.readEvents(ctx, ready)
+	<-ready
+}
+
+func (c *Client) readEvents(ctx context.Context, ready chan struct{}) {
+	req, err := http.NewRequest("GET", c.URL+eventsPath, nil)
+	if err != nil {
+		close(ready)
+		log.Debugf("Error receiving the events of the server: %s", err)
+		return
+	}
+
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	close(ready)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Debugf("Error receiving the events of the server: %s", err)
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Debugf("The server does not send its events: %s", resp.Status)
+		return
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg eventMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if ctx.Err() == nil {
+				log.Debugf("Stopped receiving the events of the server: %s", err)
+			}
+			return
+		}
+
+		if c.isKnown(msg.HostName) {
+			c.dispatcher().Publish(msg.decode())
+		}
+	}
+}
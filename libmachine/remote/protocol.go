@@ -0,0 +1,324 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnflag"
+)
+
+// The server exposes the following endpoints, which all take and return
+// JSON documents:
+//
+//	GET    /v1/machines         names of the machines
+//	GET    /v1/machines/<name>  configuration of a machine
+//	PUT    /v1/machines/<name>  save a machine
+//	DELETE /v1/machines/<name>  remove a machine from the store
+//	POST   /v1/hosts            build a new host for a driver
+//	POST   /v1/create           create or resume the creation of a machine
+//	POST   /v1/driver           call a method of the driver of a machine
+//	GET    /v1/events           stream of the lifecycle events
+//	POST   /v1/move             move the directory of a renamed machine
+const (
+	machinesPath = "/v1/machines"
+	hostsPath    = "/v1/hosts"
+	createPath   = "/v1/create"
+	driverPath   = "/v1/driver"
+	eventsPath   = "/v1/events"
+	movePath     = "/v1/move"
+)
+
+const (
+	codeHostDoesNotExist = "HostDoesNotExist"
+	codePreCreate        = "PreCreate"
+	codeNotSupported     = "NotSupported"
+)
+
+var (
+	errUnknownMethod      = errors.New("Unknown driver method")
+	errUnsupportedOptions = errors.New("The driver options can not be sent to the server")
+	errServerClosed       = errors.New("The server is shutting down")
+	errCanNotMove         = errors.New("The store of the server can not move the directories of the machines")
+	errHooksNotAllowed    = errors.New("The hooks of a machine can not be sent to the server, their commands would run on it")
+)
+
+type errorResponse struct {
+	Error string
+	Code  string `json:",omitempty"`
+
+	// DriverName and Feature are set for the NotSupported code.
+	DriverName string `json:",omitempty"`
+	Feature    string `json:",omitempty"`
+}
+
+func newErrorResponse(err error) errorResponse {
+	resp := errorResponse{Error: err.Error()}
+
+	switch e := err.(type) {
+	case mcnerror.ErrHostDoesNotExist:
+		resp.Code = codeHostDoesNotExist
+	case mcnerror.ErrDuringPreCreate:
+		resp.Code = codePreCreate
+		resp.Error = e.Cause.Error()
+	case drivers.FeatureNotSupported:
+		resp.Code = codeNotSupported
+		resp.DriverName = e.DriverName
+		resp.Feature = e.Feature
+	}
+
+	return resp
+}
+
+// err returns the error reported by the server. Errors which the commands
+// handle specifically keep their type.
+func (resp errorResponse) err(name string) error {
+	switch resp.Code {
+	case codeHostDoesNotExist:
+		return mcnerror.ErrHostDoesNotExist{Name: name}
+	case codePreCreate:
+		return mcnerror.ErrDuringPreCreate{Cause: errors.New(resp.Error)}
+	case codeNotSupported:
+		return drivers.FeatureNotSupported{DriverName: resp.DriverName, Feature: resp.Feature}
+	}
+
+	return errors.New(resp.Error)
+}
+
+type newHostRequest struct {
+	DriverName string
+	RawDriver  json.RawMessage
+}
+
+type createRequest struct {
+	Host   json.RawMessage
+	Resume bool
+}
+
+type moveRequest struct {
+	Name    string
+	NewName string
+}
+
+type driverRequest struct {
+	Name       string
+	DriverName string
+	Driver     json.RawMessage
+	Method     string
+	Args       json.RawMessage `json:",omitempty"`
+}
+
+type driverResponse struct {
+	Driver json.RawMessage
+	Result json.RawMessage `json:",omitempty"`
+}
+
+// eventMessage is the serialized form of an event.Event.
+type eventMessage struct {
+	Type       event.Type
+	Stage      event.Stage
+	HostName   string
+	DriverName string
+	Detail     string `json:",omitempty"`
+	Time       time.Time
+	Duration   time.Duration `json:",omitempty"`
+	Err        string        `json:",omitempty"`
+}
+
+func encodeEvent(e event.Event) eventMessage {
+	msg := eventMessage{
+		Type:       e.Type,
+		Stage:      e.Stage,
+		HostName:   e.HostName,
+		DriverName: e.DriverName,
+		Detail:     e.Detail,
+		Time:       e.Time,
+		Duration:   e.Duration,
+	}
+	if e.Err != nil {
+		msg.Err = e.Err.Error()
+	}
+
+	return msg
+}
+
+func (msg eventMessage) decode() event.Event {
+	e := event.Event{
+		Type:       msg.Type,
+		Stage:      msg.Stage,
+		HostName:   msg.HostName,
+		DriverName: msg.DriverName,
+		Detail:     msg.Detail,
+		Time:       msg.Time,
+		Duration:   msg.Duration,
+	}
+	if msg.Err != "" {
+		e.Err = errors.New(msg.Err)
+	}
+
+	return e
+}
+
+// flag is the serialized form of an mcnflag.Flag.
+type flag struct {
+	Type   string
+	Name   string
+	Usage  string
+	EnvVar string
+	Value  json.RawMessage `json:",omitempty"`
+}
+
+func encodeFlags(mcnFlags []mcnflag.Flag) ([]flag, error) {
+	flags := []flag{}
+
+	for _, f := range mcnFlags {
+		var (
+			encoded flag
+			value   interface{}
+		)
+
+		switch t := f.(type) {
+		case *mcnflag.StringFlag:
+			encoded, value = flag{Type: "string", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case mcnflag.StringFlag:
+			encoded, value = flag{Type: "string", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case *mcnflag.StringSliceFlag:
+			encoded, value = flag{Type: "stringSlice", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case mcnflag.StringSliceFlag:
+			encoded, value = flag{Type: "stringSlice", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case *mcnflag.IntFlag:
+			encoded, value = flag{Type: "int", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case mcnflag.IntFlag:
+			encoded, value = flag{Type: "int", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}, t.Value
+		case *mcnflag.BoolFlag:
+			encoded = flag{Type: "bool", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}
+		case mcnflag.BoolFlag:
+			encoded = flag{Type: "bool", Name: t.Name, Usage: t.Usage, EnvVar: t.EnvVar}
+		default:
+			return nil, fmt.Errorf("Flag %s has an unknown type %T", f, f)
+		}
+
+		if value != nil {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			encoded.Value = data
+		}
+
+		flags = append(flags, encoded)
+	}
+
+	return flags, nil
+}
+
+// decodeFlags returns the flags the way the plugin drivers do, as pointers.
+func decodeFlags(flags []flag) ([]mcnflag.Flag, error) {
+	mcnFlags := []mcnflag.Flag{}
+
+	for _, f := range flags {
+		var (
+			decoded mcnflag.Flag
+			value   interface{}
+		)
+
+		switch f.Type {
+		case "string":
+			t := &mcnflag.StringFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar}
+			decoded, value = t, &t.Value
+		case "stringSlice":
+			t := &mcnflag.StringSliceFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar}
+			decoded, value = t, &t.Value
+		case "int":
+			t := &mcnflag.IntFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar}
+			decoded, value = t, &t.Value
+		case "bool":
+			decoded = &mcnflag.BoolFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar}
+		default:
+			return nil, fmt.Errorf("Flag %s has an unknown type %q", f.Name, f.Type)
+		}
+
+		if value != nil && len(f.Value) > 0 {
+			if err := json.Unmarshal(f.Value, value); err != nil {
+				return nil, err
+			}
+		}
+
+		mcnFlags = append(mcnFlags, decoded)
+	}
+
+	return mcnFlags, nil
+}
+
+// options is the serialized form of the driver options, which keeps the
+// type of their values.
+type options struct {
+	Strings      map[string]string
+	StringSlices map[string][]string
+	Ints         map[string]int
+	Bools        map[string]bool
+}
+
+func encodeOptions(opts drivers.DriverOptions) (*options, error) {
+	var values map[string]interface{}
+
+	switch t := opts.(type) {
+	case rpcdriver.RPCFlags:
+		values = t.Values
+	case *rpcdriver.RPCFlags:
+		values = t.Values
+	default:
+		return nil, errUnsupportedOptions
+	}
+
+	encoded := &options{
+		Strings:      map[string]string{},
+		StringSlices: map[string][]string{},
+		Ints:         map[string]int{},
+		Bools:        map[string]bool{},
+	}
+
+	for name, value := range values {
+		switch v := value.(type) {
+		case string:
+			encoded.Strings[name] = v
+		case []string:
+			encoded.StringSlices[name] = v
+		case int:
+			encoded.Ints[name] = v
+		case bool:
+			encoded.Bools[name] = v
+		case nil:
+		default:
+			return nil, fmt.Errorf("Option %s has an unknown type %T", name, value)
+		}
+	}
+
+	return encoded, nil
+}
+
+func (o *options) decode() rpcdriver.RPCFlags {
+	opts := rpcdriver.RPCFlags{
+		Values: map[string]interface{}{},
+	}
+
+	for name, value := range o.Strings {
+		opts.Values[name] = value
+	}
+	for name, value := range o.StringSlices {
+		opts.Values[name] = value
+	}
+	for name, value := range o.Ints {
+		opts.Values[name] = value
+	}
+	for name, value := range o.Bools {
+		opts.Values[name] = value
+	}
+
+	return opts
+}
@@ -0,0 +1,409 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// fakeAPI builds machines with the none driver instead of driver plugins.
+type fakeAPI struct {
+	*persist.Filestore
+	createErr error
+	events    *event.Dispatcher
+	newHosts  int
+	closes    int
+}
+
+func (api *fakeAPI) NewHost(driverName string, rawDriver []byte) (*host.Host, error) {
+	driver := none.NewDriver("", api.Path)
+	if err := json.Unmarshal(rawDriver, driver); err != nil {
+		return nil, err
+	}
+	api.newHosts++
+
+	certDir := filepath.Join(api.Path, "certs")
+
+	return &host.Host{
+		ConfigVersion: version.ConfigVersion,
+		Name:          driver.GetMachineName(),
+		Driver:        driver,
+		DriverName:    driverName,
+		HostOptions: &host.Options{
+			AuthOptions: &auth.Options{
+				CertDir:          certDir,
+				CaCertPath:       filepath.Join(certDir, "ca.pem"),
+				CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+				ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+				ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+			},
+		},
+	}, nil
+}
+
+func (api *fakeAPI) Subscribe(handler event.Handler) func() {
+	return api.events.Subscribe(handler)
+}
+
+func (api *fakeAPI) Create(h *host.Host) error {
+	return api.CreateContext(context.Background(), h)
+}
+
+func (api *fakeAPI) CreateContext(ctx context.Context, h *host.Host) error {
+	op := api.events.Begin(event.Create, h.Name, h.DriverName)
+	if api.createErr != nil {
+		return op.End(api.createErr)
+	}
+
+	return op.End(api.Save(h))
+}
+
+func (api *fakeAPI) ResumeCreateContext(ctx context.Context, h *host.Host) error {
+	return api.CreateContext(ctx, h)
+}
+
+func (api *fakeAPI) Close() error {
+	api.closes++
+	return nil
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeAPI, func()) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeAPI{
+		Filestore: persist.NewFilestore(tmpDir, "", ""),
+		events:    event.NewDispatcher(),
+	}
+	server := NewServer(api.Filestore, tmpDir, func() (libmachine.API, error) { return api, nil })
+	httpServer := httptest.NewServer(server)
+	client := NewClient(httpServer.URL, "/local/store", nil)
+
+	return client, api, func() {
+		client.Close()
+		server.Close()
+		httpServer.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestClientIsAnAPI(t *testing.T) {
+	var _ libmachine.API = &Client{}
+}
+
+func TestCreateAndDrive(t *testing.T) {
+	client, _, cleanup := newTestClient(t)
+	defer cleanup()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", h.Name)
+	assert.Equal(t, "none", h.Driver.DriverName())
+
+	flags := h.Driver.GetCreateFlags()
+	assert.Equal(t, []mcnflag.Flag{&mcnflag.StringFlag{Name: "url", Usage: "URL of host when no driver is selected"}}, flags)
+
+	assert.NoError(t, h.Driver.SetConfigFromFlags(rpcdriver.RPCFlags{
+		Values: map[string]interface{}{"url": "tcp://1.2.3.4:2376"},
+	}))
+
+	assert.NoError(t, client.Create(h))
+
+	hostNames, err := client.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev"}, hostNames)
+
+	loaded, err := client.Load("dev")
+	assert.NoError(t, err)
+	assert.IsType(t, &Driver{}, loaded.Driver)
+
+	url, err := loaded.Driver.GetURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://1.2.3.4:2376", url)
+
+	ip, err := loaded.Driver.GetIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3.4:2376", ip)
+}
+
+func TestCreateError(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	api.createErr = mcnerror.ErrDuringPreCreate{Cause: errors.New("no quota left")}
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev"}`))
+	assert.NoError(t, err)
+
+	err = client.Create(h)
+
+	assert.Equal(t, mcnerror.ErrDuringPreCreate{Cause: errors.New("no quota left")}, err)
+}
+
+func TestSnapshotsNotSupported(t *testing.T) {
+	client, _, cleanup := newTestClient(t)
+	defer cleanup()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Create(h))
+
+	loaded, err := client.Load("dev")
+	assert.NoError(t, err)
+
+	s, err := drivers.GetSnapshotter(loaded.Driver)
+	assert.NoError(t, err)
+
+	err = s.CreateSnapshot("clean")
+
+	assert.Equal(t, drivers.FeatureNotSupported{DriverName: "none", Feature: "snapshots"}, err)
+}
+
+func TestStore(t *testing.T) {
+	client, _, cleanup := newTestClient(t)
+	defer cleanup()
+
+	_, err := client.Load("dev")
+	assert.Equal(t, mcnerror.ErrHostDoesNotExist{Name: "dev"}, err)
+
+	exists, err := client.Exists("dev")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Save(h))
+
+	exists, err = client.Exists("dev")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, client.Remove("dev"))
+
+	exists, err = client.Exists("dev")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestOptionsRoundTrip(t *testing.T) {
+	opts := rpcdriver.RPCFlags{
+		Values: map[string]interface{}{
+			"string": "value",
+			"slice":  []string{"a", "b"},
+			"int":    42,
+			"bool":   true,
+		},
+	}
+
+	encoded, err := encodeOptions(opts)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(encoded)
+	assert.NoError(t, err)
+
+	decoded := &options{}
+	assert.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, opts, decoded.decode())
+}
+
+func TestPathsAreRelativeToEachStore(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","StorePath":"/local/store","SSHKeyPath":"/local/store/machines/dev/id_rsa"}`))
+	assert.NoError(t, err)
+	h.HostOptions.AuthOptions.CertDir = filepath.Join("/local/store", "certs")
+	assert.NoError(t, client.Save(h))
+
+	saved, err := api.Filestore.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(api.Path, "certs"), saved.HostOptions.AuthOptions.CertDir)
+
+	var driver none.Driver
+	assert.NoError(t, json.Unmarshal(saved.RawDriver, &driver))
+	assert.Equal(t, api.Path, driver.StorePath)
+	assert.Equal(t, filepath.Join(api.Path, "machines", "dev", "id_rsa"), driver.SSHKeyPath)
+
+	loaded, err := client.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/local/store", "certs"), loaded.HostOptions.AuthOptions.CertDir)
+	assert.NoError(t, json.Unmarshal(loaded.RawDriver, &driver))
+	assert.Equal(t, filepath.Join("/local/store", "machines", "dev", "id_rsa"), driver.SSHKeyPath)
+}
+
+func TestDriverIsKeptBetweenCalls(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, h.Driver.SetConfigFromFlags(rpcdriver.RPCFlags{
+		Values: map[string]interface{}{"url": "tcp://1.2.3.4:2376"},
+	}))
+	assert.NoError(t, client.Create(h))
+
+	loaded, err := client.Load("dev")
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		url, err := loaded.Driver.GetURL()
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp://1.2.3.4:2376", url)
+	}
+
+	assert.Equal(t, 1, api.newHosts)
+	assert.Equal(t, 0, api.closes)
+
+	assert.NoError(t, client.Remove("dev"))
+
+	assert.Equal(t, 1, api.closes)
+}
+
+func TestCreateUsesTheCertificatesOfTheServer(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	h.HostOptions.AuthOptions = &auth.Options{
+		CertDir:          "/etc",
+		CaCertPath:       "/etc/ca.pem",
+		CaPrivateKeyPath: "/etc/shadow",
+		ServerCertPath:   "/etc/passwd",
+		ServerCertSANs:   []string{"dev.example.com"},
+	}
+	assert.NoError(t, client.Create(h))
+
+	saved, err := api.Filestore.Load("dev")
+	assert.NoError(t, err)
+
+	certDir := filepath.Join(api.Path, "certs")
+	machineDir := filepath.Join(api.Path, "machines", "dev")
+	assert.Equal(t, &auth.Options{
+		CertDir:          certDir,
+		CaCertPath:       filepath.Join(certDir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+		ServerCertPath:   filepath.Join(machineDir, "server.pem"),
+		ServerKeyPath:    filepath.Join(machineDir, "server-key.pem"),
+		ServerCertSANs:   []string{"dev.example.com"},
+		StorePath:        machineDir,
+	}, saved.HostOptions.AuthOptions)
+}
+
+func TestEventsOfTheServer(t *testing.T) {
+	client, _, cleanup := newTestClient(t)
+	defer cleanup()
+
+	received := make(chan event.Event, 10)
+	defer client.Subscribe(func(e event.Event) {
+		received <- e
+	})()
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Create(h))
+
+	for _, expected := range []event.Type{event.Started, event.Finished} {
+		select {
+		case e := <-received:
+			assert.Equal(t, expected, e.Type)
+			assert.Equal(t, event.Create, e.Stage)
+			assert.Equal(t, "dev", e.HostName)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The %s event of the creation was not received", expected)
+		}
+	}
+}
+
+func TestHooksAreNotSentToTheServer(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	clientHooks := &hook.Options{Hooks: []hook.Hook{{Event: hook.PostCreate, Command: "touch /tmp/pwned"}}}
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	h.HostOptions.HookOptions = clientHooks
+
+	err = client.Create(h)
+	assert.EqualError(t, err, errHooksNotAllowed.Error())
+
+	h.HostOptions.HookOptions = nil
+	assert.NoError(t, client.Create(h))
+
+	h.HostOptions.HookOptions = clientHooks
+	assert.NoError(t, client.Save(h))
+
+	saved, err := api.Load("dev")
+	assert.NoError(t, err)
+	assert.Nil(t, saved.Hooks())
+
+	serverHooks := &hook.Options{Hooks: []hook.Hook{{Event: hook.PostStart, Command: "true"}}}
+	saved.HostOptions.HookOptions = serverHooks
+	assert.NoError(t, api.Save(saved))
+
+	assert.NoError(t, client.Save(h))
+
+	saved, err = api.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, serverHooks, saved.Hooks())
+}
+
+func TestPathsOutsideTheStoreAreRejected(t *testing.T) {
+	client, api, cleanup := newTestClient(t)
+	defer cleanup()
+
+	_, err := client.NewHost("none", []byte(`{"MachineName":"dev","SSHKeyPath":"/etc/shadow"}`))
+	assert.EqualError(t, err, `The path "/etc/shadow" is not in the store of the server`)
+
+	h, err := client.NewHost("none", []byte(`{"MachineName":"dev","URL":"tcp://1.2.3.4:2376"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, client.Create(h))
+
+	h.HostOptions.AuthOptions.CaPrivateKeyPath = "/etc/shadow"
+	assert.EqualError(t, client.Save(h), `The path "/etc/shadow" is not in the store of the server`)
+
+	saved, err := api.Load("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(api.Path, "certs", "ca-key.pem"), saved.HostOptions.AuthOptions.CaPrivateKeyPath)
+
+	loaded, err := client.Load("dev")
+	assert.NoError(t, err)
+	driver := loaded.Driver.(*Driver)
+	driver.data = []byte(`{"MachineName":"dev","StorePath":"/"}`)
+	_, err = loaded.Driver.GetURL()
+	assert.EqualError(t, err, `The path "/" is not in the store of the server`)
+}
+
+func TestCallDriverWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := callDriver(ctx, none.NewDriver("dev", "/store"), "Start", nil)
+
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,844 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/persist"
+	"golang.org/x/net/context"
+)
+
+// Server exposes a libmachine API over HTTP, so that several clients can
+// share the machines it manages.
+type Server struct {
+	// Store holds the configuration of the machines.
+	Store persist.Store
+
+	// StorePath is the storage path of the server. The paths under it are
+	// sent relative to the storage path, so that the clients can resolve
+	// them against their own.
+	StorePath string
+
+	// NewAPI returns the API used to create the machines and to call their
+	// drivers. Each machine gets its own API, which is kept along with its
+	// driver until the machine is removed or the server is closed, so that
+	// its driver plugin keeps running between requests.
+	NewAPI func() (libmachine.API, error)
+
+	mux    *http.ServeMux
+	events *event.Dispatcher
+	done   chan struct{}
+
+	lock    sync.Mutex
+	drivers map[string]*liveDriver
+	closed  bool
+}
+
+// liveDriver is the driver of a machine, kept running between requests. Its
+// lock serializes the calls to the driver.
+type liveDriver struct {
+	lock        sync.Mutex
+	api         libmachine.API
+	host        *host.Host
+	unsubscribe func()
+
+	// removed is set once the driver is closed, so that the requests which
+	// were waiting for it start another one.
+	removed bool
+}
+
+func (l *liveDriver) close() {
+	if l.unsubscribe != nil {
+		l.unsubscribe()
+	}
+	if l.api != nil {
+		if err := l.api.Close(); err != nil {
+			log.Warnf("Error closing the driver of %q: %s", l.host.Name, err)
+		}
+	}
+
+	l.api = nil
+	l.host = nil
+	l.removed = true
+}
+
+func NewServer(store persist.Store, storePath string, newAPI func() (libmachine.API, error)) *Server {
+	s := &Server{
+		Store:     store,
+		StorePath: storePath,
+		NewAPI:    newAPI,
+		mux:       http.NewServeMux(),
+		events:    event.NewDispatcher(),
+		done:      make(chan struct{}),
+		drivers:   map[string]*liveDriver{},
+	}
+
+	s.mux.HandleFunc(machinesPath, s.handleList)
+	s.mux.HandleFunc(machinesPath+"/", s.handleMachine)
+	s.mux.HandleFunc(hostsPath, s.handleNewHost)
+	s.mux.HandleFunc(createPath, s.handleCreate)
+	s.mux.HandleFunc(driverPath, s.handleDriver)
+	s.mux.HandleFunc(eventsPath, s.handleEvents)
+	s.mux.HandleFunc(movePath, s.handleMove)
+
+	return s
+}
+
+// Close stops the drivers of the machines and ends the event streams.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	live := s.drivers
+	s.drivers = map[string]*liveDriver{}
+	s.lock.Unlock()
+
+	close(s.done)
+
+	for _, l := range live {
+		l.lock.Lock()
+		l.close()
+		l.lock.Unlock()
+	}
+
+	return nil
+}
+
+// newLiveDriver starts the driver of a machine with a new API. The events
+// the API publishes are forwarded to the clients of the server.
+func (s *Server) newLiveDriver(driverName string, rawDriver []byte) (*liveDriver, error) {
+	api, err := s.NewAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := api.NewHost(driverName, rawDriver)
+	if err != nil {
+		api.Close()
+		return nil, err
+	}
+
+	l := &liveDriver{
+		api:  api,
+		host: h,
+	}
+	if sub, ok := api.(subscriber); ok {
+		l.unsubscribe = sub.Subscribe(s.events.Publish)
+	}
+
+	return l, nil
+}
+
+// acquire returns the driver of the machine name, locked, once configured
+// with rawDriver. The driver is started if the machine has none yet, or if
+// it has one of another driver.
+func (s *Server) acquire(name, driverName string, rawDriver []byte) (*liveDriver, error) {
+	for {
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			return nil, errServerClosed
+		}
+		l, ok := s.drivers[name]
+		if !ok {
+			l = &liveDriver{}
+			s.drivers[name] = l
+		}
+		s.lock.Unlock()
+
+		l.lock.Lock()
+		if l.removed {
+			l.lock.Unlock()
+			continue
+		}
+
+		if l.host != nil && l.host.DriverName == driverName {
+			if err := json.Unmarshal(rawDriver, l.host.Driver); err != nil {
+				l.lock.Unlock()
+				return nil, err
+			}
+			return l, nil
+		}
+
+		if l.api != nil {
+			l.close()
+			l.removed = false
+		}
+
+		started, err := s.newLiveDriver(driverName, rawDriver)
+		if err != nil {
+			l.removed = true
+			l.lock.Unlock()
+			s.forget(name, l)
+			return nil, err
+		}
+		l.api, l.host, l.unsubscribe = started.api, started.host, started.unsubscribe
+
+		return l, nil
+	}
+}
+
+// keep makes l the driver of the machine name, in place of the one it had.
+func (s *Server) keep(name string, l *liveDriver) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		l.close()
+		return
+	}
+	previous := s.drivers[name]
+	s.drivers[name] = l
+	s.lock.Unlock()
+
+	if previous != nil && previous != l {
+		previous.lock.Lock()
+		previous.close()
+		previous.lock.Unlock()
+	}
+}
+
+// forget removes l from the drivers if it is still the one of the machine.
+func (s *Server) forget(name string, l *liveDriver) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.drivers[name] == l {
+		delete(s.drivers, name)
+	}
+}
+
+// release stops the driver of the machine name, if it has one.
+func (s *Server) release(name string) {
+	s.lock.Lock()
+	l, ok := s.drivers[name]
+	delete(s.drivers, name)
+	s.lock.Unlock()
+
+	if ok {
+		l.lock.Lock()
+		l.close()
+		l.lock.Unlock()
+	}
+}
+
+// rename keeps the driver of a machine under its new name.
+func (s *Server) rename(oldName, newName string, l *liveDriver) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.drivers[oldName] == l {
+		delete(s.drivers, oldName)
+	}
+	if previous, ok := s.drivers[newName]; ok && previous != l {
+		go func() {
+			previous.lock.Lock()
+			previous.close()
+			previous.lock.Unlock()
+		}()
+	}
+	s.drivers[newName] = l
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("%s %s", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("Error writing response: %s", err)
+	}
+}
+
+// writeConfig writes the configuration of a machine or of a driver with its
+// paths relative to the storage path.
+func (s *Server) writeConfig(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	config, err := persist.RelativizePaths(data, s.StorePath)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, (*json.RawMessage)(&config))
+}
+
+// readConfig resolves the paths of a configuration sent by a client.
+func (s *Server) readConfig(data []byte) ([]byte, error) {
+	return persist.ResolvePaths(data, s.StorePath)
+}
+
+// checkPaths returns an error if a path of the configuration of a machine or
+// of a driver, once read, is not under the storage path, so that a client
+// can not make the server read or write files outside of its store.
+func (s *Server) checkPaths(config []byte) error {
+	outside, err := persist.OutsidePaths(config, s.StorePath)
+	if err != nil {
+		return err
+	}
+
+	if len(outside) > 0 {
+		return fmt.Errorf("The path %q is not in the store of the server", outside[0])
+	}
+
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if _, ok := err.(mcnerror.ErrHostDoesNotExist); ok {
+		status = http.StatusNotFound
+	}
+
+	writeJSON(w, status, newErrorResponse(err))
+}
+
+func writeBadRequest(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusBadRequest, newErrorResponse(err))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{
+		Error: fmt.Sprintf("Method %s is not allowed on %s", r.Method, r.URL.Path),
+	})
+}
+
+// requestContext returns a context which is cancelled when the client goes
+// away, e.g. because the command was interrupted.
+func requestContext(w http.ResponseWriter) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed := notifier.CloseNotify()
+		go func() {
+			select {
+			case <-closed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	return ctx, cancel
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	hostNames, err := s.Store.List()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hostNames)
+}
+
+func (s *Server) handleMachine(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, machinesPath+"/")
+	if !host.ValidateHostName(name) {
+		writeBadRequest(w, mcnerror.ErrInvalidHostname)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		h, err := s.Store.Load(name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.writeConfig(w, h)
+	case "PUT":
+		h, err := s.decodeHost(r, name)
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		config, err := json.Marshal(h)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := s.checkPaths(config); err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		if err := s.keepHooks(h); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := s.Store.Save(h); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, struct{}{})
+	case "DELETE":
+		if err := s.Store.Remove(name); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.release(name)
+		writeJSON(w, http.StatusOK, struct{}{})
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// keepHooks replaces the hooks of a machine sent by the client with those
+// saved on the server, since their commands run on the server.
+func (s *Server) keepHooks(h *host.Host) error {
+	if h.HostOptions == nil {
+		return nil
+	}
+	h.HostOptions.HookOptions = nil
+
+	exists, err := s.Store.Exists(h.Name)
+	if err != nil || !exists {
+		return err
+	}
+
+	saved, err := s.Store.Load(h.Name)
+	if err != nil {
+		return err
+	}
+	h.HostOptions.HookOptions = saved.Hooks()
+
+	return nil
+}
+
+func (s *Server) decodeHost(r *http.Request, name string) (*host.Host, error) {
+	var data json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return s.unmarshalHost(data, name)
+}
+
+func (s *Server) unmarshalHost(data []byte, name string) (*host.Host, error) {
+	config, err := s.readConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	h, _, err := host.MigrateHost(&host.Host{Name: name}, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		h.Name = name
+	}
+
+	return h, nil
+}
+
+func (s *Server) handleNewHost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	var req newHostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	rawDriver, err := s.readConfig(req.RawDriver)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if err := s.checkPaths(rawDriver); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	l, err := s.newLiveDriver(req.DriverName, rawDriver)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Keep the driver for the calls which configure the machine before
+	// creating it, unless the configuration does not name the machine.
+	if !host.ValidateHostName(l.host.Name) {
+		defer l.close()
+		s.writeConfig(w, l.host)
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	s.keep(l.host.Name, l)
+	s.writeConfig(w, l.host)
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if !host.ValidateHostName(req.Name) || !host.ValidateHostName(req.NewName) {
+		writeBadRequest(w, mcnerror.ErrInvalidHostname)
+		return
+	}
+
+	api, err := s.NewAPI()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer api.Close()
+
+	mover, ok := libmachine.GetDirMover(api)
+	if !ok {
+		writeError(w, errCanNotMove)
+		return
+	}
+
+	if err := mover.MoveDir(req.Name, req.NewName); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	h, err := s.unmarshalHost(req.Host, "")
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if !host.ValidateHostName(h.Name) {
+		writeBadRequest(w, mcnerror.ErrInvalidHostname)
+		return
+	}
+
+	// The commands of the hooks would run on the server.
+	if hooks := h.Hooks(); hooks != nil && len(hooks.Hooks) > 0 {
+		writeBadRequest(w, errHooksNotAllowed)
+		return
+	}
+
+	// The paths of the certificates are replaced below, those of the driver
+	// are checked.
+	if err := s.checkPaths(h.RawDriver); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	l, err := s.acquire(h.Name, h.DriverName, h.RawDriver)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer l.lock.Unlock()
+
+	// Replace the raw configuration of the driver by an actual driver.
+	h.Driver = l.host.Driver
+	s.setAuthOptions(h, l.host.AuthOptions())
+
+	ctx, cancel := requestContext(w)
+	defer cancel()
+
+	if req.Resume {
+		err = l.api.ResumeCreateContext(ctx, h)
+	} else {
+		err = l.api.CreateContext(ctx, h)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeConfig(w, h)
+}
+
+// setAuthOptions replaces the paths of the certificates of a machine sent by
+// the client with those of the server. The CA and the client certificates are
+// those of server, the API creating the machine.
+func (s *Server) setAuthOptions(h *host.Host, server *auth.Options) {
+	if server == nil {
+		certDir := filepath.Join(s.StorePath, "certs")
+		server = &auth.Options{
+			CertDir:          certDir,
+			CaCertPath:       filepath.Join(certDir, "ca.pem"),
+			CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+			ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+			ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+		}
+	}
+
+	if h.HostOptions == nil {
+		h.HostOptions = &host.Options{}
+	}
+
+	options := auth.Options{}
+	if h.HostOptions.AuthOptions != nil {
+		options = *h.HostOptions.AuthOptions
+	}
+
+	machineDir := filepath.Join(s.StorePath, "machines", h.Name)
+
+	options.CertDir = server.CertDir
+	options.CaCertPath = server.CaCertPath
+	options.CaPrivateKeyPath = server.CaPrivateKeyPath
+	options.ClientCertPath = server.ClientCertPath
+	options.ClientKeyPath = server.ClientKeyPath
+	options.ServerCertPath = filepath.Join(machineDir, "server.pem")
+	options.ServerKeyPath = filepath.Join(machineDir, "server-key.pem")
+	options.StorePath = machineDir
+
+	h.HostOptions.AuthOptions = &options
+}
+
+func (s *Server) handleDriver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	var req driverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if !host.ValidateHostName(req.Name) {
+		writeBadRequest(w, mcnerror.ErrInvalidHostname)
+		return
+	}
+
+	rawDriver, err := s.readConfig(req.Driver)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if err := s.checkPaths(rawDriver); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	l, err := s.acquire(req.Name, req.DriverName, rawDriver)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer l.lock.Unlock()
+
+	ctx, cancel := requestContext(w)
+	defer cancel()
+
+	result, err := callDriver(ctx, l.host.Driver, req.Method, req.Args)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if req.Method == "Rename" {
+		var newName string
+		if err := json.Unmarshal(req.Args, &newName); err == nil {
+			s.rename(req.Name, newName, l)
+		}
+	}
+
+	resp := driverResponse{}
+
+	// Send the configuration of the driver back as the call may have
+	// changed it, e.g. Create records the IP of the machine.
+	driverData, err := json.Marshal(l.host.Driver)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if resp.Driver, err = persist.RelativizePaths(driverData, s.StorePath); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if result != nil {
+		if resp.Result, err = json.Marshal(result); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, &resp)
+}
+
+// callDriver runs method on d. The operations which may take long are
+// abandoned once ctx is done, e.g. when the client goes away.
+func callDriver(ctx context.Context, d drivers.Driver, method string, args json.RawMessage) (interface{}, error) {
+	switch method {
+	case "Create":
+		return nil, drivers.CreateWithContext(ctx, d)
+	case "PreCreateCheck":
+		return nil, d.PreCreateCheck()
+	case "Remove":
+		return nil, drivers.RemoveWithContext(ctx, d)
+	case "Start":
+		return nil, drivers.StartWithContext(ctx, d)
+	case "Stop":
+		return nil, drivers.StopWithContext(ctx, d)
+	case "Restart":
+		return nil, drivers.RestartWithContext(ctx, d)
+	case "Kill":
+		return nil, drivers.KillWithContext(ctx, d)
+	case "GetCreateFlags":
+		return encodeFlags(d.GetCreateFlags())
+	case "SetConfigFromFlags":
+		opts := &options{}
+		if err := json.Unmarshal(args, opts); err != nil {
+			return nil, err
+		}
+		return nil, d.SetConfigFromFlags(opts.decode())
+	case "GetMachineName":
+		return d.GetMachineName(), nil
+	case "GetSSHKeyPath":
+		return d.GetSSHKeyPath(), nil
+	case "GetSSHUsername":
+		return d.GetSSHUsername(), nil
+	case "GetIP":
+		return d.GetIP()
+	case "GetSSHHostname":
+		return d.GetSSHHostname()
+	case "GetSSHPort":
+		return d.GetSSHPort()
+	case "GetURL":
+		return d.GetURL()
+	case "GetState":
+		return d.GetState()
+	case "CreateSnapshot", "ListSnapshots", "RestoreSnapshot", "RemoveSnapshot":
+		return callSnapshotter(d, method, args)
+	case "Pause", "Unpause", "Suspend":
+		return nil, callPauser(ctx, d, method)
+	case "Resize":
+		r, err := drivers.GetResizer(d)
+		if err != nil {
+			return nil, err
+		}
+		opts := drivers.ResizeOptions{}
+		if err := json.Unmarshal(args, &opts); err != nil {
+			return nil, err
+		}
+		return nil, r.Resize(opts)
+	case "Clone":
+		c, err := drivers.GetCloner(d)
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		if err := json.Unmarshal(args, &name); err != nil {
+			return nil, err
+		}
+		data, err := c.Clone(name)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(data), nil
+	case "Rename":
+		r, err := drivers.GetRenamer(d)
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		if err := json.Unmarshal(args, &name); err != nil {
+			return nil, err
+		}
+		return nil, r.Rename(name)
+	case "ReleaseStoreDir":
+		return nil, drivers.ReleaseStoreDir(d)
+	case "UseStoreDir":
+		return nil, drivers.UseStoreDir(d)
+	}
+
+	return nil, errUnknownMethod
+}
+
+func callSnapshotter(d drivers.Driver, method string, args json.RawMessage) (interface{}, error) {
+	s, err := drivers.GetSnapshotter(d)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "ListSnapshots" {
+		return s.ListSnapshots()
+	}
+
+	var name string
+	if err := json.Unmarshal(args, &name); err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case "CreateSnapshot":
+		return nil, s.CreateSnapshot(name)
+	case "RestoreSnapshot":
+		return nil, s.RestoreSnapshot(name)
+	default:
+		return nil, s.RemoveSnapshot(name)
+	}
+}
+
+func callPauser(ctx context.Context, d drivers.Driver, method string) error {
+	switch method {
+	case "Pause":
+		return drivers.PauseWithContext(ctx, d)
+	case "Unpause":
+		return drivers.UnpauseWithContext(ctx, d)
+	default:
+		return drivers.SuspendWithContext(ctx, d)
+	}
+}
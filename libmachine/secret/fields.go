@@ -0,0 +1,145 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	fieldsLock sync.RWMutex
+
+	// driverSecretFields lists, for each driver, the fields of its
+	// serialized configuration which hold credentials. Nested fields are
+	// separated by dots.
+	driverSecretFields = map[string][]string{
+		"amazonec2":       {"SecretKey", "SessionToken"},
+		"digitalocean":    {"AccessToken"},
+		"exoscale":        {"ApiSecretKey"},
+		"openstack":       {"Password"},
+		"rackspace":       {"Password", "APIKey"},
+		"softlayer":       {"Client.ApiKey"},
+		"vmwarevcloudair": {"UserPassword"},
+		"vmwarevsphere":   {"Password"},
+	}
+)
+
+// RegisterFields flags fields of the configuration of a driver as secrets,
+// e.g. for drivers which are not bundled with Docker Machine.
+func RegisterFields(driverName string, fields ...string) {
+	fieldsLock.Lock()
+	defer fieldsLock.Unlock()
+
+	driverSecretFields[driverName] = append(driverSecretFields[driverName], fields...)
+}
+
+// Fields returns the secret fields of a driver.
+func Fields(driverName string) []string {
+	fieldsLock.RLock()
+	defer fieldsLock.RUnlock()
+
+	return append([]string{}, driverSecretFields[driverName]...)
+}
+
+// EncryptFields encrypts the secret fields of the serialized configuration
+// of a driver. Empty and already encrypted fields are left as they are.
+func EncryptFields(driverName string, rawDriver []byte, key []byte) ([]byte, error) {
+	return transformFields(driverName, rawDriver, func(value string) (string, error) {
+		if value == "" || IsEncrypted(value) {
+			return value, nil
+		}
+		return Encrypt(key, value)
+	})
+}
+
+// DecryptFields is the reverse of EncryptFields.
+func DecryptFields(driverName string, rawDriver []byte, key []byte) ([]byte, error) {
+	return transformFields(driverName, rawDriver, func(value string) (string, error) {
+		if !IsEncrypted(value) {
+			return value, nil
+		}
+		return Decrypt(key, value)
+	})
+}
+
+// RedactFields blanks the secret fields of the serialized configuration of a
+// driver, e.g. before handing it to someone else.
+func RedactFields(driverName string, rawDriver []byte) ([]byte, error) {
+	return transformFields(driverName, rawDriver, func(value string) (string, error) {
+		return "", nil
+	})
+}
+
+// HasEncryptedFields returns whether one of the secret fields of the
+// serialized configuration of a driver is encrypted.
+func HasEncryptedFields(driverName string, rawDriver []byte) bool {
+	encrypted := false
+
+	transformFields(driverName, rawDriver, func(value string) (string, error) {
+		if IsEncrypted(value) {
+			encrypted = true
+		}
+		return value, nil
+	})
+
+	return encrypted
+}
+
+func transformFields(driverName string, rawDriver []byte, transform func(string) (string, error)) ([]byte, error) {
+	fields := Fields(driverName)
+	if len(fields) == 0 {
+		return rawDriver, nil
+	}
+
+	// Keep the numbers as they are written instead of turning them into
+	// floats, which would lose the precision of large integers.
+	var config map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(rawDriver))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	changed := false
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+
+		parent := config
+		for _, name := range path[:len(path)-1] {
+			child, ok := parent[name].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = child
+		}
+
+		if parent == nil {
+			continue
+		}
+
+		name := path[len(path)-1]
+		value, ok := parent[name].(string)
+		if !ok {
+			continue
+		}
+
+		transformed, err := transform(value)
+		if err != nil {
+			return nil, fmt.Errorf("Error with secret field %s: %s", field, err)
+		}
+
+		if transformed != value {
+			parent[name] = transformed
+			changed = true
+		}
+	}
+
+	if !changed {
+		return rawDriver, nil
+	}
+
+	return json.Marshal(config)
+}
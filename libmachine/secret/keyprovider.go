@@ -0,0 +1,125 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	saltSize         = 16
+	pbkdf2Iterations = 100000
+)
+
+// KeyProvider supplies the key used to encrypt and decrypt the secrets of
+// the machines.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// PassphraseKeyProvider derives the key from a passphrase. The salt is kept
+// in a file, which is created the first time the key is needed.
+type PassphraseKeyProvider struct {
+	Passphrase string
+	SaltPath   string
+
+	once sync.Once
+	key  []byte
+	err  error
+}
+
+func NewPassphraseKeyProvider(passphrase, saltPath string) *PassphraseKeyProvider {
+	return &PassphraseKeyProvider{
+		Passphrase: passphrase,
+		SaltPath:   saltPath,
+	}
+}
+
+func (p *PassphraseKeyProvider) Key() ([]byte, error) {
+	// Deriving the key is purposely slow, only do it once.
+	p.once.Do(func() {
+		salt, err := p.salt()
+		if err != nil {
+			p.err = err
+			return
+		}
+		p.key = pbkdf2([]byte(p.Passphrase), salt, pbkdf2Iterations, KeySize)
+	})
+
+	return p.key, p.err
+}
+
+func (p *PassphraseKeyProvider) salt() ([]byte, error) {
+	salt, err := ioutil.ReadFile(p.SaltPath)
+	if err == nil {
+		return salt, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	salt = make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(p.SaltPath, salt, 0600); err != nil {
+		return nil, fmt.Errorf("Error saving the salt of the passphrase: %s", err)
+	}
+
+	return salt, nil
+}
+
+// KeyFileProvider reads the key from a file holding a base64 encoded key,
+// such as the ones written by GenerateKeyFile.
+type KeyFileProvider struct {
+	Path string
+}
+
+func NewKeyFileProvider(path string) *KeyFileProvider {
+	return &KeyFileProvider{
+		Path: path,
+	}
+}
+
+func (p *KeyFileProvider) Key() ([]byte, error) {
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid key file %s: %s", p.Path, err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("Invalid key file %s: the key must be %d bytes long", p.Path, KeySize)
+	}
+
+	return key, nil
+}
+
+// GenerateKeyFile writes a new random key to path. It refuses to overwrite
+// an existing file, which would make the secrets encrypted with it
+// unrecoverable.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, base64.StdEncoding.EncodeToString(key))
+	return err
+}
@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// pbkdf2 derives a key of keyLen bytes from the password and the salt as
+// described in RFC 2898, using HMAC-SHA256 as the pseudorandom function.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
@@ -0,0 +1,84 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySize is the size in bytes of the keys used to encrypt secrets.
+const KeySize = 32
+
+// prefix marks the values encrypted by this package, so that they are never
+// encrypted twice and can be told apart from plaintext values.
+const prefix = "encrypted:v1:"
+
+var ErrWrongKey = errors.New("Unable to decrypt the secret, the key is probably wrong")
+
+// IsEncrypted returns whether the value has been produced by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts and authenticates the plaintext with AES-GCM.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value produced by Encrypt.
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("The value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("Invalid encrypted value: %s", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("Invalid encrypted value: too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("Invalid key size %d, expected %d bytes", len(key), KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,137 @@
+package secret
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := Encrypt(testKey, "secret")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "secret")
+
+	decrypted, err := Decrypt(testKey, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	encrypted, err := Encrypt(testKey, "secret")
+	assert.NoError(t, err)
+
+	_, err = Decrypt([]byte("abcdef0123456789abcdef0123456789"), encrypted)
+	assert.Equal(t, ErrWrongKey, err)
+}
+
+func TestEncryptInvalidKey(t *testing.T) {
+	_, err := Encrypt([]byte("short"), "secret")
+	assert.EqualError(t, err, "Invalid key size 5, expected 32 bytes")
+}
+
+func TestPBKDF2(t *testing.T) {
+	// Test vector of PBKDF2-HMAC-SHA256.
+	key := pbkdf2([]byte("password"), []byte("salt"), 1, 32)
+	assert.Equal(t, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b", hex.EncodeToString(key))
+
+	key = pbkdf2([]byte("password"), []byte("salt"), 2, 32)
+	assert.Equal(t, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43", hex.EncodeToString(key))
+}
+
+func TestEncryptFields(t *testing.T) {
+	raw := []byte(`{"AccessKey":"access","SecretKey":"secret","SessionToken":"","RootSize":9007199254740993}`)
+
+	encrypted, err := EncryptFields("amazonec2", raw, testKey)
+	assert.NoError(t, err)
+	assert.True(t, HasEncryptedFields("amazonec2", encrypted))
+	assert.NotContains(t, string(encrypted), `"secret"`)
+	assert.Contains(t, string(encrypted), `"AccessKey":"access"`)
+	assert.Contains(t, string(encrypted), `"SessionToken":""`)
+	assert.Contains(t, string(encrypted), `"RootSize":9007199254740993`)
+
+	// Encrypting twice is a no-op.
+	again, err := EncryptFields("amazonec2", encrypted, testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, encrypted, again)
+
+	decrypted, err := DecryptFields("amazonec2", encrypted, testKey)
+	assert.NoError(t, err)
+
+	var config map[string]interface{}
+	assert.NoError(t, json.Unmarshal(decrypted, &config))
+	assert.Equal(t, "secret", config["SecretKey"])
+}
+
+func TestEncryptNestedFields(t *testing.T) {
+	raw := []byte(`{"Client":{"User":"user","ApiKey":"key"}}`)
+
+	encrypted, err := EncryptFields("softlayer", raw, testKey)
+	assert.NoError(t, err)
+	assert.Contains(t, string(encrypted), `"User":"user"`)
+	assert.NotContains(t, string(encrypted), `"ApiKey":"key"`)
+
+	decrypted, err := DecryptFields("softlayer", encrypted, testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Client":{"ApiKey":"key","User":"user"}}`, string(decrypted))
+}
+
+func TestEncryptFieldsUnknownDriver(t *testing.T) {
+	raw := []byte(`{"Password":"secret"}`)
+
+	encrypted, err := EncryptFields("none", raw, testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, encrypted)
+}
+
+func TestRegisterFields(t *testing.T) {
+	RegisterFields("test-driver", "Token")
+	defer func() {
+		fieldsLock.Lock()
+		delete(driverSecretFields, "test-driver")
+		fieldsLock.Unlock()
+	}()
+
+	encrypted, err := EncryptFields("test-driver", []byte(`{"Token":"secret"}`), testKey)
+	assert.NoError(t, err)
+	assert.True(t, HasEncryptedFields("test-driver", encrypted))
+}
+
+func TestKeyProviders(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	saltPath := filepath.Join(tmpDir, "salt")
+
+	key, err := NewPassphraseKeyProvider("passphrase", saltPath).Key()
+	assert.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	// The salt is reused, so the same passphrase gives the same key.
+	sameKey, err := NewPassphraseKeyProvider("passphrase", saltPath).Key()
+	assert.NoError(t, err)
+	assert.Equal(t, key, sameKey)
+
+	keyPath := filepath.Join(tmpDir, "key")
+	assert.NoError(t, GenerateKeyFile(keyPath))
+	assert.Error(t, GenerateKeyFile(keyPath))
+
+	key, err = NewKeyFileProvider(keyPath).Key()
+	assert.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	assert.NoError(t, ioutil.WriteFile(keyPath, []byte(strings.Repeat("a", 8)), 0600))
+	_, err = NewKeyFileProvider(keyPath).Key()
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+// Package watch periodically checks the health of machines and recovers the
+// ones which went down according to their restart policy.
+package watch
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/check"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/hook"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+	"golang.org/x/net/context"
+)
+
+// DefaultInterval is the time between two checks when none is given.
+const DefaultInterval = 30 * time.Second
+
+// Status is the health of a machine.
+type Status struct {
+	State state.State
+
+	// Err is the reason why the state of a machine could not be read, or
+	// why its Docker daemon could not be reached if it is running.
+	Err error
+}
+
+// Healthy returns true if the machine is running and its Docker daemon
+// can be reached.
+func (s Status) Healthy() bool {
+	return s.State == state.Running && s.Err == nil
+}
+
+func (s Status) String() string {
+	if s.Err == nil {
+		return s.State.String()
+	}
+	if s.State == state.Running {
+		return fmt.Sprintf("Running, Docker can not be reached: %s", s.Err)
+	}
+	return fmt.Sprintf("%s: %s", s.State, s.Err)
+}
+
+// Watcher checks the health of machines.
+type Watcher struct {
+	api libmachine.API
+
+	// Names are the machines to watch, all the machines of the store if
+	// empty.
+	Names []string
+
+	// Interval is the time between two checks.
+	Interval time.Duration
+
+	// DefaultPolicy is the restart policy of the machines which have none.
+	DefaultPolicy host.RestartPolicy
+
+	// ConnChecker checks that the Docker daemon of a running machine can
+	// be reached.
+	ConnChecker check.ConnChecker
+
+	statuses map[string]Status
+	down     map[string]bool
+}
+
+func NewWatcher(api libmachine.API) *Watcher {
+	return &Watcher{
+		api:           api,
+		Interval:      DefaultInterval,
+		DefaultPolicy: host.RestartNo,
+		ConnChecker:   check.DefaultConnChecker,
+		statuses:      map[string]Status{},
+		down:          map[string]bool{},
+	}
+}
+
+// Run checks the machines every interval until ctx is done, and returns
+// the error of ctx.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.CheckAll(ctx); err != nil {
+			log.Warnf("Error listing the machines: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every watched machine once, publishing a Changed event
+// for each machine whose status changed and recovering the machines which
+// went down.
+func (w *Watcher) CheckAll(ctx context.Context) error {
+	names := w.Names
+	if len(names) == 0 {
+		var err error
+		if names, err = w.api.List(); err != nil {
+			return err
+		}
+		sort.Strings(names)
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		seen[name] = true
+
+		// The machine is loaded at every check to see the changes made
+		// by other commands, and its driver is closed afterwards, so
+		// that no plugin binary is left running for it.
+		h, err := w.api.Load(name)
+		if err != nil {
+			log.Warnf("Error loading %q: %s", name, err)
+			continue
+		}
+
+		w.checkHost(ctx, h)
+
+		if err := drivers.Close(h.Driver); err != nil {
+			log.Debugf("Error closing the driver of %q: %s", name, err)
+		}
+	}
+
+	// Forget the machines which were removed.
+	for name := range w.statuses {
+		if !seen[name] {
+			delete(w.statuses, name)
+			delete(w.down, name)
+		}
+	}
+
+	return nil
+}
+
+// Status returns the last status of the machine and false if it was not
+// checked yet.
+func (w *Watcher) Status(name string) (Status, bool) {
+	s, ok := w.statuses[name]
+	return s, ok
+}
+
+func (w *Watcher) checkHost(ctx context.Context, h *host.Host) {
+	previous, known := w.statuses[h.Name]
+	current := w.status(h)
+	w.statuses[h.Name] = current
+
+	if !known || previous.String() != current.String() {
+		h.Publish(event.Event{
+			Type:       event.Changed,
+			Stage:      event.Health,
+			HostName:   h.Name,
+			DriverName: h.DriverName,
+			Detail:     current.String(),
+			Err:        current.Err,
+		})
+
+		h.SetCachedState(current.State)
+		if current.Err != nil {
+			h.CachedState.Error = current.Err.Error()
+		}
+		if err := persist.SaveCachedState(w.api, h.Name, h.CachedState); err != nil {
+			log.Warnf("Error saving %q: %s", h.Name, err)
+		}
+	}
+
+	// A machine stopped on purpose, e.g. by the stop command or the
+	// reaper, is not down.
+	if h.StopRequested() {
+		w.down[h.Name] = false
+		return
+	}
+
+	wasDown := w.down[h.Name]
+	isDown := isDown(current, wasDown || (known && previous.State == state.Running))
+	w.down[h.Name] = isDown
+
+	switch {
+	case isDown && !wasDown:
+		vars := map[string]string{"MACHINE_STATE": current.String()}
+		if err := h.RunHooksWithVars(ctx, hook.Unhealthy, vars); err != nil {
+			log.Warn(err)
+		}
+		w.recover(ctx, h, current)
+	case !isDown && wasDown:
+		if err := h.RunHooks(ctx, hook.Recovered); err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+// isDown returns true if the machine is unhealthy. A machine which is not
+// running is only down if it was running before, since it may have been
+// stopped on purpose; paused and suspended machines never are.
+func isDown(s Status, wasRunning bool) bool {
+	switch {
+	case s.Healthy():
+		return false
+	case s.State == state.Running, s.State == state.Error:
+		return true
+	case s.State == state.Paused, s.State == state.Saved:
+		return false
+	}
+	return wasRunning
+}
+
+func (w *Watcher) status(h *host.Host) Status {
+	s, err := h.Driver.GetState()
+	if err != nil {
+		return Status{State: state.Error, Err: err}
+	}
+
+	if s != state.Running {
+		return Status{State: s}
+	}
+
+	if _, _, err := w.ConnChecker.Check(h, false); err != nil {
+		return Status{State: s, Err: err}
+	}
+
+	return Status{State: s}
+}
+
+// recover applies the restart policy of a machine which went down. It is
+// only attempted once until the machine is healthy again, so that a machine
+// which can not be recovered is not restarted over and over.
+func (w *Watcher) recover(ctx context.Context, h *host.Host, s Status) {
+	policy := w.DefaultPolicy
+	if h.HostOptions != nil && h.HostOptions.RestartPolicy != "" {
+		policy = h.HostOptions.RestartPolicy
+	}
+
+	var err error
+	switch policy {
+	case host.RestartMachine:
+		if s.State == state.Running {
+			err = h.RestartContext(ctx)
+		} else {
+			err = h.StartContext(ctx)
+		}
+	case host.RestartDocker:
+		if s.State != state.Running {
+			log.Warnf("Machine %q is not running, its Docker daemon can not be restarted", h.Name)
+			return
+		}
+		err = mcnutils.RunWithContext(ctx, h.RestartDocker)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Warnf("Error recovering %q: %s", h.Name, err)
+		return
+	}
+
+	if err := w.api.Save(h); err != nil {
+		log.Warnf("Error saving %q: %s", h.Name, err)
+	}
+}
@@ -0,0 +1,194 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/event"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/libmachinetest"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type fakeConnChecker struct {
+	err error
+}
+
+func (fcc *fakeConnChecker) Check(h *host.Host, swarm bool) (string, *auth.Options, error) {
+	return "", nil, fcc.err
+}
+
+func newWatchedHost(name string, s state.State, policy host.RestartPolicy) *host.Host {
+	return &host.Host{
+		Name:       name,
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: s,
+		},
+		HostOptions: &host.Options{
+			RestartPolicy: policy,
+		},
+	}
+}
+
+func newTestWatcher(hosts ...*host.Host) (*Watcher, *fakeConnChecker) {
+	api := &libmachinetest.FakeAPI{Hosts: hosts}
+	checker := &fakeConnChecker{}
+
+	w := NewWatcher(api)
+	w.ConnChecker = checker
+	for _, h := range hosts {
+		w.Names = append(w.Names, h.Name)
+	}
+
+	return w, checker
+}
+
+func mockState(h *host.Host) state.State {
+	return h.Driver.(*fakedriver.Driver).MockState
+}
+
+func TestCheckAllPublishesChanges(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, host.RestartNo)
+	w, checker := newTestWatcher(h)
+
+	changes := []string{}
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		if e.Type == event.Changed {
+			changes = append(changes, e.HostName+": "+e.Detail)
+		}
+	})
+	defer unsubscribe()
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.NoError(t, w.CheckAll(context.Background()))
+	checker.err = errors.New("connection refused")
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, []string{
+		"foo: Running",
+		"foo: Running, Docker can not be reached: connection refused",
+	}, changes)
+
+	status, ok := w.Status("foo")
+	assert.True(t, ok)
+	assert.False(t, status.Healthy())
+}
+
+func TestCrashedMachineIsStarted(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, host.RestartMachine)
+	w, _ := newTestWatcher(h)
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	h.Driver.(*fakedriver.Driver).MockState = state.Stopped
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, state.Running, mockState(h))
+}
+
+func TestStoppedMachineIsLeftAlone(t *testing.T) {
+	h := newWatchedHost("foo", state.Stopped, host.RestartMachine)
+	w, _ := newTestWatcher(h)
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, state.Stopped, mockState(h))
+}
+
+func TestMachineStoppedOnPurposeIsLeftAlone(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, host.RestartMachine)
+	w, _ := newTestWatcher(h)
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.NoError(t, h.Stop())
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.Equal(t, state.Stopped, mockState(h))
+
+	assert.NoError(t, h.Start())
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	h.Driver.(*fakedriver.Driver).MockState = state.Stopped
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.Equal(t, state.Running, mockState(h))
+}
+
+type closingDriver struct {
+	*fakedriver.Driver
+	closes int
+}
+
+func (d *closingDriver) Close() error {
+	d.closes++
+	return nil
+}
+
+func TestDriversAreClosedAfterEachCheck(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, host.RestartNo)
+	d := &closingDriver{Driver: h.Driver.(*fakedriver.Driver)}
+	h.Driver = d
+	w, _ := newTestWatcher(h)
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, 2, d.closes)
+}
+
+func TestCrashedMachineWithoutPolicy(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, "")
+	w, _ := newTestWatcher(h)
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	h.Driver.(*fakedriver.Driver).MockState = state.Stopped
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, state.Stopped, mockState(h))
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, "")
+	w, _ := newTestWatcher(h)
+	w.DefaultPolicy = host.RestartMachine
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	h.Driver.(*fakedriver.Driver).MockState = state.Stopped
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, state.Running, mockState(h))
+}
+
+func TestRecoveryIsAttemptedOnce(t *testing.T) {
+	h := newWatchedHost("foo", state.Running, host.RestartMachine)
+	w, checker := newTestWatcher(h)
+	checker.err = errors.New("connection refused")
+
+	restarts := 0
+	unsubscribe := event.Subscribe(func(e event.Event) {
+		if e.Stage == event.Restart && e.Type == event.Started {
+			restarts++
+		}
+	})
+	defer unsubscribe()
+
+	assert.NoError(t, w.CheckAll(context.Background()))
+	assert.NoError(t, w.CheckAll(context.Background()))
+
+	assert.Equal(t, 1, restarts)
+}
+
+func TestPausedMachineIsNotDown(t *testing.T) {
+	assert.False(t, isDown(Status{State: state.Paused}, true))
+	assert.False(t, isDown(Status{State: state.Saved}, true))
+	assert.True(t, isDown(Status{State: state.Stopped}, true))
+	assert.False(t, isDown(Status{State: state.Stopped}, false))
+	assert.True(t, isDown(Status{State: state.Error, Err: errors.New("boom")}, false))
+}